@@ -20,6 +20,8 @@ import (
 	"github.com/marmotedu/component-base/pkg/util/homedir"
 
 	"github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam"
+	"github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam/testserver"
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
 	"github.com/marmotedu/marmotedu-sdk-go/tools/clientcmd"
 )
 
@@ -34,12 +36,22 @@ func main() {
 	} else {
 		iamconfig = flag.String("iamconfig", "", "absolute path to the iamconfig file")
 	}
+	offline := flag.Bool("offline", false, "run against an in-process fake IAM server instead of iamconfig")
 	flag.Parse()
 
-	// use the current context in iamconfig
-	config, err := clientcmd.BuildConfigFromFlags("", *iamconfig)
-	if err != nil {
-		panic(err.Error())
+	var config *rest.Config
+	if *offline {
+		srv := testserver.New()
+		defer srv.Close()
+
+		config = srv.Config()
+	} else {
+		// use the current context in iamconfig
+		var err error
+		config, err = clientcmd.BuildConfigFromFlags("", *iamconfig)
+		if err != nil {
+			panic(err.Error())
+		}
 	}
 
 	// create the iamclient
@@ -18,6 +18,8 @@ import (
 	"github.com/marmotedu/component-base/pkg/util/homedir"
 
 	"github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam"
+	"github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam/testserver"
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
 	"github.com/marmotedu/marmotedu-sdk-go/tools/clientcmd"
 )
 
@@ -32,12 +34,22 @@ func main() {
 	} else {
 		iamconfig = flag.String("iamconfig", "", "absolute path to the iamconfig file")
 	}
+	offline := flag.Bool("offline", false, "run against an in-process fake IAM server instead of iamconfig")
 	flag.Parse()
 
-	// use the current context in iamconfig
-	config, err := clientcmd.BuildConfigFromFlags("", *iamconfig)
-	if err != nil {
-		panic(err.Error())
+	var config *rest.Config
+	if *offline {
+		srv := testserver.New()
+		defer srv.Close()
+
+		config = srv.Config()
+	} else {
+		// use the current context in iamconfig
+		var err error
+		config, err = clientcmd.BuildConfigFromFlags("", *iamconfig)
+		if err != nil {
+			panic(err.Error())
+		}
 	}
 
 	// create the iamclient
@@ -61,11 +73,16 @@ func main() {
 	// Create user
 	fmt.Println("Creating user...")
 	ret, err := usersClient.Create(context.TODO(), user, metav1.CreateOptions{})
-	if err != nil {
+	switch {
+	case rest.IsAlreadyExists(err):
+		// left over by a previous run, reuse it
+		fmt.Printf("User %q already exists.\n", user.Name)
+	case err != nil:
 		panic(err.Error())
+	default:
+		fmt.Printf("Created user %q.\n", ret.GetObjectMeta().GetName())
 	}
 
-	fmt.Printf("Created user %q.\n", ret.GetObjectMeta().GetName())
 	defer func() {
 		// Delete secret
 		fmt.Println("Deleting user...")
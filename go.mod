@@ -1,6 +1,6 @@
 module github.com/marmotedu/marmotedu-sdk-go
 
-go 1.17
+go 1.18
 
 require (
 	github.com/elazarl/goproxy v0.0.0-20210110162100-a92cc753f88e
@@ -9,6 +9,7 @@ require (
 	github.com/marmotedu/errors v1.0.2
 	github.com/ory/ladon v1.2.0
 	github.com/pkg/errors v0.9.1
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 	golang.org/x/net v0.0.0-20210316092652-d523dce5a7f4
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	moul.io/http2curl v1.0.0
@@ -37,7 +38,6 @@ require (
 	github.com/sony/sonyflake v1.0.0 // indirect
 	github.com/speps/go-hashids v2.0.0+incompatible // indirect
 	github.com/stretchr/testify v1.7.0 // indirect
-	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2 // indirect
 	golang.org/x/sys v0.0.0-20210315160823-c6e025ad8005 // indirect
 	gorm.io/gorm v1.22.4 // indirect
 )
@@ -5,6 +5,8 @@
 package marmotedu
 
 import (
+	"context"
+
 	"github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam"
 	"github.com/marmotedu/marmotedu-sdk-go/rest"
 )
@@ -38,13 +40,20 @@ func (c *Clientset) Iam() iam.IamInterface {
 // If config's RateLimiter is not set and QPS and Burst are acceptable,
 // NewForConfig will generate a rate-limiter in configShallowCopy.
 func NewForConfig(c *rest.Config) (*Clientset, error) {
+	return NewForConfigWithContext(context.Background(), c)
+}
+
+// NewForConfigWithContext creates a new Clientset for the given config, bound to ctx:
+// cancelling ctx cancels all in-flight requests of the clientset, eg. on shutdown.
+// Requests are still bound to their own context as well.
+func NewForConfigWithContext(ctx context.Context, c *rest.Config) (*Clientset, error) {
 	configShallowCopy := *c
 
 	var cs Clientset
 
 	var err error
 
-	cs.iam, err = iam.NewForConfig(&configShallowCopy)
+	cs.iam, err = iam.NewForConfigWithContext(ctx, &configShallowCopy)
 	if err != nil {
 		return nil, err
 	}
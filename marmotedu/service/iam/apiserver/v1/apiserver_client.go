@@ -5,6 +5,9 @@
 package v1
 
 import (
+	"context"
+	"time"
+
 	v1 "github.com/marmotedu/api/apiserver/v1"
 	"github.com/marmotedu/component-base/pkg/runtime"
 
@@ -22,6 +25,7 @@ type APIV1Interface interface {
 // APIV1Client is used to interact with features provided by the group.
 type APIV1Client struct {
 	restClient rest.Interface
+	validators Validators
 }
 
 // Users create and return user rest client.
@@ -39,17 +43,42 @@ func (c *APIV1Client) Policies() PolicyInterface {
 	return newPolicies(c)
 }
 
+// WithTimeout returns a copy of the client whose requests time out after timeout, in place of
+// the Timeout of its config, see rest.RESTClient.WithTimeout. The client is left unaffected.
+// A client built with New from another implementation of rest.Interface is returned as is.
+func (c *APIV1Client) WithTimeout(timeout time.Duration) *APIV1Client {
+	restClient, ok := c.restClient.(*rest.RESTClient)
+	if !ok {
+		return c
+	}
+
+	return &APIV1Client{restClient: restClient.WithTimeout(timeout), validators: c.validators}
+}
+
+// WithValidators returns a copy of the client whose Create methods validate the objects with
+// validators, eg. to check more fields, or to disable the validation of a kind of object. The
+// client is left unaffected.
+func (c *APIV1Client) WithValidators(validators Validators) *APIV1Client {
+	return &APIV1Client{restClient: c.restClient, validators: validators}
+}
+
 // NewForConfig creates a new APIV1Client for the given config.
 func NewForConfig(c *rest.Config) (*APIV1Client, error) {
+	return NewForConfigWithContext(context.Background(), c)
+}
+
+// NewForConfigWithContext creates a new APIV1Client for the given config, whose requests
+// are cancelled when ctx is done.
+func NewForConfigWithContext(ctx context.Context, c *rest.Config) (*APIV1Client, error) {
 	config := *c
 	setConfigDefaults(&config)
 
-	client, err := rest.RESTClientFor(&config)
+	client, err := rest.RESTClientForWithContext(ctx, &config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &APIV1Client{client}, nil
+	return New(client), nil
 }
 
 // NewForConfigOrDie creates a new APIV1Client for the given config and
@@ -65,7 +94,7 @@ func NewForConfigOrDie(c *rest.Config) *APIV1Client {
 
 // New creates a new APIV1Client for the given RESTClient.
 func New(c rest.Interface) *APIV1Client {
-	return &APIV1Client{c}
+	return &APIV1Client{restClient: c, validators: DefaultValidators()}
 }
 
 func setConfigDefaults(config *rest.Config) {
@@ -0,0 +1,44 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestWithTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-req.Context().Done():
+		}
+
+		_, _ = w.Write([]byte(`{"metadata":{"name":"colin"}}`))
+	}))
+	defer srv.Close()
+
+	base, err := NewForConfig(&rest.Config{Host: srv.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hot := base.WithTimeout(20 * time.Millisecond)
+
+	if _, err := hot.Users().Get(context.TODO(), "colin", metav1.GetOptions{}); err == nil {
+		t.Error("expected the request of the clone to time out")
+	}
+
+	if _, err := base.Users().Get(context.TODO(), "colin", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the request of the base client to succeed, got %v", err)
+	}
+}
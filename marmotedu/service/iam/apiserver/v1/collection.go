@@ -0,0 +1,88 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// continueParam is the query parameter carrying the continue token of a batched delete.
+const continueParam = "continue"
+
+// deleteCollectionStatus is returned by servers which delete a collection in batches.
+// A non-empty Continue means more objects are left and must be deleted with another request.
+type deleteCollectionStatus struct {
+	Deleted  int64  `json:"deleted,omitempty"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// deleteCollection deletes the objects of the given resource which match the list options,
+// following the continue tokens returned by the server until all batches are deleted.
+// It returns the total number of objects the server reported as deleted.
+func deleteCollection(ctx context.Context, client rest.Interface, resource string,
+	opts metav1.DeleteOptions, listOpts metav1.ListOptions) (int64, error) {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+
+	var deleted int64
+
+	var continueToken string
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		req := client.Delete().
+			Resource(resource).
+			VersionedParams(listOpts).
+			Timeout(timeout).
+			Body(&opts)
+		if continueToken != "" {
+			req = req.Param(continueParam, continueToken)
+		}
+
+		result := req.Do(ctx)
+
+		body, err := result.Raw()
+		if err != nil {
+			return deleted, err
+		}
+
+		// Servers which delete the whole collection at once return no status.
+		if len(bytes.TrimSpace(body)) == 0 {
+			return deleted, nil
+		}
+
+		// decoded leniently, as the status is a partial view of what the server returns, which
+		// Config.StrictDecoding would reject
+		status := &deleteCollectionStatus{}
+		if err := json.Unmarshal(body, status); err != nil {
+			return deleted, fmt.Errorf("decode delete collection status: %w", err)
+		}
+
+		deleted += status.Deleted
+
+		if status.Continue == "" {
+			return deleted, nil
+		}
+
+		if status.Continue == continueToken {
+			return deleted, fmt.Errorf("server returned the same continue token %q twice", continueToken)
+		}
+
+		continueToken = status.Continue
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// PartialObjectMetadata is the metadata-only representation of an object.
+type PartialObjectMetadata struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard object's metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+// PartialObjectMetadataList is a list of the metadata-only representation of objects.
+type PartialObjectMetadataList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard list metadata.
+	metav1.ListMeta `json:",inline"`
+
+	Items []*PartialObjectMetadata `json:"items"`
+}
+
+// listMetadata lists the given resource asking for its PartialObjectMetadataList representation.
+// A full list decodes into the same type, since the metadata lives under the same field.
+func listMetadata(ctx context.Context, client rest.Interface, resource string,
+	opts metav1.ListOptions) (*PartialObjectMetadataList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	result := &PartialObjectMetadataList{}
+	err := client.Get().
+		Resource(resource).
+		VersionedParams(opts).
+		SetHeader("Accept", rest.PartialObjectMetadataListAcceptContentTypes).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		return nil, fmt.Errorf("list %s metadata: %w", resource, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+)
+
+// GetOptions may be provided when getting an object with GetWithOptions, for the options of the
+// IAM API which metav1.GetOptions lacks.
+type GetOptions struct {
+	metav1.GetOptions `json:",inline"`
+
+	// ResourceVersion asks for the object at the given resource version rather than the latest.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// DeleteOptions may be provided when deleting an object with DeleteWithOptions, for the options
+// of the IAM API which metav1.DeleteOptions lacks.
+type DeleteOptions struct {
+	metav1.DeleteOptions `json:",inline"`
+
+	// Preconditions must be fulfilled by the object for the server to delete it.
+	Preconditions Preconditions `json:"-"`
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+)
+
+// DefaultPageSize is the number of items requested per page when paginating a collection with
+// list options which don't set Limit.
+const DefaultPageSize int64 = 100
+
+// listPages lists a collection page by page with the Offset and Limit list options, starting at
+// opts.Offset. listPage fetches the page selected by its options, and returns the number of items
+// of the page, the total number of items of the collection and whether to list the next page.
+// A positive maxItems bounds the number of listed items, in which case truncated tells whether
+// items were left unlisted. When the budget runs out and the total is unknown, the item past the
+// budget is fetched with a one-item page to tell, so listPage may see maxItems+1 items overall.
+func listPages(ctx context.Context, opts metav1.ListOptions, maxItems int64,
+	listPage func(opts metav1.ListOptions) (items int, total int64, cont bool, err error)) (truncated bool, err error) {
+	pageSize := DefaultPageSize
+	if opts.Limit != nil && *opts.Limit > 0 {
+		pageSize = *opts.Limit
+	}
+
+	var offset, listed int64
+	if opts.Offset != nil {
+		offset = *opts.Offset
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		limit := pageSize
+		if maxItems > 0 && maxItems-listed < limit {
+			limit = maxItems - listed
+		}
+
+		pageOpts := opts
+		pageOpts.Offset = int64Ptr(offset)
+		pageOpts.Limit = int64Ptr(limit)
+
+		items, total, cont, err := listPage(pageOpts)
+		if err != nil || !cont {
+			return false, err
+		}
+
+		listed += int64(items)
+		offset += int64(items)
+
+		// a short page is the last one
+		if int64(items) < limit || (total > 0 && offset >= total) {
+			return false, nil
+		}
+
+		if maxItems > 0 && listed >= maxItems {
+			if total > 0 {
+				return true, nil
+			}
+
+			pageOpts.Offset = int64Ptr(offset)
+			pageOpts.Limit = int64Ptr(1)
+
+			items, _, _, err := listPage(pageOpts)
+			if err != nil {
+				return false, err
+			}
+
+			return items > 0, nil
+		}
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -6,6 +6,7 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	v1 "github.com/marmotedu/api/apiserver/v1"
@@ -25,6 +26,7 @@ type PolicyInterface interface {
 	Create(ctx context.Context, policy *v1.Policy, opts metav1.CreateOptions) (*v1.Policy, error)
 	Update(ctx context.Context, policy *v1.Policy, opts metav1.UpdateOptions) (*v1.Policy, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteObject(ctx context.Context, policy *v1.Policy, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Policy, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*v1.PolicyList, error)
@@ -33,27 +35,21 @@ type PolicyInterface interface {
 
 // policies implements PolicyInterface.
 type policies struct {
-	client rest.Interface
+	client   rest.Interface
+	validate func(policy *v1.Policy) error
 }
 
 // newPolicies returns a Policies.
 func newPolicies(c *APIV1Client) *policies {
 	return &policies{
-		client: c.RESTClient(),
+		client:   c.RESTClient(),
+		validate: c.validators.Policy,
 	}
 }
 
 // Get takes name of the policy, and returns the corresponding policy object, and an error if there is any.
 func (c *policies) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.Policy, err error) {
-	result = &v1.Policy{}
-	err = c.client.Get().
-		Resource("policies").
-		Name(name).
-		VersionedParams(options).
-		Do(ctx).
-		Into(result)
-
-	return
+	return c.GetWithOptions(ctx, name, GetOptions{GetOptions: options})
 }
 
 // List takes label and field selectors, and returns the list of Policies that match those selectors.
@@ -70,6 +66,16 @@ func (c *policies) List(ctx context.Context, opts metav1.ListOptions) (result *v
 		Timeout(timeout).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("list policies: %w", err)
+
+		return
+	}
+
+	// servers may return null items for an empty list, callers get an empty slice either way
+	if result.Items == nil {
+		result.Items = []*v1.Policy{}
+	}
 
 	return
 }
@@ -78,6 +84,12 @@ func (c *policies) List(ctx context.Context, opts metav1.ListOptions) (result *v
 // Returns the server's representation of the policy, and an error, if there is any.
 func (c *policies) Create(ctx context.Context, policy *v1.Policy,
 	opts metav1.CreateOptions) (result *v1.Policy, err error) {
+	if c.validate != nil && !validationSkipped(ctx) {
+		if err = c.validate(policy); err != nil {
+			return nil, fmt.Errorf("create policy %q: %w", policy.Name, err)
+		}
+	}
+
 	result = &v1.Policy{}
 	err = c.client.Post().
 		Resource("policies").
@@ -85,6 +97,9 @@ func (c *policies) Create(ctx context.Context, policy *v1.Policy,
 		Body(policy).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("create policy %q: %w", policy.Name, err)
+	}
 
 	return
 }
@@ -101,31 +116,35 @@ func (c *policies) Update(ctx context.Context, policy *v1.Policy,
 		Body(policy).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("update policy %q: %w", policy.Name, err)
+	}
 
 	return
 }
 
 func (c *policies) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
-	return c.client.Delete().
-		Resource("policies").
-		Name(name).
-		Body(&opts).
-		Do(ctx).
-		Error()
+	return c.DeleteWithOptions(ctx, name, DeleteOptions{DeleteOptions: opts})
+}
+
+// DeleteObject takes the policy object and deletes it by its name. Returns an error if the object is nil
+// or its name is not set.
+func (c *policies) DeleteObject(ctx context.Context, policy *v1.Policy, opts metav1.DeleteOptions) error {
+	if policy == nil {
+		return fmt.Errorf("delete policy: object is nil")
+	}
+
+	name := policy.GetObjectMeta().GetName()
+	if len(name) == 0 {
+		return fmt.Errorf("delete policy: object name is empty")
+	}
+
+	return c.Delete(ctx, name, opts)
 }
 
 // DeleteCollection deletes a collection of objects.
 func (c *policies) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
-	var timeout time.Duration
-	if listOpts.TimeoutSeconds != nil {
-		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
-	}
+	_, err := c.DeleteCollectionWithCount(ctx, opts, listOpts)
 
-	return c.client.Delete().
-		Resource("policies").
-		VersionedParams(listOpts).
-		Timeout(timeout).
-		Body(&opts).
-		Do(ctx).
-		Error()
+	return err
 }
@@ -4,5 +4,196 @@
 
 package v1
 
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
 // The PolicyExpansion interface allows manually adding extra methods to the PolicyInterface.
-type PolicyExpansion interface{}
+type PolicyExpansion interface {
+	// ListNames returns the names of the policies that match the list options. It asks the
+	// server to only return object names, which keeps the response small for large collections.
+	ListNames(ctx context.Context, opts metav1.ListOptions) ([]string, error)
+
+	// Watch returns a watcher streaming the changes to the policies that match the list options.
+	Watch(ctx context.Context, opts metav1.ListOptions) (rest.Watcher, error)
+
+	// ListMetadata returns only the metadata of the policies that match the list options.
+	ListMetadata(ctx context.Context, opts metav1.ListOptions) (*PartialObjectMetadataList, error)
+
+	// UpdateIfChanged updates the policy to desired, unless desired does not differ from current
+	// in which case current is returned without a request to the server.
+	UpdateIfChanged(ctx context.Context, current, desired *v1.Policy, opts metav1.UpdateOptions) (*v1.Policy, error)
+
+	// DeleteCollectionWithCount deletes the policies that match the list options, following the continue
+	// tokens of servers which delete in batches, and returns the number of deleted policies.
+	DeleteCollectionWithCount(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) (int64, error)
+
+	// GetTable returns the policy as a Table, for command line tools to print it.
+	GetTable(ctx context.Context, name string, opts metav1.GetOptions) (*Table, error)
+
+	// ListTable returns the policies that match the list options as a Table, for command line tools to print them.
+	ListTable(ctx context.Context, opts metav1.ListOptions) (*Table, error)
+
+	// ListAll lists all the policies that match the list options, requesting them page by page. A
+	// positive maxItems bounds the number of listed policies, truncated tells whether some were left.
+	ListAll(ctx context.Context, opts metav1.ListOptions, maxItems int64) (result *v1.PolicyList, truncated bool, err error)
+
+	// ListPages lists the policies that match the list options page by page, calling fn with every page
+	// until fn returns false or an error, or the pages are exhausted.
+	ListPages(ctx context.Context, opts metav1.ListOptions, fn func(page *v1.PolicyList) (cont bool, err error)) error
+
+	// GetWithOptions is Get with the options of the IAM API which metav1.GetOptions lacks, eg.
+	// the resource version the policy is read at.
+	GetWithOptions(ctx context.Context, name string, opts GetOptions) (*v1.Policy, error)
+
+	// DeleteWithOptions is Delete with the options of the IAM API which metav1.DeleteOptions
+	// lacks, eg. the preconditions the policy must fulfill to be deleted.
+	DeleteWithOptions(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// GetWithOptions takes name of the policy and the options of the IAM API, and returns the
+// corresponding policy object, and an error if there is any.
+func (c *policies) GetWithOptions(ctx context.Context, name string, opts GetOptions) (result *v1.Policy, err error) {
+	result = &v1.Policy{}
+	err = c.client.Get().
+		Resource("policies").
+		Name(name).
+		VersionedParams(opts).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		err = fmt.Errorf("get policy %q: %w", name, err)
+	}
+
+	return
+}
+
+// DeleteWithOptions takes name of the policy and the options of the IAM API, and deletes it.
+// Returns an error if one occurs, a conflict if the policy doesn't fulfill the preconditions.
+func (c *policies) DeleteWithOptions(ctx context.Context, name string, opts DeleteOptions) error {
+	err := c.client.Delete().
+		Resource("policies").
+		Name(name).
+		VersionedParams(opts.Preconditions).
+		Body(&opts.DeleteOptions).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("delete policy %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// ListNames takes label and field selectors, and returns the names of the policies that match those selectors.
+func (c *policies) ListNames(ctx context.Context, opts metav1.ListOptions) ([]string, error) {
+	return listNames(ctx, c.client, "policies", opts)
+}
+
+// Watch returns a watcher streaming the changes to the policies that match the list options.
+func (c *policies) Watch(ctx context.Context, opts metav1.ListOptions) (rest.Watcher, error) {
+	return c.client.Get().
+		Resource("policies").
+		VersionedParams(opts).
+		Watch(ctx)
+}
+
+// ListMetadata takes label and field selectors, and returns the metadata of the policies that match those selectors.
+func (c *policies) ListMetadata(ctx context.Context, opts metav1.ListOptions) (*PartialObjectMetadataList, error) {
+	return listMetadata(ctx, c.client, "policies", opts)
+}
+
+// UpdateIfChanged takes the current and the desired representation of a policy, and updates it
+// if they differ. Fields populated by the server are not compared.
+func (c *policies) UpdateIfChanged(ctx context.Context, current, desired *v1.Policy,
+	opts metav1.UpdateOptions) (*v1.Policy, error) {
+	ok, err := changed(current, desired)
+	if err != nil {
+		return nil, fmt.Errorf("diff policy %q: %w", desired.Name, err)
+	}
+
+	if !ok {
+		return current, nil
+	}
+
+	return c.Update(ctx, desired, opts)
+}
+
+// DeleteCollectionWithCount deletes the policies that match the list options, and returns the
+// number of policies the server reported as deleted.
+func (c *policies) DeleteCollectionWithCount(ctx context.Context, opts metav1.DeleteOptions,
+	listOpts metav1.ListOptions) (int64, error) {
+	deleted, err := deleteCollection(ctx, c.client, "policies", opts, listOpts)
+	if err != nil {
+		return deleted, fmt.Errorf("delete collection of policies: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// GetTable takes name of the policy, and returns its Table representation. The table is rendered on
+// the client side when the server does not support the representation.
+func (c *policies) GetTable(ctx context.Context, name string, opts metav1.GetOptions) (*Table, error) {
+	return getTable(ctx, c.client, "policies", name, opts)
+}
+
+// ListTable takes label and field selectors, and returns the Table representation of the policies that
+// match those selectors. The table is rendered on the client side when the server does not support
+// the representation.
+func (c *policies) ListTable(ctx context.Context, opts metav1.ListOptions) (*Table, error) {
+	return listTable(ctx, c.client, "policies", opts)
+}
+
+// ListAll takes label and field selectors, and returns all the policies that match those selectors,
+// listed page by page with the Offset and Limit list options. Listing stops without error once
+// maxItems policies are listed, if maxItems is positive, and truncated tells whether some were left.
+func (c *policies) ListAll(ctx context.Context, opts metav1.ListOptions,
+	maxItems int64) (result *v1.PolicyList, truncated bool, err error) {
+	result = &v1.PolicyList{Items: []*v1.Policy{}}
+	truncated, err = listPages(ctx, opts, maxItems, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		result.TotalCount = page.TotalCount
+		result.Items = append(result.Items, page.Items...)
+
+		return len(page.Items), page.TotalCount, true, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// drop the item fetched past the budget to tell whether the listing is truncated
+	if maxItems > 0 && int64(len(result.Items)) > maxItems {
+		result.Items = result.Items[:maxItems]
+	}
+
+	return result, truncated, nil
+}
+
+// ListPages takes label and field selectors, and lists the policies that match those selectors page
+// by page with the Offset and Limit list options, so that only one page is held in memory at a time.
+// fn is called with every page, listing stops once fn returns false or an error, which is returned.
+func (c *policies) ListPages(ctx context.Context, opts metav1.ListOptions,
+	fn func(page *v1.PolicyList) (cont bool, err error)) error {
+	_, err := listPages(ctx, opts, 0, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		cont, err := fn(page)
+
+		return len(page.Items), page.TotalCount, cont, err
+	})
+
+	return err
+}
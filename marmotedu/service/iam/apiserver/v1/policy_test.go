@@ -0,0 +1,60 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+)
+
+func TestPolicyUpdateIfChanged(t *testing.T) {
+	current := &v1.Policy{
+		ObjectMeta: metav1.ObjectMeta{ID: 1, Name: "policy", CreatedAt: time.Now()},
+		Username:   "colin",
+	}
+
+	tests := []struct {
+		name        string
+		desired     *v1.Policy
+		wantUpdates int
+	}{
+		{"no change", &v1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "policy"}, Username: "colin"}, 0},
+		{"changed", &v1.Policy{ObjectMeta: metav1.ObjectMeta{Name: "policy"}, Username: "sdk"}, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var updates int
+			client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodPut && req.URL.Path == "/v1/policies/policy" {
+					updates++
+				}
+				_, _ = w.Write([]byte(`{"metadata":{"id":1,"name":"policy"},"username":"sdk"}`))
+			})
+
+			got, err := client.Policies().UpdateIfChanged(context.TODO(), current, tc.desired, metav1.UpdateOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if updates != tc.wantUpdates {
+				t.Errorf("expected %d updates, got %d", tc.wantUpdates, updates)
+			}
+
+			if tc.wantUpdates == 0 && got != current {
+				t.Error("expected the current object to be returned unchanged")
+			}
+
+			if tc.wantUpdates != 0 && got.Username != "sdk" {
+				t.Errorf("expected the updated object, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+// Preconditions must be fulfilled by an object for the server to delete it, see
+// DeleteOptions. They are sent as query parameters. The server rejects the deletion of an
+// object which does not match them with a conflict, see rest.IsConflict.
+type Preconditions struct {
+	// UID is the instanceID the object must have.
+	UID string `json:"preconditions.uid,omitempty"`
+	// ResourceVersion is the resource version the object must have.
+	ResourceVersion string `json:"preconditions.resourceVersion,omitempty"`
+}
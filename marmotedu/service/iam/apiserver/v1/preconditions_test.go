@@ -0,0 +1,55 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestDeletePreconditions(t *testing.T) {
+	var deleted int
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("preconditions.uid") != "" && query.Get("preconditions.uid") != "user-current" ||
+			query.Get("preconditions.resourceVersion") != "" && query.Get("preconditions.resourceVersion") != "2" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = w.Write([]byte(`{"code":100003,"message":"Precondition failed"}`))
+
+			return
+		}
+
+		deleted++
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	stale := DeleteOptions{Preconditions: Preconditions{UID: "user-current", ResourceVersion: "1"}}
+	err := client.Users().DeleteWithOptions(context.TODO(), "colin", stale)
+	if !rest.IsConflict(err) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+
+	if deleted != 0 {
+		t.Fatalf("expected the user not to be deleted, got %d deletions", deleted)
+	}
+
+	current := DeleteOptions{Preconditions: Preconditions{UID: "user-current", ResourceVersion: "2"}}
+	if err := client.Users().DeleteWithOptions(context.TODO(), "colin", current); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.Secrets().Delete(context.TODO(), "secret", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error without preconditions: %v", err)
+	}
+
+	if deleted != 2 {
+		t.Errorf("expected 2 deletions, got %d", deleted)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// namesProjection is the field projection asking the server to only return object names.
+const namesProjection = "metadata.name"
+
+// nameList decodes both a projected list and a full list, since the object names live
+// under the same metadata field in either representation.
+type nameList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// listNames lists the given resource with a names-only projection and returns the object names.
+// Servers which ignore the projection return full objects, whose names are extracted instead.
+func listNames(ctx context.Context, client rest.Interface, resource string, opts metav1.ListOptions) ([]string, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	list := &nameList{}
+	err := client.Get().
+		Resource(resource).
+		VersionedParams(opts).
+		Param("fields", namesProjection).
+		Timeout(timeout).
+		Do(ctx).
+		Into(list)
+	if err != nil {
+		return nil, fmt.Errorf("list %s names: %w", resource, err)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.Metadata.Name)
+	}
+
+	return names, nil
+}
@@ -6,6 +6,7 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	v1 "github.com/marmotedu/api/apiserver/v1"
@@ -25,6 +26,7 @@ type SecretInterface interface {
 	Create(ctx context.Context, secret *v1.Secret, opts metav1.CreateOptions) (*v1.Secret, error)
 	Update(ctx context.Context, secret *v1.Secret, opts metav1.UpdateOptions) (*v1.Secret, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteObject(ctx context.Context, secret *v1.Secret, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.Secret, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*v1.SecretList, error)
@@ -33,27 +35,21 @@ type SecretInterface interface {
 
 // secrets implements SecretInterface.
 type secrets struct {
-	client rest.Interface
+	client   rest.Interface
+	validate func(secret *v1.Secret) error
 }
 
 // newSecrets returns a Secrets.
 func newSecrets(c *APIV1Client) *secrets {
 	return &secrets{
-		client: c.RESTClient(),
+		client:   c.RESTClient(),
+		validate: c.validators.Secret,
 	}
 }
 
 // Get takes name of the secret, and returns the corresponding secret object, and an error if there is any.
 func (c *secrets) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.Secret, err error) {
-	result = &v1.Secret{}
-	err = c.client.Get().
-		Resource("secrets").
-		Name(name).
-		VersionedParams(options).
-		Do(ctx).
-		Into(result)
-
-	return
+	return c.GetWithOptions(ctx, name, GetOptions{GetOptions: options})
 }
 
 // List takes label and field selectors, and returns the list of Secrets that match those selectors.
@@ -70,6 +66,16 @@ func (c *secrets) List(ctx context.Context, opts metav1.ListOptions) (result *v1
 		Timeout(timeout).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("list secrets: %w", err)
+
+		return
+	}
+
+	// servers may return null items for an empty list, callers get an empty slice either way
+	if result.Items == nil {
+		result.Items = []*v1.Secret{}
+	}
 
 	return
 }
@@ -78,6 +84,12 @@ func (c *secrets) List(ctx context.Context, opts metav1.ListOptions) (result *v1
 // Returns the server's representation of the secret, and an error, if there is any.
 func (c *secrets) Create(ctx context.Context, secret *v1.Secret,
 	opts metav1.CreateOptions) (result *v1.Secret, err error) {
+	if c.validate != nil && !validationSkipped(ctx) {
+		if err = c.validate(secret); err != nil {
+			return nil, fmt.Errorf("create secret %q: %w", secret.Name, err)
+		}
+	}
+
 	result = &v1.Secret{}
 	err = c.client.Post().
 		Resource("secrets").
@@ -85,6 +97,9 @@ func (c *secrets) Create(ctx context.Context, secret *v1.Secret,
 		Body(secret).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("create secret %q: %w", secret.Name, err)
+	}
 
 	return
 }
@@ -101,31 +116,35 @@ func (c *secrets) Update(ctx context.Context, secret *v1.Secret,
 		Body(secret).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("update secret %q: %w", secret.Name, err)
+	}
 
 	return
 }
 
 func (c *secrets) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
-	return c.client.Delete().
-		Resource("secrets").
-		Name(name).
-		Body(&opts).
-		Do(ctx).
-		Error()
+	return c.DeleteWithOptions(ctx, name, DeleteOptions{DeleteOptions: opts})
+}
+
+// DeleteObject takes the secret object and deletes it by its name. Returns an error if the object is nil
+// or its name is not set.
+func (c *secrets) DeleteObject(ctx context.Context, secret *v1.Secret, opts metav1.DeleteOptions) error {
+	if secret == nil {
+		return fmt.Errorf("delete secret: object is nil")
+	}
+
+	name := secret.GetObjectMeta().GetName()
+	if len(name) == 0 {
+		return fmt.Errorf("delete secret: object name is empty")
+	}
+
+	return c.Delete(ctx, name, opts)
 }
 
 // DeleteCollection deletes a collection of objects.
 func (c *secrets) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
-	var timeout time.Duration
-	if listOpts.TimeoutSeconds != nil {
-		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
-	}
+	_, err := c.DeleteCollectionWithCount(ctx, opts, listOpts)
 
-	return c.client.Delete().
-		Resource("secrets").
-		VersionedParams(listOpts).
-		Timeout(timeout).
-		Body(&opts).
-		Do(ctx).
-		Error()
+	return err
 }
@@ -4,5 +4,244 @@
 
 package v1
 
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
 // The SecretExpansion interface allows manually adding extra methods to the SecretInterface.
-type SecretExpansion interface{}
+type SecretExpansion interface {
+	// ListNames returns the names of the secrets that match the list options. It asks the
+	// server to only return object names, which keeps the response small for large collections.
+	ListNames(ctx context.Context, opts metav1.ListOptions) ([]string, error)
+
+	// Watch returns a watcher streaming the changes to the secrets that match the list options.
+	Watch(ctx context.Context, opts metav1.ListOptions) (rest.Watcher, error)
+
+	// ListMetadata returns only the metadata of the secrets that match the list options.
+	ListMetadata(ctx context.Context, opts metav1.ListOptions) (*PartialObjectMetadataList, error)
+
+	// UpdateIfChanged updates the secret to desired, unless desired does not differ from current
+	// in which case current is returned without a request to the server.
+	UpdateIfChanged(ctx context.Context, current, desired *v1.Secret, opts metav1.UpdateOptions) (*v1.Secret, error)
+
+	// DeleteCollectionWithCount deletes the secrets that match the list options, following the continue
+	// tokens of servers which delete in batches, and returns the number of deleted secrets.
+	DeleteCollectionWithCount(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) (int64, error)
+
+	// GetTable returns the secret as a Table, for command line tools to print it.
+	GetTable(ctx context.Context, name string, opts metav1.GetOptions) (*Table, error)
+
+	// ListTable returns the secrets that match the list options as a Table, for command line tools to print them.
+	ListTable(ctx context.Context, opts metav1.ListOptions) (*Table, error)
+
+	// ListAll lists all the secrets that match the list options, requesting them page by page. A
+	// positive maxItems bounds the number of listed secrets, truncated tells whether some were left.
+	ListAll(ctx context.Context, opts metav1.ListOptions, maxItems int64) (result *v1.SecretList, truncated bool, err error)
+
+	// ListPages lists the secrets that match the list options page by page, calling fn with every page
+	// until fn returns false or an error, or the pages are exhausted.
+	ListPages(ctx context.Context, opts metav1.ListOptions, fn func(page *v1.SecretList) (cont bool, err error)) error
+
+	// ListExpired lists all the secrets that match the list options and expired before now.
+	ListExpired(ctx context.Context, now time.Time, opts metav1.ListOptions) (*v1.SecretList, error)
+
+	// GetWithOptions is Get with the options of the IAM API which metav1.GetOptions lacks, eg.
+	// the resource version the secret is read at.
+	GetWithOptions(ctx context.Context, name string, opts GetOptions) (*v1.Secret, error)
+
+	// DeleteWithOptions is Delete with the options of the IAM API which metav1.DeleteOptions
+	// lacks, eg. the preconditions the secret must fulfill to be deleted.
+	DeleteWithOptions(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// GetWithOptions takes name of the secret and the options of the IAM API, and returns the
+// corresponding secret object, and an error if there is any.
+func (c *secrets) GetWithOptions(ctx context.Context, name string, opts GetOptions) (result *v1.Secret, err error) {
+	result = &v1.Secret{}
+	err = c.client.Get().
+		Resource("secrets").
+		Name(name).
+		VersionedParams(opts).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		err = fmt.Errorf("get secret %q: %w", name, err)
+	}
+
+	return
+}
+
+// DeleteWithOptions takes name of the secret and the options of the IAM API, and deletes it.
+// Returns an error if one occurs, a conflict if the secret doesn't fulfill the preconditions.
+func (c *secrets) DeleteWithOptions(ctx context.Context, name string, opts DeleteOptions) error {
+	err := c.client.Delete().
+		Resource("secrets").
+		Name(name).
+		VersionedParams(opts.Preconditions).
+		Body(&opts.DeleteOptions).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("delete secret %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// ListNames takes label and field selectors, and returns the names of the secrets that match those selectors.
+func (c *secrets) ListNames(ctx context.Context, opts metav1.ListOptions) ([]string, error) {
+	return listNames(ctx, c.client, "secrets", opts)
+}
+
+// Watch returns a watcher streaming the changes to the secrets that match the list options.
+func (c *secrets) Watch(ctx context.Context, opts metav1.ListOptions) (rest.Watcher, error) {
+	return c.client.Get().
+		Resource("secrets").
+		VersionedParams(opts).
+		Watch(ctx)
+}
+
+// ListMetadata takes label and field selectors, and returns the metadata of the secrets that match those selectors.
+func (c *secrets) ListMetadata(ctx context.Context, opts metav1.ListOptions) (*PartialObjectMetadataList, error) {
+	return listMetadata(ctx, c.client, "secrets", opts)
+}
+
+// UpdateIfChanged takes the current and the desired representation of a secret, and updates it
+// if they differ. Fields populated by the server are not compared.
+func (c *secrets) UpdateIfChanged(ctx context.Context, current, desired *v1.Secret,
+	opts metav1.UpdateOptions) (*v1.Secret, error) {
+	ok, err := changed(current, desired)
+	if err != nil {
+		return nil, fmt.Errorf("diff secret %q: %w", desired.Name, err)
+	}
+
+	if !ok {
+		return current, nil
+	}
+
+	return c.Update(ctx, desired, opts)
+}
+
+// DeleteCollectionWithCount deletes the secrets that match the list options, and returns the
+// number of secrets the server reported as deleted.
+func (c *secrets) DeleteCollectionWithCount(ctx context.Context, opts metav1.DeleteOptions,
+	listOpts metav1.ListOptions) (int64, error) {
+	deleted, err := deleteCollection(ctx, c.client, "secrets", opts, listOpts)
+	if err != nil {
+		return deleted, fmt.Errorf("delete collection of secrets: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// GetTable takes name of the secret, and returns its Table representation. The table is rendered on
+// the client side when the server does not support the representation.
+func (c *secrets) GetTable(ctx context.Context, name string, opts metav1.GetOptions) (*Table, error) {
+	return getTable(ctx, c.client, "secrets", name, opts)
+}
+
+// ListTable takes label and field selectors, and returns the Table representation of the secrets that
+// match those selectors. The table is rendered on the client side when the server does not support
+// the representation.
+func (c *secrets) ListTable(ctx context.Context, opts metav1.ListOptions) (*Table, error) {
+	return listTable(ctx, c.client, "secrets", opts)
+}
+
+// ListAll takes label and field selectors, and returns all the secrets that match those selectors,
+// listed page by page with the Offset and Limit list options. Listing stops without error once
+// maxItems secrets are listed, if maxItems is positive, and truncated tells whether some were left.
+func (c *secrets) ListAll(ctx context.Context, opts metav1.ListOptions,
+	maxItems int64) (result *v1.SecretList, truncated bool, err error) {
+	result = &v1.SecretList{Items: []*v1.Secret{}}
+	truncated, err = listPages(ctx, opts, maxItems, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		result.TotalCount = page.TotalCount
+		result.Items = append(result.Items, page.Items...)
+
+		return len(page.Items), page.TotalCount, true, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// drop the item fetched past the budget to tell whether the listing is truncated
+	if maxItems > 0 && int64(len(result.Items)) > maxItems {
+		result.Items = result.Items[:maxItems]
+	}
+
+	return result, truncated, nil
+}
+
+// ListPages takes label and field selectors, and lists the secrets that match those selectors page
+// by page with the Offset and Limit list options, so that only one page is held in memory at a time.
+// fn is called with every page, listing stops once fn returns false or an error, which is returned.
+func (c *secrets) ListPages(ctx context.Context, opts metav1.ListOptions,
+	fn func(page *v1.SecretList) (cont bool, err error)) error {
+	_, err := listPages(ctx, opts, 0, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		cont, err := fn(page)
+
+		return len(page.Items), page.TotalCount, cont, err
+	})
+
+	return err
+}
+
+// ListExpired takes label and field selectors, and returns all the secrets that match those
+// selectors and expired before now, listed page by page. The server is asked to only return them
+// with the expiresBefore parameter, and the pages are filtered on the Expires field as well for
+// servers which ignore it. Secrets which never expire, with a zero Expires, are not listed.
+func (c *secrets) ListExpired(ctx context.Context, now time.Time, opts metav1.ListOptions) (*v1.SecretList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	expiresBefore := now.Unix()
+	result := &v1.SecretList{Items: []*v1.Secret{}}
+
+	_, err := listPages(ctx, opts, 0, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page := &v1.SecretList{}
+		err := c.client.Get().
+			Resource("secrets").
+			VersionedParams(opts).
+			Param("expiresBefore", strconv.FormatInt(expiresBefore, 10)).
+			Timeout(timeout).
+			Do(ctx).
+			Into(page)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		for _, secret := range page.Items {
+			if secret.Expires != 0 && secret.Expires < expiresBefore {
+				result.Items = append(result.Items, secret)
+			}
+		}
+
+		return len(page.Items), page.TotalCount, true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list expired secrets: %w", err)
+	}
+
+	result.TotalCount = int64(len(result.Items))
+
+	return result, nil
+}
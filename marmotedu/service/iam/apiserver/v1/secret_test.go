@@ -0,0 +1,112 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+)
+
+func TestSecretListExpired(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	// 150 secrets: every third never expires, the others alternate between expired and valid
+	var secrets []*v1.Secret
+	for i := 0; i < 150; i++ {
+		secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("secret-%d", i)}}
+		switch {
+		case i%3 == 0:
+		case i%2 == 0:
+			secret.Expires = now.Add(-time.Hour).Unix()
+		default:
+			secret.Expires = now.Add(time.Hour).Unix()
+		}
+
+		secrets = append(secrets, secret)
+	}
+
+	var wantExpired int
+	for _, secret := range secrets {
+		if secret.Expires != 0 && secret.Expires < now.Unix() {
+			wantExpired++
+		}
+	}
+
+	tests := []struct {
+		name         string
+		serverFilter bool
+	}{
+		{"server filter", true},
+		{"client filter", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+				requests++
+
+				query := req.URL.Query()
+				if query.Get("expiresBefore") != strconv.FormatInt(now.Unix(), 10) {
+					t.Errorf("unexpected expiresBefore %q", query.Get("expiresBefore"))
+				}
+
+				matching := secrets
+				if tc.serverFilter {
+					matching = nil
+					for _, secret := range secrets {
+						if secret.Expires != 0 && secret.Expires < now.Unix() {
+							matching = append(matching, secret)
+						}
+					}
+				}
+
+				offset, _ := strconv.Atoi(query.Get("offset"))
+				limit, _ := strconv.Atoi(query.Get("limit"))
+
+				list := &v1.SecretList{ListMeta: metav1.ListMeta{TotalCount: int64(len(matching))}}
+				for i := offset; i < offset+limit && i < len(matching); i++ {
+					list.Items = append(list.Items, matching[i])
+				}
+
+				if err := json.NewEncoder(w).Encode(list); err != nil {
+					t.Error(err)
+				}
+			})
+
+			list, err := client.Secrets().ListExpired(context.TODO(), now, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(list.Items) != wantExpired || list.TotalCount != int64(wantExpired) {
+				t.Errorf("expected %d expired secrets, got %d (total %d)", wantExpired, len(list.Items), list.TotalCount)
+			}
+
+			for _, secret := range list.Items {
+				if secret.Expires == 0 || secret.Expires >= now.Unix() {
+					t.Errorf("unexpected secret %s expiring at %d", secret.Name, secret.Expires)
+				}
+			}
+
+			wantRequests := 2
+			if tc.serverFilter {
+				wantRequests = 1
+			}
+
+			if requests != wantRequests {
+				t.Errorf("expected %d requests, got %d", wantRequests, requests)
+			}
+		})
+	}
+}
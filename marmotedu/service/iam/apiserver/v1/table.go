@@ -0,0 +1,176 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// Table is the tabular representation of a set of objects, as printed by command line tools.
+type Table struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Standard list metadata.
+	metav1.ListMeta `json:",inline"`
+
+	// ColumnDefinitions describes the columns of every row.
+	ColumnDefinitions []TableColumnDefinition `json:"columnDefinitions"`
+	// Rows holds one row for every object.
+	Rows []TableRow `json:"rows"`
+}
+
+// TableColumnDefinition describes a column of a Table.
+type TableColumnDefinition struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Format      string `json:"format,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// TableRow is a row of a Table, holding a cell for every column.
+type TableRow struct {
+	Cells []interface{} `json:"cells"`
+}
+
+// Print writes the table to w with aligned columns, headed by the upper-cased column names.
+func (t *Table) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+
+	headers := make([]string, 0, len(t.ColumnDefinitions))
+	for _, column := range t.ColumnDefinitions {
+		headers = append(headers, strings.ToUpper(column.Name))
+	}
+
+	if _, err := fmt.Fprintln(tw, strings.Join(headers, "\t")); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		cells := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			cells = append(cells, fmt.Sprint(cell))
+		}
+
+		if _, err := fmt.Fprintln(tw, strings.Join(cells, "\t")); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// metadataColumns are the columns of the tables rendered on the client side.
+var metadataColumns = []TableColumnDefinition{
+	{Name: "Name", Type: "string", Description: "Name of the object."},
+	{Name: "ID", Type: "integer", Description: "Identifier of the object."},
+	{Name: "Created", Type: "string", Format: "date-time", Description: "Creation time of the object."},
+}
+
+// metadataRow renders the row of an object on the client side.
+func metadataRow(meta metav1.ObjectMeta) TableRow {
+	return TableRow{Cells: []interface{}{meta.Name, meta.ID, meta.CreatedAt.Format(time.RFC3339)}}
+}
+
+// tableResponse tells a Table apart from the full representation of an object or list.
+type tableResponse struct {
+	Kind              string            `json:"kind"`
+	ColumnDefinitions []json.RawMessage `json:"columnDefinitions"`
+}
+
+// getTable gets the named object of the given resource as a Table.
+func getTable(ctx context.Context, client rest.Interface, resource, name string,
+	opts metav1.GetOptions) (*Table, error) {
+	result := client.Get().
+		Resource(resource).
+		Name(name).
+		VersionedParams(opts).
+		SetHeader("Accept", rest.TableAcceptContentTypes).
+		Do(ctx)
+
+	table, err := tableFrom(result, func() (*Table, error) {
+		object := &PartialObjectMetadata{}
+		if err := result.Into(object); err != nil {
+			return nil, err
+		}
+
+		return &Table{ColumnDefinitions: metadataColumns, Rows: []TableRow{metadataRow(object.ObjectMeta)}}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get %s %q table: %w", resource, name, err)
+	}
+
+	return table, nil
+}
+
+// listTable lists the given resource as a Table.
+func listTable(ctx context.Context, client rest.Interface, resource string, opts metav1.ListOptions) (*Table, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+
+	result := client.Get().
+		Resource(resource).
+		VersionedParams(opts).
+		SetHeader("Accept", rest.TableAcceptContentTypes).
+		Timeout(timeout).
+		Do(ctx)
+
+	table, err := tableFrom(result, func() (*Table, error) {
+		list := &PartialObjectMetadataList{}
+		if err := result.Into(list); err != nil {
+			return nil, err
+		}
+
+		table := &Table{ListMeta: list.ListMeta, ColumnDefinitions: metadataColumns, Rows: []TableRow{}}
+		for _, item := range list.Items {
+			table.Rows = append(table.Rows, metadataRow(item.ObjectMeta))
+		}
+
+		return table, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list %s table: %w", resource, err)
+	}
+
+	return table, nil
+}
+
+// tableFrom decodes the Table returned by the server, or renders one with render when the
+// server does not support the Table representation and returned the full one instead. The
+// response is decoded with encoding/json rather than the decoder of the client, as it only
+// partially matches tableResponse and Config.StrictDecoding would reject it.
+func tableFrom(result rest.Result, render func() (*Table, error)) (*Table, error) {
+	body, err := result.Raw()
+	if err != nil {
+		return nil, result.Error()
+	}
+
+	response := &tableResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, err
+	}
+
+	if response.Kind != "Table" && len(response.ColumnDefinitions) == 0 {
+		return render()
+	}
+
+	table := &Table{}
+	if err := json.Unmarshal(body, table); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
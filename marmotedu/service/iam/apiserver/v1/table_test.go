@@ -0,0 +1,165 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+const serverTable = `{"kind":"Table","totalCount":2,` +
+	`"columnDefinitions":[{"name":"Name","type":"string"},{"name":"Email","type":"string"}],` +
+	`"rows":[{"cells":["colin","colin@foxmail.com"]},{"cells":["sdk","sdk@foxmail.com"]}]}`
+
+func TestUserListTable(t *testing.T) {
+	var accept string
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		accept = req.Header.Get("Accept")
+		_, _ = w.Write([]byte(serverTable))
+	})
+
+	table, err := client.Users().ListTable(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if accept != rest.TableAcceptContentTypes {
+		t.Errorf("expected Accept %q, got %q", rest.TableAcceptContentTypes, accept)
+	}
+
+	if table.TotalCount != 2 || len(table.ColumnDefinitions) != 2 || len(table.Rows) != 2 {
+		t.Fatalf("unexpected table %+v", table)
+	}
+
+	if table.Rows[1].Cells[1] != "sdk@foxmail.com" {
+		t.Errorf("expected the email of sdk, got %v", table.Rows[1].Cells[1])
+	}
+
+	var out bytes.Buffer
+	if err := table.Print(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "NAME    EMAIL\n" +
+		"colin   colin@foxmail.com\n" +
+		"sdk     sdk@foxmail.com\n"
+	if out.String() != want {
+		t.Errorf("expected output\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+func TestTableClientSideFallback(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/colin") {
+			_, _ = w.Write([]byte(`{"metadata":{"id":1,"name":"colin","createdAt":"2020-09-01T10:00:00Z"},"nickname":"colin"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"totalCount":1,"items":[` +
+			`{"metadata":{"id":2,"name":"sdk","createdAt":"2020-09-02T10:00:00Z"},"policy":{}}]}`))
+	})
+
+	table, err := client.Users().GetTable(context.TODO(), "colin", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(table.Rows) != 1 || table.Rows[0].Cells[0] != "colin" || table.Rows[0].Cells[2] != "2020-09-01T10:00:00Z" {
+		t.Errorf("unexpected client-side table %+v", table)
+	}
+
+	table, err = client.Policies().ListTable(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if table.TotalCount != 1 || len(table.Rows) != 1 || table.Rows[0].Cells[0] != "sdk" {
+		t.Errorf("unexpected client-side table %+v", table)
+	}
+
+	if len(table.ColumnDefinitions) != len(table.Rows[0].Cells) {
+		t.Errorf("expected a cell for every column, got %+v", table)
+	}
+}
+
+func TestTableResponseCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the server doesn't tell the representations apart in its ETag
+		if req.Header.Get("If-None-Match") == `"1"` {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", `"1"`)
+
+		if strings.Contains(req.Header.Get("Accept"), "as=Table") {
+			_, _ = w.Write([]byte(serverTable))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"metadata":{"name":"colin"},"nickname":"colin"}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{Host: srv.URL, EnableResponseCache: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		user, err := client.Users().Get(context.TODO(), "colin", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if user.Nickname != "colin" {
+			t.Errorf("expected the user colin, got %+v", user)
+		}
+
+		table, err := client.Users().GetTable(context.TODO(), "colin", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if table.TotalCount != 2 || len(table.Rows) != 2 {
+			t.Errorf("expected the server table, got %+v", table)
+		}
+	}
+}
+
+func TestTableStrictDecoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(serverTable))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{
+		Host:          srv.URL,
+		ContentConfig: rest.ContentConfig{StrictDecoding: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	table, err := client.Users().ListTable(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if table.TotalCount != 2 || len(table.Rows) != 2 {
+		t.Errorf("unexpected table %+v", table)
+	}
+}
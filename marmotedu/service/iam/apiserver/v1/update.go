@@ -0,0 +1,92 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+	"reflect"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	"github.com/marmotedu/marmotedu-sdk-go/pkg/util/diff"
+)
+
+// systemFields are the fields populated by the server, which are not compared when deciding
+// whether an object has to be updated.
+var systemFields = []string{
+	"metadata.id",
+	"metadata.instanceID",
+	"metadata.createdAt",
+	"metadata.updatedAt",
+}
+
+// changed returns whether desired differs from current in any field not populated by the server.
+func changed(current, desired interface{}) (bool, error) {
+	equal, err := diff.Equal(current, desired, systemFields...)
+	if err != nil {
+		return false, err
+	}
+
+	return !equal, nil
+}
+
+// MergeServerDefaults copies the fields populated by the server from remote, a freshly fetched
+// copy of the object, into local, a partially specified edit of it, so that updating local
+// does not reset them. Both objects must be of the same type, one of *v1.User, *v1.Secret and
+// *v1.Policy, and not nil.
+func MergeServerDefaults(local, remote interface{}) error {
+	for _, obj := range []interface{}{local, remote} {
+		if value := reflect.ValueOf(obj); value.Kind() == reflect.Ptr && value.IsNil() {
+			return fmt.Errorf("cannot merge server defaults of %T into %T: nil object", remote, local)
+		}
+	}
+
+	switch l := local.(type) {
+	case *v1.User:
+		r, ok := remote.(*v1.User)
+		if !ok {
+			return mismatchedTypes(local, remote)
+		}
+
+		mergeObjectMeta(&l.ObjectMeta, &r.ObjectMeta)
+		l.TotalPolicy = r.TotalPolicy
+		l.LoginedAt = r.LoginedAt
+	case *v1.Secret:
+		r, ok := remote.(*v1.Secret)
+		if !ok {
+			return mismatchedTypes(local, remote)
+		}
+
+		mergeObjectMeta(&l.ObjectMeta, &r.ObjectMeta)
+		l.Username = r.Username
+		l.SecretID = r.SecretID
+		l.SecretKey = r.SecretKey
+	case *v1.Policy:
+		r, ok := remote.(*v1.Policy)
+		if !ok {
+			return mismatchedTypes(local, remote)
+		}
+
+		mergeObjectMeta(&l.ObjectMeta, &r.ObjectMeta)
+		l.Username = r.Username
+	default:
+		return fmt.Errorf("cannot merge server defaults into %T", local)
+	}
+
+	return nil
+}
+
+// mergeObjectMeta copies the metadata fields listed in systemFields from remote into local.
+func mergeObjectMeta(local, remote *metav1.ObjectMeta) {
+	local.ID = remote.ID
+	local.InstanceID = remote.InstanceID
+	local.CreatedAt = remote.CreatedAt
+	local.UpdatedAt = remote.UpdatedAt
+}
+
+func mismatchedTypes(local, remote interface{}) error {
+	return fmt.Errorf("cannot merge server defaults of %T into %T", remote, local)
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+)
+
+func TestMergeServerDefaults(t *testing.T) {
+	createdAt := time.Date(2020, 10, 1, 8, 0, 0, 0, time.UTC)
+	updatedAt := createdAt.Add(time.Hour)
+	remoteMeta := metav1.ObjectMeta{
+		ID:         10,
+		InstanceID: "user-xyz",
+		Name:       "colin",
+		CreatedAt:  createdAt,
+		UpdatedAt:  updatedAt,
+	}
+
+	t.Run("user", func(t *testing.T) {
+		remote := &v1.User{
+			ObjectMeta:  remoteMeta,
+			Nickname:    "colin",
+			Email:       "colin@foxmail.com",
+			TotalPolicy: 3,
+			LoginedAt:   updatedAt,
+		}
+		local := &v1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "colin"},
+			Nickname:   "colin404",
+			Email:      "colin404@foxmail.com",
+		}
+
+		if err := MergeServerDefaults(local, remote); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if local.ID != 10 || local.InstanceID != "user-xyz" ||
+			!local.CreatedAt.Equal(createdAt) || !local.UpdatedAt.Equal(updatedAt) {
+			t.Errorf("expected the server metadata to survive, got %+v", local.ObjectMeta)
+		}
+
+		if local.TotalPolicy != 3 || !local.LoginedAt.Equal(updatedAt) {
+			t.Errorf("expected the server fields to survive, got %+v", local)
+		}
+
+		if local.Nickname != "colin404" || local.Email != "colin404@foxmail.com" {
+			t.Errorf("expected the local edits to be kept, got %+v", local)
+		}
+
+		changedFields, err := changed(remote, local)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !changedFields {
+			t.Error("expected the merged user to differ from the remote one")
+		}
+	})
+
+	t.Run("secret", func(t *testing.T) {
+		remote := &v1.Secret{
+			ObjectMeta:  remoteMeta,
+			Username:    "colin",
+			SecretID:    "id",
+			SecretKey:   "key",
+			Expires:     100,
+			Description: "old",
+		}
+		local := &v1.Secret{
+			ObjectMeta:  metav1.ObjectMeta{Name: "colin"},
+			Expires:     200,
+			Description: "new",
+		}
+
+		if err := MergeServerDefaults(local, remote); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if local.ID != 10 || local.InstanceID != "user-xyz" ||
+			!local.CreatedAt.Equal(createdAt) || !local.UpdatedAt.Equal(updatedAt) {
+			t.Errorf("expected the server metadata to survive, got %+v", local.ObjectMeta)
+		}
+
+		if local.Username != "colin" || local.SecretID != "id" || local.SecretKey != "key" {
+			t.Errorf("expected the server fields to survive, got %+v", local)
+		}
+
+		if local.Expires != 200 || local.Description != "new" {
+			t.Errorf("expected the local edits to be kept, got %+v", local)
+		}
+	})
+
+	t.Run("mismatched types", func(t *testing.T) {
+		if err := MergeServerDefaults(&v1.User{}, &v1.Secret{}); err == nil {
+			t.Error("expected an error merging a secret into a user")
+		}
+
+		if err := MergeServerDefaults(v1.User{}, v1.User{}); err == nil {
+			t.Error("expected an error merging non pointer objects")
+		}
+	})
+
+	t.Run("nil objects", func(t *testing.T) {
+		var user *v1.User
+		if err := MergeServerDefaults(user, &v1.User{}); err == nil {
+			t.Error("expected an error merging into a nil user")
+		}
+
+		if err := MergeServerDefaults(&v1.User{}, user); err == nil {
+			t.Error("expected an error merging from a nil user")
+		}
+
+		if err := MergeServerDefaults(nil, nil); err == nil {
+			t.Error("expected an error merging nil interfaces")
+		}
+	})
+}
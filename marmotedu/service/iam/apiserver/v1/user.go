@@ -6,6 +6,7 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	v1 "github.com/marmotedu/api/apiserver/v1"
@@ -25,6 +26,7 @@ type UserInterface interface {
 	Create(ctx context.Context, user *v1.User, opts metav1.CreateOptions) (*v1.User, error)
 	Update(ctx context.Context, user *v1.User, opts metav1.UpdateOptions) (*v1.User, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	DeleteObject(ctx context.Context, user *v1.User, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.User, error)
 	List(ctx context.Context, opts metav1.ListOptions) (*v1.UserList, error)
@@ -33,27 +35,21 @@ type UserInterface interface {
 
 // users implements UserInterface.
 type users struct {
-	client rest.Interface
+	client   rest.Interface
+	validate func(user *v1.User) error
 }
 
 // newUsers returns a Users.
 func newUsers(c *APIV1Client) *users {
 	return &users{
-		client: c.RESTClient(),
+		client:   c.RESTClient(),
+		validate: c.validators.User,
 	}
 }
 
 // Get takes name of the user, and returns the corresponding user object, and an error if there is any.
 func (c *users) Get(ctx context.Context, name string, options metav1.GetOptions) (result *v1.User, err error) {
-	result = &v1.User{}
-	err = c.client.Get().
-		Resource("users").
-		Name(name).
-		VersionedParams(options).
-		Do(ctx).
-		Into(result)
-
-	return
+	return c.GetWithOptions(ctx, name, GetOptions{GetOptions: options})
 }
 
 // List takes label and field selectors, and returns the list of Users that match those selectors.
@@ -70,6 +66,16 @@ func (c *users) List(ctx context.Context, opts metav1.ListOptions) (result *v1.U
 		Timeout(timeout).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("list users: %w", err)
+
+		return
+	}
+
+	// servers may return null items for an empty list, callers get an empty slice either way
+	if result.Items == nil {
+		result.Items = []*v1.User{}
+	}
 
 	return
 }
@@ -77,6 +83,12 @@ func (c *users) List(ctx context.Context, opts metav1.ListOptions) (result *v1.U
 // Create takes the representation of a user and creates it.
 // Returns the server's representation of the user, and an error, if there is any.
 func (c *users) Create(ctx context.Context, user *v1.User, opts metav1.CreateOptions) (result *v1.User, err error) {
+	if c.validate != nil && !validationSkipped(ctx) {
+		if err = c.validate(user); err != nil {
+			return nil, fmt.Errorf("create user %q: %w", user.Name, err)
+		}
+	}
+
 	result = &v1.User{}
 	err = c.client.Post().
 		Resource("users").
@@ -84,6 +96,9 @@ func (c *users) Create(ctx context.Context, user *v1.User, opts metav1.CreateOpt
 		Body(user).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("create user %q: %w", user.Name, err)
+	}
 
 	return
 }
@@ -99,31 +114,35 @@ func (c *users) Update(ctx context.Context, user *v1.User, opts metav1.UpdateOpt
 		Body(user).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("update user %q: %w", user.Name, err)
+	}
 
 	return
 }
 
 func (c *users) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
-	return c.client.Delete().
-		Resource("users").
-		Name(name).
-		Body(&opts).
-		Do(ctx).
-		Error()
+	return c.DeleteWithOptions(ctx, name, DeleteOptions{DeleteOptions: opts})
+}
+
+// DeleteObject takes the user object and deletes it by its name. Returns an error if the object is nil
+// or its name is not set.
+func (c *users) DeleteObject(ctx context.Context, user *v1.User, opts metav1.DeleteOptions) error {
+	if user == nil {
+		return fmt.Errorf("delete user: object is nil")
+	}
+
+	name := user.GetObjectMeta().GetName()
+	if len(name) == 0 {
+		return fmt.Errorf("delete user: object name is empty")
+	}
+
+	return c.Delete(ctx, name, opts)
 }
 
 // DeleteCollection deletes a collection of objects.
 func (c *users) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
-	var timeout time.Duration
-	if listOpts.TimeoutSeconds != nil {
-		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
-	}
+	_, err := c.DeleteCollectionWithCount(ctx, opts, listOpts)
 
-	return c.client.Delete().
-		Resource("users").
-		VersionedParams(listOpts).
-		Timeout(timeout).
-		Body(&opts).
-		Do(ctx).
-		Error()
+	return err
 }
@@ -4,10 +4,109 @@
 
 package v1
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	rest "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
 // The UserExpansion interface allows manually adding extra methods to the UserInterface.
 type UserExpansion interface { // PatchStatus modifies the status of an existing node. It returns the copy
 	// of the node that the server returns, or an error.
 	// PatchStatus(ctx context.Context, nodeName string, data []byte) (*v1.Node, error)
+
+	// ListNames returns the names of the users that match the list options. It asks the
+	// server to only return object names, which keeps the response small for large collections.
+	ListNames(ctx context.Context, opts metav1.ListOptions) ([]string, error)
+
+	// Watch returns a watcher streaming the changes to the users that match the list options.
+	Watch(ctx context.Context, opts metav1.ListOptions) (rest.Watcher, error)
+
+	// ListMetadata returns only the metadata of the users that match the list options.
+	ListMetadata(ctx context.Context, opts metav1.ListOptions) (*PartialObjectMetadataList, error)
+
+	// UpdateIfChanged updates the user to desired, unless desired does not differ from current
+	// in which case current is returned without a request to the server.
+	UpdateIfChanged(ctx context.Context, current, desired *v1.User, opts metav1.UpdateOptions) (*v1.User, error)
+
+	// DeleteCollectionWithCount deletes the users that match the list options, following the continue
+	// tokens of servers which delete in batches, and returns the number of deleted users.
+	DeleteCollectionWithCount(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) (int64, error)
+
+	// GetTable returns the user as a Table, for command line tools to print it.
+	GetTable(ctx context.Context, name string, opts metav1.GetOptions) (*Table, error)
+
+	// ListTable returns the users that match the list options as a Table, for command line tools to print them.
+	ListTable(ctx context.Context, opts metav1.ListOptions) (*Table, error)
+
+	// ListAll lists all the users that match the list options, requesting them page by page. A
+	// positive maxItems bounds the number of listed users, truncated tells whether some were left.
+	ListAll(ctx context.Context, opts metav1.ListOptions, maxItems int64) (result *v1.UserList, truncated bool, err error)
+
+	// ListPages lists the users that match the list options page by page, calling fn with every page
+	// until fn returns false or an error, or the pages are exhausted.
+	ListPages(ctx context.Context, opts metav1.ListOptions, fn func(page *v1.UserList) (cont bool, err error)) error
+
+	// Disable soft-deletes the user, for servers which keep disabled users rather than deleting them.
+	Disable(ctx context.Context, name string, opts UserStatusOptions) (*v1.User, error)
+
+	// Enable restores a user disabled by Disable.
+	Enable(ctx context.Context, name string, opts UserStatusOptions) (*v1.User, error)
+
+	// GetWithOptions is Get with the options of the IAM API which metav1.GetOptions lacks, eg.
+	// the resource version the user is read at.
+	GetWithOptions(ctx context.Context, name string, opts GetOptions) (*v1.User, error)
+
+	// DeleteWithOptions is Delete with the options of the IAM API which metav1.DeleteOptions
+	// lacks, eg. the preconditions the user must fulfill to be deleted.
+	DeleteWithOptions(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// GetWithOptions takes name of the user and the options of the IAM API, and returns the
+// corresponding user object, and an error if there is any.
+func (c *users) GetWithOptions(ctx context.Context, name string, opts GetOptions) (result *v1.User, err error) {
+	result = &v1.User{}
+	err = c.client.Get().
+		Resource("users").
+		Name(name).
+		VersionedParams(opts).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		err = fmt.Errorf("get user %q: %w", name, err)
+	}
+
+	return
+}
+
+// DeleteWithOptions takes name of the user and the options of the IAM API, and deletes it.
+// Returns an error if one occurs, a conflict if the user doesn't fulfill the preconditions.
+func (c *users) DeleteWithOptions(ctx context.Context, name string, opts DeleteOptions) error {
+	err := c.client.Delete().
+		Resource("users").
+		Name(name).
+		VersionedParams(opts.Preconditions).
+		Body(&opts.DeleteOptions).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("delete user %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// UserStatusOptions may be provided when disabling or enabling a user.
+type UserStatusOptions struct {
+	metav1.UpdateOptions `json:",inline"`
+
+	// Verb is the HTTP method of the request, POST if not set, for servers which expect another one.
+	Verb string `json:"-"`
 }
 
 /*
@@ -25,3 +124,151 @@ func (c *nodes) PatchStatus(ctx context.Context, nodeName string, data []byte) (
 	return result, err
 }
 */
+
+// ListNames takes label and field selectors, and returns the names of the users that match those selectors.
+func (c *users) ListNames(ctx context.Context, opts metav1.ListOptions) ([]string, error) {
+	return listNames(ctx, c.client, "users", opts)
+}
+
+// Watch returns a watcher streaming the changes to the users that match the list options.
+func (c *users) Watch(ctx context.Context, opts metav1.ListOptions) (rest.Watcher, error) {
+	return c.client.Get().
+		Resource("users").
+		VersionedParams(opts).
+		Watch(ctx)
+}
+
+// ListMetadata takes label and field selectors, and returns the metadata of the users that match those selectors.
+func (c *users) ListMetadata(ctx context.Context, opts metav1.ListOptions) (*PartialObjectMetadataList, error) {
+	return listMetadata(ctx, c.client, "users", opts)
+}
+
+// UpdateIfChanged takes the current and the desired representation of a user, and updates it
+// if they differ. Fields populated by the server are not compared.
+func (c *users) UpdateIfChanged(ctx context.Context, current, desired *v1.User,
+	opts metav1.UpdateOptions) (*v1.User, error) {
+	ok, err := changed(current, desired)
+	if err != nil {
+		return nil, fmt.Errorf("diff user %q: %w", desired.Name, err)
+	}
+
+	if !ok {
+		return current, nil
+	}
+
+	return c.Update(ctx, desired, opts)
+}
+
+// DeleteCollectionWithCount deletes the users that match the list options, and returns the
+// number of users the server reported as deleted.
+func (c *users) DeleteCollectionWithCount(ctx context.Context, opts metav1.DeleteOptions,
+	listOpts metav1.ListOptions) (int64, error) {
+	deleted, err := deleteCollection(ctx, c.client, "users", opts, listOpts)
+	if err != nil {
+		return deleted, fmt.Errorf("delete collection of users: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// GetTable takes name of the user, and returns its Table representation. The table is rendered on
+// the client side when the server does not support the representation.
+func (c *users) GetTable(ctx context.Context, name string, opts metav1.GetOptions) (*Table, error) {
+	return getTable(ctx, c.client, "users", name, opts)
+}
+
+// ListTable takes label and field selectors, and returns the Table representation of the users that
+// match those selectors. The table is rendered on the client side when the server does not support
+// the representation.
+func (c *users) ListTable(ctx context.Context, opts metav1.ListOptions) (*Table, error) {
+	return listTable(ctx, c.client, "users", opts)
+}
+
+// ListAll takes label and field selectors, and returns all the users that match those selectors,
+// listed page by page with the Offset and Limit list options. Listing stops without error once
+// maxItems users are listed, if maxItems is positive, and truncated tells whether some were left.
+func (c *users) ListAll(ctx context.Context, opts metav1.ListOptions,
+	maxItems int64) (result *v1.UserList, truncated bool, err error) {
+	result = &v1.UserList{Items: []*v1.User{}}
+	truncated, err = listPages(ctx, opts, maxItems, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		result.TotalCount = page.TotalCount
+		result.Items = append(result.Items, page.Items...)
+
+		return len(page.Items), page.TotalCount, true, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// drop the item fetched past the budget to tell whether the listing is truncated
+	if maxItems > 0 && int64(len(result.Items)) > maxItems {
+		result.Items = result.Items[:maxItems]
+	}
+
+	return result, truncated, nil
+}
+
+// ListPages takes label and field selectors, and lists the users that match those selectors page
+// by page with the Offset and Limit list options, so that only one page is held in memory at a time.
+// fn is called with every page, listing stops once fn returns false or an error, which is returned.
+func (c *users) ListPages(ctx context.Context, opts metav1.ListOptions,
+	fn func(page *v1.UserList) (cont bool, err error)) error {
+	_, err := listPages(ctx, opts, 0, func(opts metav1.ListOptions) (int, int64, bool, error) {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		cont, err := fn(page)
+
+		return len(page.Items), page.TotalCount, cont, err
+	})
+
+	return err
+}
+
+// Disable takes name of the user, and soft-deletes it with a request to its disable custom
+// method, eg. POST /v1/users/colin:disable. Returns the server's representation of the user.
+func (c *users) Disable(ctx context.Context, name string, opts UserStatusOptions) (*v1.User, error) {
+	result, err := c.setStatus(ctx, name, "disable", opts)
+	if err != nil {
+		return nil, fmt.Errorf("disable user %q: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// Enable takes name of the user, and restores it with a request to its enable custom method,
+// eg. POST /v1/users/colin:enable. Returns the server's representation of the user.
+func (c *users) Enable(ctx context.Context, name string, opts UserStatusOptions) (*v1.User, error) {
+	result, err := c.setStatus(ctx, name, "enable", opts)
+	if err != nil {
+		return nil, fmt.Errorf("enable user %q: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// setStatus sends a request to the given custom method of the user, which the server expects
+// after a colon in the name segment of the path, eg. users/colin:disable.
+func (c *users) setStatus(ctx context.Context, name, method string, opts UserStatusOptions) (*v1.User, error) {
+	verb := opts.Verb
+	if len(verb) == 0 {
+		verb = http.MethodPost
+	}
+
+	result := &v1.User{}
+	err := c.client.Verb(verb).
+		Resource("users").
+		Name(name + ":" + method).
+		VersionedParams(opts).
+		Do(ctx).
+		Into(result)
+
+	return result, err
+}
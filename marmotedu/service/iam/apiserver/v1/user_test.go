@@ -0,0 +1,512 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) *APIV1Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return client
+}
+
+func TestUserErrorsCarryContext(t *testing.T) {
+	serverErr := `{"code":110001,"message":"User not found"}`
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(serverErr))
+	})
+
+	_, err := client.Users().Get(context.TODO(), "sdk", metav1.GetOptions{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if !strings.HasPrefix(err.Error(), `get user "sdk": `) {
+		t.Errorf("expected error to carry the operation context, got %q", err.Error())
+	}
+
+	if cause := errors.Unwrap(err); cause == nil || cause.Error() != serverErr {
+		t.Errorf("expected wrapped server error %q, got %v", serverErr, cause)
+	}
+
+	var statusErr *rest.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected the wrapped error to be a *rest.StatusError, got %T", errors.Unwrap(err))
+	}
+
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the status code %d, got %d", http.StatusNotFound, statusErr.StatusCode)
+	}
+
+	err = client.Users().Delete(context.TODO(), "sdk", metav1.DeleteOptions{})
+	if err == nil || !strings.HasPrefix(err.Error(), `delete user "sdk": `) {
+		t.Errorf("expected error to carry the operation context, got %v", err)
+	}
+}
+
+func TestUserGetWithOptions(t *testing.T) {
+	var query string
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.RawQuery
+		_, _ = w.Write([]byte(`{"metadata":{"name":"colin"}}`))
+	})
+
+	user, err := client.Users().GetWithOptions(context.TODO(), "colin", GetOptions{ResourceVersion: "123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.Name != "colin" {
+		t.Errorf("expected the user colin, got %+v", user)
+	}
+
+	if query != "resourceVersion=123" {
+		t.Errorf("expected the query %q, got %q", "resourceVersion=123", query)
+	}
+
+	if _, err := client.Users().Get(context.TODO(), "colin", metav1.GetOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if query != "" {
+		t.Errorf("expected no query, got %q", query)
+	}
+}
+
+func TestUserListNames(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"projected response", `{"totalCount":2,"items":[{"metadata":{"name":"colin"}},{"metadata":{"name":"sdk"}}]}`},
+		{"projection ignored", `{"totalCount":2,"items":[` +
+			`{"metadata":{"id":1,"name":"colin"},"nickname":"colin","email":"colin@foxmail.com"},` +
+			`{"metadata":{"id":2,"name":"sdk"},"nickname":"sdk","email":"sdk@foxmail.com"}]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+				if got := req.URL.Query().Get("fields"); got != "metadata.name" {
+					t.Errorf("expected fields projection %q, got %q", "metadata.name", got)
+				}
+				_, _ = w.Write([]byte(tc.body))
+			})
+
+			names, err := client.Users().ListNames(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(names) != 2 || names[0] != "colin" || names[1] != "sdk" {
+				t.Errorf("expected names [colin sdk], got %v", names)
+			}
+		})
+	}
+}
+
+func TestUserListMetadata(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Accept"); got != rest.PartialObjectMetadataListAcceptContentTypes {
+			t.Errorf("expected Accept %q, got %q", rest.PartialObjectMetadataListAcceptContentTypes, got)
+		}
+		_, _ = w.Write([]byte(`{"kind":"PartialObjectMetadataList","apiVersion":"v1","totalCount":2,"items":[` +
+			`{"kind":"PartialObjectMetadata","metadata":{"name":"colin","createdAt":"2020-10-01T08:00:00Z"}},` +
+			`{"kind":"PartialObjectMetadata","metadata":{"name":"sdk","createdAt":"2020-10-02T08:00:00Z"}}]}`))
+	})
+
+	list, err := client.Users().ListMetadata(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if list.TotalCount != 2 || len(list.Items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", list)
+	}
+
+	if item := list.Items[1]; item.Name != "sdk" || item.CreatedAt.Day() != 2 {
+		t.Errorf("unexpected item metadata %+v", item.ObjectMeta)
+	}
+}
+
+func TestUserDeleteObject(t *testing.T) {
+	var paths []string
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.Method+" "+req.URL.Path)
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	user := &v1.User{ObjectMeta: metav1.ObjectMeta{Name: "colin"}}
+	if err := client.Users().DeleteObject(context.TODO(), user, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(paths) != 1 || paths[0] != "DELETE /v1/users/colin" {
+		t.Errorf("expected a single DELETE of /v1/users/colin, got %v", paths)
+	}
+
+	if err := client.Users().DeleteObject(context.TODO(), &v1.User{}, metav1.DeleteOptions{}); err == nil {
+		t.Error("expected an error deleting an object without a name")
+	}
+
+	if err := client.Users().DeleteObject(context.TODO(), nil, metav1.DeleteOptions{}); err == nil {
+		t.Error("expected an error deleting a nil object")
+	}
+
+	if len(paths) != 1 {
+		t.Errorf("expected no request for a nil object or one without a name, got %v", paths)
+	}
+}
+
+func TestUserDeleteCollectionBatches(t *testing.T) {
+	var tokens []string
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		token := req.URL.Query().Get("continue")
+		tokens = append(tokens, token)
+
+		switch token {
+		case "":
+			_, _ = w.Write([]byte(`{"deleted":100,"continue":"batch-2"}`))
+		case "batch-2":
+			_, _ = w.Write([]byte(`{"deleted":42}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	deleted, err := client.Users().DeleteCollectionWithCount(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 142 {
+		t.Errorf("expected 142 deleted users, got %d", deleted)
+	}
+
+	if len(tokens) != 2 || tokens[0] != "" || tokens[1] != "batch-2" {
+		t.Errorf("expected requests without and with the continue token, got %q", tokens)
+	}
+
+	tokens = nil
+	if err := client.Users().DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tokens) != 2 {
+		t.Errorf("expected DeleteCollection to delete both batches, got %q", tokens)
+	}
+}
+
+func TestUserDeleteCollectionStrictDecoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"message":"OK","deleted":42}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{
+		Host:          srv.URL,
+		ContentConfig: rest.ContentConfig{StrictDecoding: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deleted, err := client.Users().DeleteCollectionWithCount(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 42 {
+		t.Errorf("expected 42 deleted users, got %d", deleted)
+	}
+}
+
+func TestUserDeleteCollectionCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests int
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		cancel()
+		_, _ = w.Write([]byte(`{"deleted":100,"continue":"batch-2"}`))
+	})
+
+	deleted, err := client.Users().DeleteCollectionWithCount(ctx, metav1.DeleteOptions{}, metav1.ListOptions{})
+	if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("expected no request after cancellation, got %d requests", requests)
+	}
+
+	if deleted > 100 {
+		t.Errorf("expected at most the first batch to be counted, got %d", deleted)
+	}
+}
+
+// pagedUsersHandler serves a collection of total users, paginated with the offset and limit parameters.
+// The total count is left out of the pages when hideTotal is set.
+func pagedUsersHandler(t *testing.T, total int, hideTotal bool, limits *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		*limits = append(*limits, req.URL.Query().Get("limit"))
+
+		list := &v1.UserList{ListMeta: metav1.ListMeta{TotalCount: int64(total)}, Items: []*v1.User{}}
+		if hideTotal {
+			list.TotalCount = 0
+		}
+
+		for i := offset; i < offset+limit && i < total; i++ {
+			list.Items = append(list.Items, &v1.User{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("user-%d", i)}})
+		}
+
+		if err := json.NewEncoder(w).Encode(list); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestUserListAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxItems      int64
+		hideTotal     bool
+		wantItems     int
+		wantTruncated bool
+		wantLimits    string
+	}{
+		{"unbounded", 0, false, 250, false, "[100 100 100]"},
+		{"budget smaller than the collection", 150, false, 150, true, "[100 50]"},
+		{"budget on a page boundary", 200, false, 200, true, "[100 100]"},
+		{"budget larger than the collection", 1000, false, 250, false, "[100 100 100]"},
+		{"budget of the whole collection", 250, false, 250, false, "[100 100 50]"},
+		{"unknown total, budget smaller than the collection", 200, true, 200, true, "[100 100 1]"},
+		{"unknown total, budget of the whole collection", 250, true, 250, false, "[100 100 50 1]"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var limits []string
+			client := testClient(t, pagedUsersHandler(t, 250, tc.hideTotal, &limits))
+
+			list, truncated, err := client.Users().ListAll(context.TODO(), metav1.ListOptions{}, tc.maxItems)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(list.Items) != tc.wantItems || truncated != tc.wantTruncated {
+				t.Errorf("expected %d items and truncated %v, got %d and %v",
+					tc.wantItems, tc.wantTruncated, len(list.Items), truncated)
+			}
+
+			if list.Items[len(list.Items)-1].Name != fmt.Sprintf("user-%d", tc.wantItems-1) {
+				t.Errorf("unexpected last item %q", list.Items[len(list.Items)-1].Name)
+			}
+
+			if fmt.Sprint(limits) != tc.wantLimits {
+				t.Errorf("expected page limits %s, got %v", tc.wantLimits, limits)
+			}
+		})
+	}
+}
+
+func TestUserListPages(t *testing.T) {
+	var limits []string
+	client := testClient(t, pagedUsersHandler(t, 250, false, &limits))
+
+	var pages []int
+	err := client.Users().ListPages(context.TODO(), metav1.ListOptions{}, func(page *v1.UserList) (bool, error) {
+		pages = append(pages, len(page.Items))
+
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(pages) != "[100 100 50]" {
+		t.Errorf("expected fn to be called once per page, got pages of %v", pages)
+	}
+
+	limits, pages = nil, nil
+	err = client.Users().ListPages(context.TODO(), metav1.ListOptions{}, func(page *v1.UserList) (bool, error) {
+		pages = append(pages, len(page.Items))
+
+		return len(pages) < 2, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pages) != 2 || len(limits) != 2 {
+		t.Errorf("expected listing to stop after 2 pages, got %d calls and %d requests", len(pages), len(limits))
+	}
+
+	errStop := errors.New("stop")
+	limits = nil
+	err = client.Users().ListPages(context.TODO(), metav1.ListOptions{}, func(page *v1.UserList) (bool, error) {
+		return true, errStop
+	})
+	if !errors.Is(err, errStop) || len(limits) != 1 {
+		t.Errorf("expected the fn error after 1 request, got %v after %d requests", err, len(limits))
+	}
+}
+
+func TestListEmptyItems(t *testing.T) {
+	for _, body := range []string{`{"totalCount":0,"items":null}`, `{"totalCount":0,"items":[]}`, `{"totalCount":0}`} {
+		t.Run(body, func(t *testing.T) {
+			client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(body))
+			})
+
+			users, err := client.Users().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if users.Items == nil || len(users.Items) != 0 {
+				t.Errorf("expected empty non-nil user items, got %#v", users.Items)
+			}
+
+			secrets, err := client.Secrets().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if secrets.Items == nil || len(secrets.Items) != 0 {
+				t.Errorf("expected empty non-nil secret items, got %#v", secrets.Items)
+			}
+
+			policies, err := client.Policies().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if policies.Items == nil || len(policies.Items) != 0 {
+				t.Errorf("expected empty non-nil policy items, got %#v", policies.Items)
+			}
+		})
+	}
+}
+
+func TestUserDisableEnable(t *testing.T) {
+	var method, path string
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		method, path = req.Method, req.RequestURI
+		_, _ = w.Write([]byte(`{"metadata":{"name":"colin"},"status":0}`))
+	})
+
+	tests := []struct {
+		name       string
+		call       func() (*v1.User, error)
+		wantMethod string
+		wantPath   string
+	}{
+		{
+			name: "disable",
+			call: func() (*v1.User, error) {
+				return client.Users().Disable(context.TODO(), "colin", UserStatusOptions{})
+			},
+			wantMethod: http.MethodPost,
+			wantPath:   "/v1/users/colin:disable",
+		},
+		{
+			name: "enable",
+			call: func() (*v1.User, error) {
+				return client.Users().Enable(context.TODO(), "colin", UserStatusOptions{})
+			},
+			wantMethod: http.MethodPost,
+			wantPath:   "/v1/users/colin:enable",
+		},
+		{
+			name: "disable with a custom verb",
+			call: func() (*v1.User, error) {
+				return client.Users().Disable(context.TODO(), "colin", UserStatusOptions{Verb: http.MethodPut})
+			},
+			wantMethod: http.MethodPut,
+			wantPath:   "/v1/users/colin:disable",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := tc.call()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if method != tc.wantMethod || path != tc.wantPath {
+				t.Errorf("expected %s %s, got %s %s", tc.wantMethod, tc.wantPath, method, path)
+			}
+
+			if user.Name != "colin" {
+				t.Errorf("expected the returned user, got %+v", user)
+			}
+		})
+	}
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method)
+		_, _ = w.Write([]byte(`{"metadata":{"name":"colin"}}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{Host: srv.URL, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user := &v1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "colin"},
+		Nickname:   "colin",
+		Password:   "Admin@2020",
+		Email:      "colin@foxmail.com",
+	}
+	if _, err := client.Users().Create(context.TODO(), user, metav1.CreateOptions{}); !errors.Is(err, rest.ErrReadOnly) {
+		t.Errorf("expected the read-only error, got %v", err)
+	}
+
+	if err := client.Users().Delete(context.TODO(), "colin", metav1.DeleteOptions{}); !errors.Is(err, rest.ErrReadOnly) {
+		t.Errorf("expected the read-only error, got %v", err)
+	}
+
+	if _, err := client.Users().Get(context.TODO(), "colin", metav1.GetOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(requests) != "[GET]" {
+		t.Errorf("expected only the GET request to reach the server, got %v", requests)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+)
+
+// ErrMissingRequiredFields is returned, wrapped, by the typed Create methods when the object
+// lacks fields the server requires. No request is sent in that case.
+var ErrMissingRequiredFields = errors.New("missing required fields")
+
+// Validators validate the objects before the typed Create methods of a client send them, see
+// APIV1Client.WithValidators. A nil function disables the validation of its kind.
+type Validators struct {
+	User   func(user *v1.User) error
+	Secret func(secret *v1.Secret) error
+	Policy func(policy *v1.Policy) error
+}
+
+// DefaultValidators returns the validators of the clients, which require the name, nickname,
+// password and email of a user, and the name of a secret or a policy.
+func DefaultValidators() Validators {
+	return Validators{
+		User: func(user *v1.User) error {
+			return requireFields(
+				"metadata.name", user.Name,
+				"nickname", user.Nickname,
+				"password", user.Password,
+				"email", user.Email,
+			)
+		},
+		Secret: func(secret *v1.Secret) error {
+			return requireFields("metadata.name", secret.Name)
+		},
+		Policy: func(policy *v1.Policy) error {
+			return requireFields("metadata.name", policy.Name)
+		},
+	}
+}
+
+type skipValidationKey struct{}
+
+// SkipValidation returns a copy of ctx which makes the typed Create methods send objects to
+// the server without validating them locally first.
+func SkipValidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipValidationKey{}, true)
+}
+
+// validationSkipped reports whether ctx was returned by SkipValidation.
+func validationSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipValidationKey{}).(bool)
+
+	return skip
+}
+
+// requireFields takes pairs of field path and value, and reports the paths whose value is empty.
+func requireFields(pathsAndValues ...string) error {
+	var missing []string
+	for i := 0; i+1 < len(pathsAndValues); i += 2 {
+		if strings.TrimSpace(pathsAndValues[i+1]) == "" {
+			missing = append(missing, pathsAndValues[i])
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrMissingRequiredFields, strings.Join(missing, ", "))
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+)
+
+func TestCreateRequiredFields(t *testing.T) {
+	validUser := func() *v1.User {
+		return &v1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "colin"},
+			Nickname:   "colin",
+			Password:   "Admin@2020",
+			Email:      "colin@foxmail.com",
+		}
+	}
+
+	tests := []struct {
+		name        string
+		create      func(ctx context.Context, client *APIV1Client) error
+		wantMissing string
+	}{
+		{
+			name: "user without name and password",
+			create: func(ctx context.Context, client *APIV1Client) error {
+				user := validUser()
+				user.Name, user.Password = "", ""
+				_, err := client.Users().Create(ctx, user, metav1.CreateOptions{})
+
+				return err
+			},
+			wantMissing: "metadata.name, password",
+		},
+		{
+			name: "user without nickname and email",
+			create: func(ctx context.Context, client *APIV1Client) error {
+				user := validUser()
+				user.Nickname, user.Email = "", " "
+				_, err := client.Users().Create(ctx, user, metav1.CreateOptions{})
+
+				return err
+			},
+			wantMissing: "nickname, email",
+		},
+		{
+			name: "valid user",
+			create: func(ctx context.Context, client *APIV1Client) error {
+				_, err := client.Users().Create(ctx, validUser(), metav1.CreateOptions{})
+
+				return err
+			},
+		},
+		{
+			name: "secret without name",
+			create: func(ctx context.Context, client *APIV1Client) error {
+				_, err := client.Secrets().Create(ctx, &v1.Secret{Description: "secret"}, metav1.CreateOptions{})
+
+				return err
+			},
+			wantMissing: "metadata.name",
+		},
+		{
+			name: "policy without name",
+			create: func(ctx context.Context, client *APIV1Client) error {
+				_, err := client.Policies().Create(ctx, &v1.Policy{Username: "colin"}, metav1.CreateOptions{})
+
+				return err
+			},
+			wantMissing: "metadata.name",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+				requests++
+				_, _ = w.Write([]byte(`{}`))
+			})
+
+			err := tc.create(context.TODO(), client)
+			if tc.wantMissing == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if requests != 1 {
+					t.Errorf("expected 1 request, got %d", requests)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, ErrMissingRequiredFields) {
+				t.Fatalf("expected ErrMissingRequiredFields, got %v", err)
+			}
+
+			if !strings.HasSuffix(err.Error(), ": "+tc.wantMissing) {
+				t.Errorf("expected missing fields %q, got %v", tc.wantMissing, err)
+			}
+
+			if requests != 0 {
+				t.Errorf("expected no request to be sent, got %d", requests)
+			}
+
+			if err := tc.create(SkipValidation(context.TODO()), client); err != nil {
+				t.Fatalf("unexpected error when skipping validation: %v", err)
+			}
+
+			if requests != 1 {
+				t.Errorf("expected the request to be sent when skipping validation, got %d", requests)
+			}
+		})
+	}
+}
+
+func TestCreateValidatorOverride(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	validators := DefaultValidators()
+	validators.Secret = nil
+
+	if _, err := client.WithValidators(validators).Secrets().Create(context.TODO(), &v1.Secret{},
+		metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error with validation disabled: %v", err)
+	}
+
+	errExpires := errors.New("expires is required")
+	validators.Secret = func(secret *v1.Secret) error {
+		if secret.Expires == 0 {
+			return errExpires
+		}
+
+		return nil
+	}
+
+	if _, err := client.WithValidators(validators).Secrets().Create(context.TODO(), &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "s"}},
+		metav1.CreateOptions{}); !errors.Is(err, errExpires) {
+		t.Fatalf("expected the custom validator error, got %v", err)
+	}
+
+	// the validators of a client don't affect the other clients
+	if _, err := client.Secrets().Create(context.TODO(), &v1.Secret{}, metav1.CreateOptions{}); !errors.Is(err, ErrMissingRequiredFields) {
+		t.Fatalf("expected the default validation of the original client, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ package v1
 
 import (
 	"context"
+	"fmt"
 
 	authzv1 "github.com/marmotedu/api/authz/v1"
 	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
@@ -48,6 +49,9 @@ func (c *authz) Authorize(ctx context.Context, request *ladon.Request,
 		Body(request).
 		Do(ctx).
 		Into(result)
+	if err != nil {
+		err = fmt.Errorf("authorize subject %q: %w", request.Subject, err)
+	}
 
 	return
 }
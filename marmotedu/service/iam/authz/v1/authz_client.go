@@ -5,6 +5,8 @@
 package v1
 
 import (
+	"context"
+
 	v1 "github.com/marmotedu/api/authz/v1"
 	"github.com/marmotedu/component-base/pkg/runtime"
 
@@ -29,10 +31,16 @@ func (c *AuthzV1Client) Authz() AuthzInterface {
 
 // NewForConfig creates a new AuthzV1Client for the given config.
 func NewForConfig(c *rest.Config) (*AuthzV1Client, error) {
+	return NewForConfigWithContext(context.Background(), c)
+}
+
+// NewForConfigWithContext creates a new AuthzV1Client for the given config, whose requests
+// are cancelled when ctx is done.
+func NewForConfigWithContext(ctx context.Context, c *rest.Config) (*AuthzV1Client, error) {
 	config := *c
 	setConfigDefaults(&config)
 
-	client, err := rest.RESTClientFor(&config)
+	client, err := rest.RESTClientForWithContext(ctx, &config)
 	if err != nil {
 		return nil, err
 	}
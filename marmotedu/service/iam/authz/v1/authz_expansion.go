@@ -4,5 +4,147 @@
 
 package v1
 
+import (
+	"context"
+	"fmt"
+
+	authzv1 "github.com/marmotedu/api/authz/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/ory/ladon"
+)
+
+// ImpersonateSubjectHeader is the header carrying the subject impersonated by the
+// authenticated identity of the client.
+const ImpersonateSubjectHeader = "Impersonate-Subject"
+
+// Explanation is the verbose authorization response, which tells the policies the decision
+// is based on.
+type Explanation struct {
+	authzv1.Response `json:",inline"`
+
+	// Policies lists the policies matching the request.
+	Policies []MatchedPolicy `json:"policies,omitempty"`
+}
+
+// MatchedPolicy is a policy matching an authorization request.
+type MatchedPolicy struct {
+	ID string `json:"id"`
+	// Effect is the effect of the policy, ladon.AllowAccess or ladon.DenyAccess.
+	Effect      string `json:"effect"`
+	Description string `json:"description,omitempty"`
+}
+
+// Decision is the authorization response of policy engines which return obligations and advice
+// along with the decision, eg. "log this access" or "mask the password field".
+type Decision struct {
+	authzv1.Response `json:",inline"`
+
+	// Obligations are the actions the client must fulfill to enforce the decision.
+	Obligations []Obligation `json:"obligations,omitempty"`
+	// Advice are the actions the client may fulfill, and may ignore.
+	Advice []Obligation `json:"advice,omitempty"`
+}
+
+// Obligation is an obligation or an advice of a Decision.
+type Obligation struct {
+	ID string `json:"id"`
+	// Attributes are the arguments of the obligation, eg. {"field": "password"} for a mask.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// FindObligation returns the obligation with the given id, and whether it was found.
+func (d *Decision) FindObligation(id string) (Obligation, bool) {
+	return findObligation(d.Obligations, id)
+}
+
+// FindAdvice returns the advice with the given id, and whether it was found.
+func (d *Decision) FindAdvice(id string) (Obligation, bool) {
+	return findObligation(d.Advice, id)
+}
+
+func findObligation(obligations []Obligation, id string) (Obligation, bool) {
+	for _, obligation := range obligations {
+		if obligation.ID == id {
+			return obligation, true
+		}
+	}
+
+	return Obligation{}, false
+}
+
 // The AuthzExpansion interface allows manually adding extra methods to the AuthzInterface.
-type AuthzExpansion interface{}
+type AuthzExpansion interface {
+	// AuthorizeAs authorizes the request on behalf of the impersonated subject. The client
+	// keeps authenticating with its own credentials, and the impersonated subject is sent
+	// separately, so that the server can audit both identities.
+	AuthorizeAs(ctx context.Context, subject string, request *ladon.Request,
+		opts metav1.AuthorizeOptions) (*authzv1.Response, error)
+
+	// Explain authorizes the request like Authorize, asking the server for a verbose response
+	// which lists the policies matching the request, eg. to debug a denied request.
+	Explain(ctx context.Context, request *ladon.Request, opts metav1.AuthorizeOptions) (*Explanation, error)
+
+	// Decide authorizes the request like Authorize, keeping the obligations and the advice the
+	// server returns along with the decision, which Authorize drops.
+	Decide(ctx context.Context, request *ladon.Request, opts metav1.AuthorizeOptions) (*Decision, error)
+}
+
+// AuthorizeAs takes the impersonated subject and the authorization request, and returns the
+// authorization response, and an error if there is any.
+func (c *authz) AuthorizeAs(ctx context.Context, subject string, request *ladon.Request,
+	opts metav1.AuthorizeOptions) (result *authzv1.Response, err error) {
+	if len(subject) == 0 {
+		return nil, fmt.Errorf("authorize as: impersonated subject is empty")
+	}
+
+	result = &authzv1.Response{}
+	err = c.client.Post().
+		Resource("authz").
+		VersionedParams(opts).
+		SetHeader(ImpersonateSubjectHeader, subject).
+		Body(request).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		err = fmt.Errorf("authorize subject %q as %q: %w", request.Subject, subject, err)
+	}
+
+	return
+}
+
+// Explain takes the authorization request, and returns the authorization response along with the
+// policies matching the request, and an error if there is any.
+func (c *authz) Explain(ctx context.Context, request *ladon.Request,
+	opts metav1.AuthorizeOptions) (result *Explanation, err error) {
+	result = &Explanation{}
+	err = c.client.Post().
+		Resource("authz").
+		VersionedParams(opts).
+		Param("verbose", "true").
+		Body(request).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		err = fmt.Errorf("explain authorization of subject %q: %w", request.Subject, err)
+	}
+
+	return
+}
+
+// Decide takes the authorization request, and returns the authorization decision along with its
+// obligations and advice, and an error if there is any.
+func (c *authz) Decide(ctx context.Context, request *ladon.Request,
+	opts metav1.AuthorizeOptions) (result *Decision, err error) {
+	result = &Decision{}
+	err = c.client.Post().
+		Resource("authz").
+		VersionedParams(opts).
+		Body(request).
+		Do(ctx).
+		Into(result)
+	if err != nil {
+		err = fmt.Errorf("authorize subject %q: %w", request.Subject, err)
+	}
+
+	return
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/ory/ladon"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestAuthorizeAs(t *testing.T) {
+	var kid, impersonated, subject string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the key id of the signed token identifies the authenticated service
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0]); err == nil {
+			var fields struct {
+				Kid string `json:"kid"`
+			}
+			_ = json.Unmarshal(header, &fields)
+			kid = fields.Kid
+		}
+
+		impersonated = req.Header.Get(ImpersonateSubjectHeader)
+
+		var request ladon.Request
+		_ = json.NewDecoder(req.Body).Decode(&request)
+		subject = request.Subject
+
+		_, _ = w.Write([]byte(`{"allowed":true}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{Host: srv.URL, SecretID: "service", SecretKey: "key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	request := &ladon.Request{Resource: "resources:articles:ladon-introduction", Action: "delete", Subject: "users:colin"}
+
+	resp, err := client.Authz().AuthorizeAs(context.TODO(), "users:colin", request, metav1.AuthorizeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !resp.Allowed {
+		t.Errorf("expected the request to be allowed")
+	}
+
+	if kid != "service" || impersonated != "users:colin" || subject != "users:colin" {
+		t.Errorf("expected service identity %q impersonating %q, got %q impersonating %q (subject %q)",
+			"service", "users:colin", kid, impersonated, subject)
+	}
+
+	if _, err := client.Authz().AuthorizeAs(context.TODO(), "", request, metav1.AuthorizeOptions{}); err == nil {
+		t.Error("expected an error for an empty impersonated subject")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	var verbose string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		verbose = req.URL.Query().Get("verbose")
+		_, _ = w.Write([]byte(`{"denied":true,"reason":"Request was denied by policy",` +
+			`"policies":[{"id":"policy-1","effect":"allow"},{"id":"policy-2","effect":"deny","description":"no deletes"}]}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	request := &ladon.Request{Resource: "resources:articles:ladon-introduction", Action: "delete", Subject: "users:colin"}
+
+	explanation, err := client.Authz().Explain(context.TODO(), request, metav1.AuthorizeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if verbose != "true" {
+		t.Errorf("expected a verbose response to be requested, got verbose=%q", verbose)
+	}
+
+	if explanation.Allowed || !explanation.Denied || explanation.Reason != "Request was denied by policy" {
+		t.Errorf("unexpected authorization response %+v", explanation.Response)
+	}
+
+	want := []MatchedPolicy{
+		{ID: "policy-1", Effect: ladon.AllowAccess},
+		{ID: "policy-2", Effect: ladon.DenyAccess, Description: "no deletes"},
+	}
+	if !reflect.DeepEqual(explanation.Policies, want) {
+		t.Errorf("expected matched policies %+v, got %+v", want, explanation.Policies)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"allowed":true,` +
+			`"obligations":[{"id":"log-access"},{"id":"mask","attributes":{"field":"password"}}],` +
+			`"advice":[{"id":"notify","attributes":{"channel":"email"}}]}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	request := &ladon.Request{Resource: "resources:users:colin", Action: "get", Subject: "users:james"}
+
+	decision, err := client.Authz().Decide(context.TODO(), request, metav1.AuthorizeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !decision.Allowed {
+		t.Errorf("expected the request to be allowed")
+	}
+
+	if len(decision.Obligations) != 2 {
+		t.Fatalf("expected 2 obligations, got %+v", decision.Obligations)
+	}
+
+	if _, ok := decision.FindObligation("log-access"); !ok {
+		t.Errorf("expected the log-access obligation")
+	}
+
+	mask, ok := decision.FindObligation("mask")
+	if !ok || mask.Attributes["field"] != "password" {
+		t.Errorf("expected the mask obligation of the password field, got %+v", mask)
+	}
+
+	if _, ok := decision.FindObligation("notify"); ok {
+		t.Errorf("expected advice not to be an obligation")
+	}
+
+	notify, ok := decision.FindAdvice("notify")
+	if !ok || notify.Attributes["channel"] != "email" {
+		t.Errorf("expected the notify advice, got %+v", notify)
+	}
+}
@@ -5,6 +5,11 @@
 package iam
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
 	apiv1 "github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam/apiserver/v1"
 	authzv1 "github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam/authz/v1"
 	"github.com/marmotedu/marmotedu-sdk-go/rest"
@@ -36,18 +41,24 @@ func (c *IamClient) AuthzV1() authzv1.AuthzV1Interface {
 
 // NewForConfig creates a new IamV1Client for the given config.
 func NewForConfig(c *rest.Config) (*IamClient, error) {
+	return NewForConfigWithContext(context.Background(), c)
+}
+
+// NewForConfigWithContext creates a new IamClient for the given config. Cancelling ctx
+// cancels all in-flight requests of the client.
+func NewForConfigWithContext(ctx context.Context, c *rest.Config) (*IamClient, error) {
 	configShallowCopy := *c
 
 	var ic IamClient
 
 	var err error
 
-	ic.apiV1, err = apiv1.NewForConfig(&configShallowCopy)
+	ic.apiV1, err = apiv1.NewForConfigWithContext(ctx, &configShallowCopy)
 	if err != nil {
 		return nil, err
 	}
 
-	ic.authzV1, err = authzv1.NewForConfig(&configShallowCopy)
+	ic.authzV1, err = authzv1.NewForConfigWithContext(ctx, &configShallowCopy)
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +66,177 @@ func NewForConfig(c *rest.Config) (*IamClient, error) {
 	return &ic, nil
 }
 
+// NewForConfigAndPing creates a new IamClient for the given config and checks that the servers
+// of both sub-clients are reachable by requesting their health endpoint. ctx bounds the whole
+// construction including the pings, eg. a slow DNS resolution or TLS handshake at startup, but
+// unlike NewForConfigWithContext the returned client outlives it. When ctx is done before the
+// pings succeed, the sub-clients already constructed are closed and the error wraps ctx.Err().
+func NewForConfigAndPing(ctx context.Context, c *rest.Config) (*IamClient, error) {
+	configShallowCopy := *c
+
+	var ic IamClient
+
+	err := func() (err error) {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if ic.apiV1, err = apiv1.NewForConfig(&configShallowCopy); err != nil {
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if ic.authzV1, err = authzv1.NewForConfig(&configShallowCopy); err != nil {
+			return err
+		}
+
+		if err = ping(ctx, ic.apiV1.RESTClient()); err != nil {
+			return err
+		}
+
+		return ping(ctx, ic.authzV1.RESTClient())
+	}()
+	if err != nil {
+		ic.close()
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("create iam client: %w", ctxErr)
+		}
+
+		return nil, fmt.Errorf("create iam client: %w", err)
+	}
+
+	return &ic, nil
+}
+
+// versionedClients wires the typed clients of every version of the iam API this client
+// supports. Each constructor sets the GroupVersion of its clients to its version.
+var versionedClients = map[string]func(ctx context.Context, c *rest.Config) (*IamClient, error){
+	"v1": NewForConfigWithContext,
+}
+
+// SupportedVersions returns the versions of the iam API this client has typed clients for.
+func SupportedVersions() []string {
+	versions := make([]string, 0, len(versionedClients))
+	for version := range versionedClients {
+		versions = append(versions, version)
+	}
+
+	sort.Strings(versions)
+
+	return versions
+}
+
+// legacyVersion is the version served by the iam servers which predate version discovery.
+const legacyVersion = "v1"
+
+// APIGroup is the discovery document of an API group, listing the versions served.
+type APIGroup struct {
+	Name     string                     `json:"name"`
+	Versions []GroupVersionForDiscovery `json:"versions"`
+}
+
+// GroupVersionForDiscovery is a version served for an API group, eg. {"iam.api/v1", "v1"}.
+type GroupVersionForDiscovery struct {
+	GroupVersion string `json:"groupVersion"`
+	Version      string `json:"version"`
+}
+
+// NewForConfigVersioned creates a new IamClient for the given version of the iam API, rather
+// than whichever version NewForConfig wires. It fails when the client has no typed clients for
+// the version, see SupportedVersions, or when the servers don't serve it. The versions served
+// by a server are discovered with GET /apis/<group>; servers without discovery only serve v1.
+// The GroupVersion of the returned clients is the given version.
+func NewForConfigVersioned(ctx context.Context, c *rest.Config, version string) (*IamClient, error) {
+	newForConfig, ok := versionedClients[version]
+	if !ok {
+		return nil, fmt.Errorf("iam version %q is not supported by this client, supported versions: %v",
+			version, SupportedVersions())
+	}
+
+	ic, err := newForConfig(context.Background(), c)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, client := range []rest.Interface{ic.apiV1.RESTClient(), ic.authzV1.RESTClient()} {
+		group := client.APIVersion().Group
+		if wired := client.APIVersion().Version; wired != version {
+			ic.close()
+
+			return nil, fmt.Errorf("%s client is wired to version %q instead of %q", group, wired, version)
+		}
+
+		versions, err := serverVersions(ctx, client)
+		if err != nil {
+			ic.close()
+
+			return nil, fmt.Errorf("discover %s versions: %w", group, err)
+		}
+
+		if !containsVersion(versions, version) {
+			ic.close()
+
+			return nil, fmt.Errorf("%s version %q is not served, served versions: %v", group, version, versions)
+		}
+	}
+
+	return ic, nil
+}
+
+// serverVersions returns the versions of the group of the client served by its server.
+func serverVersions(ctx context.Context, client rest.Interface) ([]string, error) {
+	group := client.APIVersion().Group
+	result := client.Get().AbsPath("/apis", group).Do(ctx)
+
+	if resp := result.RawResponse(); resp != nil && resp.StatusCode == http.StatusNotFound {
+		return []string{legacyVersion}, nil
+	}
+
+	apiGroup := &APIGroup{}
+	if err := result.Into(apiGroup); err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(apiGroup.Versions))
+	for _, version := range apiGroup.Versions {
+		versions = append(versions, version.Version)
+	}
+
+	return versions, nil
+}
+
+func containsVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ping requests the health endpoint of the server of the given client.
+func ping(ctx context.Context, client rest.Interface) error {
+	if err := client.Get().AbsPath("/healthz").NoAuth().Do(ctx).Error(); err != nil {
+		return fmt.Errorf("ping %s: %w", client.APIVersion().Group, err)
+	}
+
+	return nil
+}
+
+// close releases the connections of the sub-clients constructed so far.
+func (c *IamClient) close() {
+	for _, client := range []interface{ RESTClient() rest.Interface }{c.apiV1, c.authzV1} {
+		if closer, ok := client.RESTClient().(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+}
+
 // NewForConfigOrDie creates a new IamClient for the given config and
 // panics if there is an error in the config.
 func NewForConfigOrDie(c *rest.Config) *IamClient {
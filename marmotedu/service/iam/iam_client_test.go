@@ -0,0 +1,218 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package iam
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/ory/ladon"
+
+	apiv1 "github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam/apiserver/v1"
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestGroupContent(t *testing.T) {
+	var mu sync.Mutex
+
+	contentTypes := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		contentTypes[req.URL.Path] = req.Header.Get("Content-Type")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfig(&rest.Config{
+		Host: srv.URL,
+		GroupContent: map[string]rest.GroupContentConfig{
+			"iam.authz": {ContentType: "application/vnd.iam.authz+json"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.APIV1().Users().Create(apiv1.SkipValidation(context.TODO()), &v1.User{}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.AuthzV1().Authz().Authorize(context.TODO(), &ladon.Request{}, metav1.AuthorizeOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"/v1/users": "application/json",
+		"/v1/authz": "application/vnd.iam.authz+json",
+	}
+	for path, contentType := range want {
+		if got := contentTypes[path]; got != contentType {
+			t.Errorf("expected %s requests to use content type %q, got %q", path, contentType, got)
+		}
+	}
+}
+
+func TestNewForConfigAndPing(t *testing.T) {
+	var mu sync.Mutex
+
+	var pinged []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/healthz" {
+			_, _ = w.Write([]byte(`{}`))
+
+			return
+		}
+
+		mu.Lock()
+		pinged = append(pinged, req.URL.Path)
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := NewForConfigAndPing(ctx, &rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pinged) != 2 || pinged[0] != "/healthz" || pinged[1] != "/healthz" {
+		t.Errorf("expected both sub-clients to ping /healthz, got %v", pinged)
+	}
+
+	// the client outlives the construction context
+	cancel()
+
+	if _, err := client.AuthzV1().Authz().Authorize(context.TODO(), &ladon.Request{}, metav1.AuthorizeOptions{}); err != nil {
+		t.Fatalf("unexpected error after the construction context is done: %v", err)
+	}
+}
+
+func TestNewForConfigAndPingDeadline(t *testing.T) {
+	disconnected := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+			close(disconnected)
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	client, err := NewForConfigAndPing(ctx, &rest.Config{Host: srv.URL})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got %v", err)
+	}
+
+	if client != nil {
+		t.Errorf("expected no client, got %v", client)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the deadline to bound the construction, took %v", elapsed)
+	}
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Error("expected the pending ping to be cancelled")
+	}
+}
+
+func TestNewForConfigVersioned(t *testing.T) {
+	discovery := map[string]string{
+		"/apis/iam.api":   `{"name":"iam.api","versions":[{"groupVersion":"iam.api/v1","version":"v1"}]}`,
+		"/apis/iam.authz": `{"name":"iam.authz","versions":[{"groupVersion":"iam.authz/v2","version":"v2"}]}`,
+	}
+
+	tests := []struct {
+		name      string
+		discovery bool
+		version   string
+		wantErr   string
+	}{
+		{"version not served by a group", true, "v1", "iam.authz version \"v1\" is not served"},
+		{"legacy server", false, "v1", ""},
+		{"unsupported version", true, "v2", "not supported by this client"},
+		{"unknown version", false, "v3", "not supported by this client"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				body, ok := discovery[req.URL.Path]
+				if !tc.discovery || !ok {
+					http.NotFound(w, req)
+
+					return
+				}
+
+				_, _ = w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			client, err := NewForConfigVersioned(context.TODO(), &rest.Config{Host: srv.URL}, tc.version)
+			if tc.wantErr == "" {
+				if err != nil || client == nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	discovery["/apis/iam.authz"] = `{"name":"iam.authz","versions":[{"groupVersion":"iam.authz/v1","version":"v1"}]}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(discovery[req.URL.Path]))
+	}))
+	defer srv.Close()
+
+	client, err := NewForConfigVersioned(context.TODO(), &rest.Config{Host: srv.URL}, "v1")
+	if err != nil {
+		t.Fatalf("unexpected error for a version served by both groups: %v", err)
+	}
+
+	if version := client.APIV1().RESTClient().APIVersion().Version; version != "v1" {
+		t.Errorf("expected the apiserver client to use v1, got %q", version)
+	}
+
+	if version := client.AuthzV1().RESTClient().APIVersion().Version; version != "v1" {
+		t.Errorf("expected the authz client to use v1, got %q", version)
+	}
+
+	// the supported versions can't be changed by callers
+	SupportedVersions()[0] = "v2"
+	if _, err := NewForConfigVersioned(context.TODO(), &rest.Config{Host: srv.URL}, "v2"); err == nil ||
+		!strings.Contains(err.Error(), "not supported by this client") {
+		t.Errorf("expected v2 to stay unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package testserver provides an in-process fake IAM API server, to run the examples offline and
+// to test code built on the iam clients without a running IAM server.
+package testserver
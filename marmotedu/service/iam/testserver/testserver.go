@@ -0,0 +1,344 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package testserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// Server is a fake IAM API server serving the users, secrets and policies of the v1 API from
+// memory. It implements create, get, list (with the offset and limit options), update, delete and
+// delete collection, and the /healthz endpoint. The server populates the metadata of the objects
+// and the credentials of the secrets, which updates keep like the passwords of the users. Errors
+// are answered with the status code of the IAM API server and a {"message": "..."} body, or its
+// {"code": ..., "message": "..."} body when the IAM API server has a dedicated error code, eg.
+// 400 Bad Request with rest.CodeUserAlreadyExist for an existing user. Objects are not validated.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	nextID    uint64
+	resources map[string]*store
+}
+
+// store holds the objects of a resource, encoded to JSON, keyed by name.
+type store struct {
+	prefix    string
+	newObject func() metav1.Object
+	// defaults sets the fields the server generates on create.
+	defaults func(obj metav1.Object)
+	// keep copies the fields which can't be updated from the current object on update.
+	keep func(obj, current metav1.Object)
+	// alreadyExists is the error the IAM API server answers the creation of an existing object
+	// with, if it isn't a conflict.
+	alreadyExists *rest.Status
+	objects       map[string][]byte
+}
+
+// New starts a fake IAM API server. The caller must call Close when done.
+func New() *Server {
+	s := &Server{
+		resources: map[string]*store{
+			"users": {
+				prefix:    "user-",
+				newObject: func() metav1.Object { return &v1.User{} },
+				keep: func(obj, current metav1.Object) {
+					obj.(*v1.User).Password = current.(*v1.User).Password
+				},
+				alreadyExists: &rest.Status{Code: rest.CodeUserAlreadyExist, Message: "User already exist"},
+			},
+			"secrets": {
+				prefix:    "secret-",
+				newObject: func() metav1.Object { return &v1.Secret{} },
+				defaults: func(obj metav1.Object) {
+					secret := obj.(*v1.Secret)
+					if len(secret.SecretID) == 0 {
+						secret.SecretID = randomString(18)
+					}
+
+					if len(secret.SecretKey) == 0 {
+						secret.SecretKey = randomString(16)
+					}
+				},
+				keep: func(obj, current metav1.Object) {
+					secret, currentSecret := obj.(*v1.Secret), current.(*v1.Secret)
+					secret.SecretID, secret.SecretKey = currentSecret.SecretID, currentSecret.SecretKey
+				},
+			},
+			"policies": {
+				prefix:    "policy-",
+				newObject: func() metav1.Object { return &v1.Policy{} },
+			},
+		},
+	}
+
+	for _, resource := range s.resources {
+		resource.objects = map[string][]byte{}
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Config returns a client configuration for the server.
+func (s *Server) Config() *rest.Config {
+	return &rest.Config{Host: s.URL}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/healthz" {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+
+		return
+	}
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || segments[0] != "v1" {
+		writeError(w, http.StatusNotFound, "page not found")
+
+		return
+	}
+
+	resource, ok := s.resources[segments[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "page not found")
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(segments) == 2 {
+		switch req.Method {
+		case http.MethodGet:
+			s.list(w, req, resource)
+		case http.MethodPost:
+			s.create(w, req, resource)
+		case http.MethodDelete:
+			resource.objects = map[string][]byte{}
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+
+		return
+	}
+
+	name := segments[2]
+
+	switch req.Method {
+	case http.MethodGet:
+		s.get(w, resource, name)
+	case http.MethodPut:
+		s.update(w, req, resource, name)
+	case http.MethodDelete:
+		s.delete(w, resource, name)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) create(w http.ResponseWriter, req *http.Request, resource *store) {
+	obj := resource.newObject()
+	if err := json.NewDecoder(req.Body).Decode(obj); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	name := obj.GetName()
+	if len(name) == 0 {
+		writeError(w, http.StatusBadRequest, "name is required")
+
+		return
+	}
+
+	if _, ok := resource.objects[name]; ok {
+		if resource.alreadyExists != nil {
+			writeJSON(w, http.StatusBadRequest, resource.alreadyExists)
+
+			return
+		}
+
+		writeError(w, http.StatusConflict, fmt.Sprintf("%s already exists", name))
+
+		return
+	}
+
+	s.nextID++
+	now := time.Now().UTC().Truncate(time.Second)
+
+	obj.SetID(s.nextID)
+	obj.SetCreatedAt(now)
+	obj.SetUpdatedAt(now)
+	objectMetaOf(obj).InstanceID = resource.prefix + strconv.FormatUint(s.nextID, 10)
+
+	if resource.defaults != nil {
+		resource.defaults(obj)
+	}
+
+	s.save(w, http.StatusCreated, resource, obj)
+}
+
+func (s *Server) get(w http.ResponseWriter, resource *store, name string) {
+	data, ok := resource.objects[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", name))
+
+		return
+	}
+
+	writeRaw(w, http.StatusOK, data)
+}
+
+func (s *Server) list(w http.ResponseWriter, req *http.Request, resource *store) {
+	offset, limit, err := pagination(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	objects := make([]metav1.Object, 0, len(resource.objects))
+	for _, data := range resource.objects {
+		obj := resource.newObject()
+		_ = json.Unmarshal(data, obj)
+		objects = append(objects, obj)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].GetID() < objects[j].GetID() })
+
+	items := []json.RawMessage{}
+	for i := offset; i < int64(len(objects)) && (limit < 0 || i < offset+limit); i++ {
+		items = append(items, resource.objects[objects[i].GetName()])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"totalCount": len(objects), "items": items})
+}
+
+func (s *Server) update(w http.ResponseWriter, req *http.Request, resource *store, name string) {
+	data, ok := resource.objects[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", name))
+
+		return
+	}
+
+	current := resource.newObject()
+	_ = json.Unmarshal(data, current)
+
+	obj := resource.newObject()
+	if err := json.NewDecoder(req.Body).Decode(obj); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	// the name and the fields populated by the server can't be updated
+	obj.SetName(name)
+	obj.SetID(current.GetID())
+	obj.SetCreatedAt(current.GetCreatedAt())
+	obj.SetUpdatedAt(time.Now().UTC().Truncate(time.Second))
+	objectMetaOf(obj).InstanceID = objectMetaOf(current).InstanceID
+
+	if resource.keep != nil {
+		resource.keep(obj, current)
+	}
+
+	s.save(w, http.StatusOK, resource, obj)
+}
+
+func (s *Server) delete(w http.ResponseWriter, resource *store, name string) {
+	if _, ok := resource.objects[name]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", name))
+
+		return
+	}
+
+	delete(resource.objects, name)
+	w.WriteHeader(http.StatusOK)
+}
+
+// save stores the object and writes it back.
+func (s *Server) save(w http.ResponseWriter, code int, resource *store, obj metav1.Object) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	resource.objects[obj.GetName()] = data
+	writeRaw(w, code, data)
+}
+
+// pagination returns the offset and limit list options of the request, limit is -1 if not set.
+func pagination(req *http.Request) (offset, limit int64, err error) {
+	query := req.URL.Query()
+	limit = -1
+
+	if value := query.Get("offset"); len(value) != 0 {
+		if offset, err = strconv.ParseInt(value, 10, 64); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", value)
+		}
+	}
+
+	if value := query.Get("limit"); len(value) != 0 {
+		if limit, err = strconv.ParseInt(value, 10, 64); err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", value)
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// objectMetaOf returns the metadata of a resource, which embeds a metav1.ObjectMeta.
+func objectMetaOf(obj metav1.Object) *metav1.ObjectMeta {
+	meta, _ := obj.(metav1.ObjectMetaAccessor).GetObjectMeta().(*metav1.ObjectMeta)
+
+	return meta
+}
+
+func randomString(n int) string {
+	b := make([]byte, (n+1)/2)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)[:n]
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		code, data = http.StatusInternalServerError, []byte(`{"message":"encode response"}`)
+	}
+
+	writeRaw(w, code, data)
+}
+
+func writeRaw(w http.ResponseWriter, code int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package testserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+
+	"github.com/marmotedu/marmotedu-sdk-go/marmotedu/service/iam"
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestServer(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	client, err := iam.NewForConfig(srv.Config())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.TODO()
+	users := client.APIV1().Users()
+
+	user := &v1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "colin"},
+		Nickname:   "colin",
+		Password:   "Admin@2020",
+		Email:      "colin@foxmail.com",
+	}
+
+	created, err := users.Create(ctx, user, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if created.ID == 0 || created.InstanceID == "" || created.CreatedAt.IsZero() || created.Nickname != "colin" {
+		t.Errorf("expected the server to populate the metadata, got %+v", created)
+	}
+
+	_, err = users.Create(ctx, user, metav1.CreateOptions{})
+	if !rest.IsAlreadyExists(err) || !rest.IsConflict(err) {
+		t.Errorf("expected an already exists conflict creating an existing user, got %v", err)
+	}
+
+	// like the IAM API server, the error is a 400 Bad Request carrying CodeUserAlreadyExist
+	var statusErr *rest.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest ||
+		statusErr.Status == nil || statusErr.Status.Code != rest.CodeUserAlreadyExist {
+		t.Errorf("expected the error of the IAM API server, got %#v", err)
+	}
+
+	got, err := users.Get(ctx, "colin", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.ID != created.ID || got.Email != "colin@foxmail.com" {
+		t.Errorf("expected the created user, got %+v", got)
+	}
+
+	update := got
+	update.Email = "colin@qq.com"
+	update.ID = 0
+
+	updated, err := users.Update(ctx, update, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if updated.Email != "colin@qq.com" || updated.ID != created.ID || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("expected the updated user to keep its metadata, got %+v", updated)
+	}
+
+	if _, err := users.Create(ctx, &v1.User{ObjectMeta: metav1.ObjectMeta{Name: "james"},
+		Nickname: "james", Password: "Admin@2020", Email: "james@foxmail.com"}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	limit := int64(1)
+
+	list, err := users.List(ctx, metav1.ListOptions{Limit: &limit})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if list.TotalCount != 2 || len(list.Items) != 1 || list.Items[0].Name != "colin" {
+		t.Errorf("expected the first page of 2 users, got %+v", list)
+	}
+
+	if err := users.Delete(ctx, "colin", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := users.Get(ctx, "colin", metav1.GetOptions{}); !rest.IsNotFound(err) {
+		t.Errorf("expected the deleted user not to be found, got %v", err)
+	}
+
+	if err := users.Delete(ctx, "colin", metav1.DeleteOptions{}); !rest.IsNotFound(err) {
+		t.Errorf("expected deleting a missing user to fail, got %v", err)
+	}
+}
+
+func TestServerSecretsAndPolicies(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	client, err := iam.NewForConfig(srv.Config())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.TODO()
+
+	secret, err := client.APIV1().Secrets().Create(ctx, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret0"},
+		Expires:    0,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if secret.SecretID == "" || secret.SecretKey == "" {
+		t.Errorf("expected the server to generate the secret credentials, got %+v", secret)
+	}
+
+	policy, err := client.APIV1().Policies().Create(ctx, &v1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy0"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if policy.ID <= secret.ID {
+		t.Errorf("expected unique IDs, got %d and %d", secret.ID, policy.ID)
+	}
+
+	if err := client.APIV1().Policies().DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policies, err := client.APIV1().Policies().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if policies.TotalCount != 0 || len(policies.Items) != 0 {
+		t.Errorf("expected the policies to be deleted, got %+v", policies)
+	}
+
+	if _, err := client.APIV1().Secrets().Get(ctx, "secret0", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the secrets to be kept, got %v", err)
+	}
+}
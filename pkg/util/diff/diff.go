@@ -0,0 +1,142 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Difference is a field which differs between two objects. Path is the dot separated path of
+// the field in the JSON representation of the objects, eg. "metadata.name" or "items.0".
+// A nil Old or New means the field is missing from that object.
+type Difference struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// String returns a human readable description of the difference.
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s -> %s", d.Path, format(d.Old), format(d.New))
+}
+
+func format(v interface{}) string {
+	if v == nil {
+		return "<missing>"
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(data)
+}
+
+// ObjectDiff returns the differences between the JSON representations of a and b, sorted by
+// path. Fields whose path is listed in ignore, or is nested below one of them, are skipped.
+// It returns no difference when the objects are structurally equal.
+func ObjectDiff(a, b interface{}, ignore ...string) ([]Difference, error) {
+	oldValue, err := toJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+
+	newValue, err := toJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := make(map[string]bool, len(ignore))
+	for _, path := range ignore {
+		ignored[path] = true
+	}
+
+	var diffs []Difference
+
+	compare("", oldValue, newValue, ignored, &diffs)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+
+	return diffs, nil
+}
+
+// Equal returns whether the JSON representations of a and b are structurally equal, skipping
+// the ignored paths.
+func Equal(a, b interface{}, ignore ...string) (bool, error) {
+	diffs, err := ObjectDiff(a, b, ignore...)
+	if err != nil {
+		return false, err
+	}
+
+	return len(diffs) == 0, nil
+}
+
+func toJSONValue(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal %T: %w", obj, err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %T: %w", obj, err)
+	}
+
+	return value, nil
+}
+
+func compare(path string, oldValue, newValue interface{}, ignored map[string]bool, diffs *[]Difference) {
+	if ignored[path] {
+		return
+	}
+
+	switch oldTyped := oldValue.(type) {
+	case map[string]interface{}:
+		newTyped, ok := newValue.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		for key, value := range oldTyped {
+			compare(join(path, key), value, newTyped[key], ignored, diffs)
+		}
+
+		for key, value := range newTyped {
+			if _, ok := oldTyped[key]; !ok {
+				compare(join(path, key), nil, value, ignored, diffs)
+			}
+		}
+
+		return
+	case []interface{}:
+		newTyped, ok := newValue.([]interface{})
+		if !ok || len(oldTyped) != len(newTyped) {
+			break
+		}
+
+		for i := range oldTyped {
+			compare(join(path, strconv.Itoa(i)), oldTyped[i], newTyped[i], ignored, diffs)
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		*diffs = append(*diffs, Difference{Path: path, Old: oldValue, New: newValue})
+	}
+}
+
+func join(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return strings.Join([]string{path, key}, ".")
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"fmt"
+	"testing"
+)
+
+type object struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		CreatedAt string `json:"createdAt,omitempty"`
+	} `json:"metadata"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Items  []string          `json:"items,omitempty"`
+}
+
+func TestObjectDiff(t *testing.T) {
+	current := object{Labels: map[string]string{"app": "iam"}, Items: []string{"a", "b"}}
+	current.Metadata.Name = "colin"
+	current.Metadata.CreatedAt = "2020-10-01T08:00:00Z"
+
+	tests := []struct {
+		name   string
+		modify func(*object)
+		ignore []string
+		want   string
+	}{
+		{"equal", func(o *object) {}, nil, "[]"},
+		{"changed field", func(o *object) { o.Metadata.Name = "sdk" }, nil, `[metadata.name: "colin" -> "sdk"]`},
+		{"added and removed map keys", func(o *object) { o.Labels = map[string]string{"env": "dev"} }, nil,
+			`[labels.app: "iam" -> <missing> labels.env: <missing> -> "dev"]`},
+		{"changed slice element", func(o *object) { o.Items = []string{"a", "c"} }, nil, `[items.1: "b" -> "c"]`},
+		{"changed slice length", func(o *object) { o.Items = []string{"a"} }, nil, `[items: ["a","b"] -> ["a"]]`},
+		{"ignored field", func(o *object) { o.Metadata.CreatedAt = "" }, []string{"metadata.createdAt"}, "[]"},
+		{"ignored parent", func(o *object) { o.Metadata.Name = "sdk" }, []string{"metadata"}, "[]"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			desired := current
+			desired.Labels = map[string]string{"app": "iam"}
+			tc.modify(&desired)
+
+			diffs, err := ObjectDiff(current, desired, tc.ignore...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := fmt.Sprint(diffs); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
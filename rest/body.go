@@ -0,0 +1,99 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"mime"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
+)
+
+// YAMLContentType is the content type of YAML request bodies, see YAMLEncoder.
+const YAMLContentType = "application/yaml"
+
+// YAMLEncoder encodes request bodies as YAML. Objects are encoded to JSON first, so that the
+// YAML document follows their json field tags like the JSON bodies do. Register it in
+// Config.BodyEncoders for YAMLContentType to send YAML bodies.
+type YAMLEncoder struct{}
+
+// Encode implements runtime.Encoder.
+func (YAMLEncoder) Encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML, which only has to be restyled from flow to block style
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	clearStyle(&document)
+
+	return yaml.Marshal(&document)
+}
+
+// clearStyle resets the style of the node and its children to the default block style. Strings
+// which would read as another type are still quoted.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// bodyEncoderFor returns the encoder registered for the media type of the content type.
+func bodyEncoderFor(encoders map[string]runtime.Encoder, contentType string) runtime.Encoder {
+	if len(encoders) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	return encoders[mediaType]
+}
+
+// encodeBody encodes the body with the body encoder registered for the content type of the
+// client, which is then sent as is. Without encoder, the body is left to the agent to encode to
+// JSON, and encoded here only to be measured.
+func (r *Request) encodeBody(obj interface{}) ([]byte, error) {
+	encoder := bodyEncoderFor(r.c.content.BodyEncoders, r.c.content.ContentType)
+	if encoder == nil {
+		return json.Marshal(obj)
+	}
+
+	data, err := encoder.Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SetHeader("Content-Type", r.c.content.ContentType)
+	r.encodedBody = data
+
+	return data, nil
+}
+
+// sendBody sets the body of the request on the agent. Bodies encoded by a body encoder are
+// sent as is, others are encoded by the agent.
+func (r *Request) sendBody(agent *gorequest.SuperAgent) *gorequest.SuperAgent {
+	if r.encodedBody == nil {
+		return agent.Send(r.body)
+	}
+
+	agent.Type(gorequest.TypeText)
+	agent.TargetType = gorequest.TypeText
+	agent.BounceToRawString = true
+
+	return agent.SendString(string(r.encodedBody))
+}
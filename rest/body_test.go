@@ -0,0 +1,72 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestYAMLBody(t *testing.T) {
+	var (
+		contentType string
+		body        []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contentType = req.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(req.Body)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.ContentType = YAMLContentType
+		c.BodyEncoders = map[string]runtime.Encoder{YAMLContentType: YAMLEncoder{}}
+	})
+
+	type policy struct {
+		Name     string            `json:"name"`
+		Enabled  string            `json:"enabled"`
+		Subjects []string          `json:"subjects"`
+		Meta     map[string]string `json:"meta,omitempty"`
+	}
+
+	req := client.Post().Resource("policies").Body(&policy{
+		Name:     "colin",
+		Enabled:  "true",
+		Subjects: []string{"users:colin", "users:sdk"},
+	})
+	if err := req.Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if contentType != YAMLContentType {
+		t.Errorf("expected content type %q, got %q", YAMLContentType, contentType)
+	}
+
+	want := "name: colin\nenabled: \"true\"\nsubjects:\n    - users:colin\n    - users:sdk\n"
+	if string(body) != want {
+		t.Errorf("expected YAML body\n%s\ngot\n%s", want, body)
+	}
+
+	var got map[string]interface{}
+	if err := yaml.Unmarshal(body, &got); err != nil {
+		t.Fatalf("expected valid YAML, got %v", err)
+	}
+
+	if got["enabled"] != "true" || len(got["subjects"].([]interface{})) != 2 {
+		t.Errorf("unexpected decoded YAML %v", got)
+	}
+
+	if req.BodySize() != int64(len(want)) {
+		t.Errorf("expected the size of the YAML body, got %d", req.BodySize())
+	}
+}
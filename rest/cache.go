@@ -0,0 +1,129 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
+)
+
+// FromCacheHeader is set on responses served from the response cache after the server
+// reported them as not modified.
+const FromCacheHeader = "X-From-Cache"
+
+// cachedResponse is a successful response kept to revalidate later requests for the same URL.
+type cachedResponse struct {
+	key          string
+	lastModified string
+	etag         string
+	body         []byte
+}
+
+// responseCache keeps the last successful GET response of every URL which carried a
+// Last-Modified or ETag header. As the URL includes the resource and the selectors, lists with
+// different selectors are cached apart. At most size responses are kept, the least recently
+// used one being evicted first.
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newResponseCache(size int) *responseCache {
+	if size <= 0 {
+		size = DefaultResponseCacheSize
+	}
+
+	return &responseCache{size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *responseCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.order.MoveToFront(element)
+
+	return element.Value.(*cachedResponse)
+}
+
+// update stores a successful response, or forgets the URL when the response can not be
+// revalidated.
+func (c *responseCache) update(key string, resp gorequest.Response, body []byte) {
+	entry := &cachedResponse{
+		key:          key,
+		lastModified: resp.Header.Get("Last-Modified"),
+		etag:         resp.Header.Get("ETag"),
+		body:         body,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+
+	if len(entry.lastModified) == 0 && len(entry.etag) == 0 {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// revalidate makes the agent send a conditional request for the cached response.
+func (e *cachedResponse) revalidate(client *gorequest.SuperAgent) {
+	if len(e.lastModified) != 0 && len(client.Header.Get("If-Modified-Since")) == 0 {
+		client.Set("If-Modified-Since", e.lastModified)
+	}
+
+	if len(e.etag) != 0 && len(client.Header.Get("If-None-Match")) == 0 {
+		client.Set("If-None-Match", e.etag)
+	}
+}
+
+// cacheVaryHeaders are the request headers which select the representation of a response, so
+// that eg. a Table and a full list of the same URL are cached apart.
+var cacheVaryHeaders = []string{"Accept", "Accept-Language"}
+
+// cacheKey returns the key of the request in the response cache, or an empty string if the
+// response of the request is not cached.
+func (r *Request) cacheKey() string {
+	if r.c.cache == nil || r.verb != http.MethodGet {
+		return ""
+	}
+
+	key := r.URL().String()
+	for _, header := range cacheVaryHeaders {
+		key += "\n" + header + ": " + strings.Join(r.headers.Values(header), ", ")
+	}
+
+	return key
+}
+
+// fromCache turns a 304 Not Modified response into the cached response it validated.
+func fromCache(resp gorequest.Response, entry *cachedResponse) []byte {
+	resp.StatusCode = http.StatusOK
+	resp.Status = "200 OK"
+	resp.Header.Set(FromCacheHeader, "true")
+
+	return entry.body
+}
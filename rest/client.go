@@ -5,8 +5,11 @@
 package rest
 
 import (
+	"context"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/marmotedu/component-base/pkg/runtime"
 	"github.com/marmotedu/component-base/pkg/scheme"
@@ -19,12 +22,72 @@ type Interface interface {
 	Verb(verb string) *Request
 	Post() *Request
 	Put() *Request
-	// Patch(pt types.PatchType) *Request
+	Patch(pt PatchType) *Request
 	Get() *Request
 	Delete() *Request
+	Options() *Request
+	Head() *Request
 	APIVersion() scheme.GroupVersion
 }
 
+// PartialObjectMetadataListAcceptContentTypes is an AcceptContentTypes preset asking the server
+// to return lists as PartialObjectMetadataList, which only carries the metadata of every item.
+// Servers which don't support the representation fall back to the full JSON objects.
+const PartialObjectMetadataListAcceptContentTypes = "application/json;as=PartialObjectMetadataList;v=v1,application/json"
+
+// TableAcceptContentTypes is an AcceptContentTypes preset asking the server to return the Table
+// representation of objects and lists, for command line tools to print them. Servers which
+// don't support the representation fall back to the full JSON objects.
+const TableAcceptContentTypes = "application/json;as=Table;v=v1,application/json"
+
+// DefaultPriorityHeader is the header carrying the request priority level when
+// ClientContentConfig.PriorityHeader is not set.
+const DefaultPriorityHeader = "X-Request-Priority"
+
+// DefaultDeadlineHeader is the header carrying the time left before the deadline of the request
+// when ClientContentConfig.DeadlineHeader is not set.
+const DefaultDeadlineHeader = "X-Deadline"
+
+// DefaultNamespaceResource is the path segment introducing the namespace of a request when
+// ClientContentConfig.NamespaceResource is not set, eg. /v1/tenants/<namespace>/users.
+const DefaultNamespaceResource = "tenants"
+
+// DefaultRetryBudgetHeader is the response header carrying the retry budget of the server when
+// ClientContentConfig.RetryBudgetHeader is not set.
+const DefaultRetryBudgetHeader = "X-Retry-Budget"
+
+// DefaultRetryJitter is the fraction of the retry interval by which retries are randomly spread
+// when ClientContentConfig.RetryJitter is not set.
+const DefaultRetryJitter = 0.2
+
+// DefaultMaxRetryAfter caps the delay a server asks for with the Retry-After header when
+// ClientContentConfig.MaxRetryAfter is not set.
+const DefaultMaxRetryAfter = time.Minute
+
+// DefaultResponseCacheSize is the number of responses kept by the response cache when
+// ClientContentConfig.ResponseCacheSize is not set.
+const DefaultResponseCacheSize = 256
+
+// DefaultRetryableStatusCodes are the status codes of the responses which are retried when
+// Config.RetryableStatusCodes is not set.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusInternalServerError,
+	http.StatusTooManyRequests,
+	http.StatusServiceUnavailable,
+}
+
+// DefaultAuthScheme is the scheme of the Authorization header carrying a token when
+// ClientContentConfig.AuthScheme is not set.
+const DefaultAuthScheme = "Bearer"
+
+// The formats of the timeout query parameter, see ClientContentConfig.TimeoutParamFormat.
+const (
+	// TimeoutParamFormatDuration renders the timeout as a Go duration string, eg. "1m30s".
+	TimeoutParamFormatDuration = "duration"
+	// TimeoutParamFormatSeconds renders the timeout as a whole number of seconds, eg. "90".
+	TimeoutParamFormatSeconds = "seconds"
+)
+
 // ClientContentConfig controls how RESTClient communicates with the server.
 type ClientContentConfig struct {
 	Username string
@@ -41,6 +104,13 @@ type ClientContentConfig struct {
 	// If set, the contents are periodically read.
 	// The last successfully read value takes precedence over BearerToken.
 	BearerTokenFile string
+	// CheckTokenExpiry fails the requests authenticated with an expired JWT bearer token.
+	CheckTokenExpiry bool
+	// AuthScheme is the scheme of the Authorization header carrying a token.
+	AuthScheme string
+	// ProbeAuth picks the first of several credentials accepted by the server at AuthProbePath.
+	ProbeAuth     bool
+	AuthProbePath string
 	TLSClientConfig
 
 	// AcceptContentTypes specifies the types the client will accept and is optional.
@@ -53,6 +123,108 @@ type ClientContentConfig struct {
 	ContentType  string
 	GroupVersion scheme.GroupVersion
 	Negotiator   runtime.ClientNegotiator
+	// StrictDecoding rejects JSON responses containing fields unknown to the target object.
+	StrictDecoding bool
+	// UseNumber decodes the numbers of JSON responses into untyped targets as json.Number.
+	UseNumber bool
+	// ResponseEnvelope is a JSON pointer to the payload of the responses.
+	ResponseEnvelope string
+	// ResponseMeta is a JSON pointer to the metadata of the responses.
+	ResponseMeta string
+	// PreserveBasePath keeps the base URL path verbatim instead of cleaning it and adding a
+	// trailing slash.
+	PreserveBasePath bool
+	// Priority is the default priority level of the requests, sent in the PriorityHeader header.
+	Priority       string
+	PriorityHeader string
+	// DeadlineHeader is the header carrying the time left before the deadline of the request.
+	DeadlineHeader string
+	// Headers are the default headers of the requests.
+	Headers http.Header
+	// Logger receives the diagnostic messages of the client.
+	Logger Logger
+	// Namespace is the default namespace of the requests, NamespaceResource the path segment
+	// introducing it.
+	Namespace         string
+	NamespaceResource string
+	// EnableResponseCache revalidates GET responses with If-Modified-Since and If-None-Match.
+	EnableResponseCache bool
+	// ResponseCacheSize is the number of responses kept by the response cache.
+	ResponseCacheSize int
+	// OnRetry is called before every retry of a request.
+	OnRetry func(attempt int, err error, nextDelay time.Duration)
+	// RetryBudgetHeader is the response header carrying the number of retries the server permits.
+	RetryBudgetHeader string
+	// RetryJitter is the fraction of the retry interval by which retries are randomly spread.
+	RetryJitter float64
+	// MaxRetryAfter caps the delay the server asks for with the Retry-After header.
+	MaxRetryAfter time.Duration
+	// StatusReasons overrides the reasons of the status codes of the error responses.
+	StatusReasons map[int]StatusReason
+	// AdaptiveThrottle delays the requests when the rate limit of the server is almost reached.
+	AdaptiveThrottle *AdaptiveThrottle
+	// ReadOnly refuses the requests which would modify objects.
+	ReadOnly bool
+	// WarningHandler is called once per distinct warning of the responses.
+	WarningHandler func(warning string)
+	// OnRequest is called once every request sent with Do completed.
+	OnRequest func(metric RequestMetric)
+	// OnTrace is called with the timing breakdown of every attempt of the requests sent with Do.
+	OnTrace func(trace RequestTrace)
+	// MaxRequestBytes is the size above which request bodies are rejected before being sent.
+	MaxRequestBytes int64
+	// MaxConcurrentRequests is the number of requests sent with Do which may be in flight at once.
+	MaxConcurrentRequests int
+	// TimeoutParamFormat is the format of the timeout query parameter.
+	TimeoutParamFormat string
+	// BodyEncoders encode the request bodies of the content types they are registered for.
+	BodyEncoders map[string]runtime.Encoder
+	// WatchBackoff makes the watchers reconnect when their stream drops, if set.
+	WatchBackoff *WatchBackoff
+}
+
+// The authentication methods returned by AuthMethod.
+const (
+	AuthMethodToken  = "token"
+	AuthMethodSecret = "secret"
+	AuthMethodBasic  = "basic"
+	AuthMethodNone   = "none"
+)
+
+// AuthMethod returns the label of the authentication method of the configuration, for
+// diagnostics. When several credentials are set, the one used in priority is returned,
+// even though requests are then refused.
+func (c *ClientContentConfig) AuthMethod() string {
+	switch {
+	case c.HasTokenAuth():
+		return AuthMethodToken
+	case c.HasKeyAuth():
+		return AuthMethodSecret
+	case c.HasBasicAuth():
+		return AuthMethodBasic
+	default:
+		return AuthMethodNone
+	}
+}
+
+// authMethods returns the labels of the authentication methods with credentials in the
+// configuration, in the order of AuthMethod.
+func (c *ClientContentConfig) authMethods() []string {
+	var methods []string
+
+	if c.HasTokenAuth() {
+		methods = append(methods, AuthMethodToken)
+	}
+
+	if c.HasKeyAuth() {
+		methods = append(methods, AuthMethodSecret)
+	}
+
+	if c.HasBasicAuth() {
+		methods = append(methods, AuthMethodBasic)
+	}
+
+	return methods
 }
 
 // HasBasicAuth returns whether the configuration has basic authentication or not.
@@ -101,7 +273,23 @@ type RESTClient struct {
 	versionedAPIPath string
 	// content describes how a RESTClient encodes and decodes responses.
 	content ClientContentConfig
-	Client  *gorequest.SuperAgent
+	// tokenFile periodically reloads the bearer token when content.BearerTokenFile is set.
+	tokenFile *cachingTokenFile
+	// cache revalidates GET responses when content.EnableResponseCache is set.
+	cache *responseCache
+	// ctx is the parent context of every request, see RESTClientForWithContext.
+	ctx context.Context
+	// probe finds the accepted credentials when content.ProbeAuth is set.
+	probe *authProber
+	// throttle delays the requests when content.AdaptiveThrottle is set.
+	throttle *throttle
+	// warnings reports the warnings of the responses to content.WarningHandler.
+	warnings *warningReporter
+	// inflight bounds the requests in flight when content.MaxConcurrentRequests is set.
+	inflight chan struct{}
+	// streamTransport sends the watch streams when set, which bypass the recorder.
+	streamTransport http.RoundTripper
+	Client          *gorequest.SuperAgent
 }
 
 // NewRESTClient creates a new RESTClient. This client performs generic REST functions
@@ -112,24 +300,60 @@ func NewRESTClient(baseURL *url.URL, versionedAPIPath string,
 		config.ContentType = "application/json"
 	}
 
+	if config.Logger == nil {
+		config.Logger = nopLogger{}
+	}
+
 	base := *baseURL
-	if !strings.HasSuffix(base.Path, "/") {
+	if !config.PreserveBasePath && !strings.HasSuffix(base.Path, "/") {
 		base.Path += "/"
 	}
 
 	base.RawQuery = ""
 	base.Fragment = ""
 
+	var tokenFile *cachingTokenFile
+	if len(config.BearerTokenFile) != 0 {
+		tokenFile = newCachingTokenFile(config.BearerTokenFile)
+	}
+
+	var cache *responseCache
+	if config.EnableResponseCache {
+		cache = newResponseCache(config.ResponseCacheSize)
+	}
+
+	var probe *authProber
+	if config.ProbeAuth {
+		probe = &authProber{}
+	}
+
+	var throttle *throttle
+	if config.AdaptiveThrottle != nil {
+		throttle = newThrottle(*config.AdaptiveThrottle)
+	}
+
+	var inflight chan struct{}
+	if config.MaxConcurrentRequests > 0 {
+		inflight = make(chan struct{}, config.MaxConcurrentRequests)
+	}
+
 	return &RESTClient{
 		base:             &base,
 		group:            config.GroupVersion.Group,
 		versionedAPIPath: versionedAPIPath,
 		content:          config,
+		tokenFile:        tokenFile,
+		cache:            cache,
+		inflight:         inflight,
+		probe:            probe,
+		throttle:         throttle,
+		warnings:         newWarningReporter(config.WarningHandler, config.Logger),
 		Client:           client,
 	}, nil
 }
 
-// Verb begins a Verb request.
+// Verb begins a Verb request. The verb is sent as is, so that it may be a nonstandard one, eg.
+// REPORT for the subresources of an extension.
 func (c *RESTClient) Verb(verb string) *Request {
 	return NewRequest(c).Verb(verb)
 }
@@ -144,6 +368,12 @@ func (c *RESTClient) Put() *Request {
 	return c.Verb("PUT")
 }
 
+// Patch begins a PATCH request. Short for c.Verb("PATCH") with the Content-Type header set to
+// pt. The patch is usually set as a []byte with Body, which is sent as is.
+func (c *RESTClient) Patch(pt PatchType) *Request {
+	return c.Verb("PATCH").SetHeader("Content-Type", string(pt))
+}
+
 // Get begins a GET request. Short for c.Verb("GET").
 func (c *RESTClient) Get() *Request {
 	return c.Verb("GET")
@@ -154,6 +384,63 @@ func (c *RESTClient) Delete() *Request {
 	return c.Verb("DELETE")
 }
 
+// Options begins an OPTIONS request. Short for c.Verb("OPTIONS").
+func (c *RESTClient) Options() *Request {
+	return c.Verb("OPTIONS")
+}
+
+// Head begins a HEAD request. Short for c.Verb("HEAD"). The response has no body, its status and
+// headers are read from Result.StatusCode and Result.Header.
+func (c *RESTClient) Head() *Request {
+	return c.Verb("HEAD")
+}
+
+// RefreshToken reloads the bearer token from BearerTokenFile right away instead of waiting for
+// the cached token to expire, eg. after the token was revoked and reissued out-of-band.
+// It is a no-op for clients without a BearerTokenFile.
+func (c *RESTClient) RefreshToken(ctx context.Context) error {
+	if c.tokenFile == nil {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.tokenFile.Refresh()
+}
+
+// CloseIdleConnections closes the idle connections of the transport owned by the client.
+// A transport shared through Config.Transport is left untouched.
+func (c *RESTClient) CloseIdleConnections() {
+	if c.Client != nil && c.Client.Transport != nil {
+		c.Client.Transport.CloseIdleConnections()
+	}
+}
+
+// WithTimeout returns a shallow copy of the client whose requests time out after timeout, in
+// place of Config.Timeout, eg. for a hot path needing a tighter timeout. The copy shares the
+// transport, credentials and limits of the client, which is left unaffected.
+func (c *RESTClient) WithTimeout(timeout time.Duration) *RESTClient {
+	clone := *c
+	clone.Client = c.Client.Clone().Timeout(timeout)
+
+	return &clone
+}
+
+// AuthMethod returns the label of the authentication method of the client, see
+// ClientContentConfig.AuthMethod, or the one accepted by the server when the credentials are
+// probed, see Config.ProbeAuth. No secret is ever returned.
+func (c *RESTClient) AuthMethod() string {
+	if c.probe != nil {
+		if method := c.probe.accepted(); len(method) != 0 {
+			return method
+		}
+	}
+
+	return c.content.AuthMethod()
+}
+
 // APIVersion returns the APIVersion this RESTClient is expected to use.
 func (c *RESTClient) APIVersion() scheme.GroupVersion {
 	return c.content.GroupVersion
@@ -5,11 +5,18 @@
 package rest
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,6 +26,7 @@ import (
 
 	"github.com/marmotedu/component-base/pkg/runtime"
 	"github.com/marmotedu/component-base/pkg/scheme"
+	"golang.org/x/crypto/pkcs12"
 
 	"github.com/marmotedu/marmotedu-sdk-go/pkg/version"
 	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
@@ -27,10 +35,69 @@ import (
 // Config holds the common attributes that can be passed to a IAM client on
 // initialization.
 type Config struct {
+	// Host must be a host string, a host:port pair, or a URL to the base of the iam server.
+	// A unix:///path/to/socket URL makes the client talk HTTP over that unix domain socket.
 	Host    string
 	APIPath string
 	ContentConfig
 
+	// GroupContent overrides the content configuration for the clients of some API groups,
+	// keyed by group name, eg. for iam.authz to use another content type than iam.api while
+	// both are built from the same Config.
+	GroupContent map[string]GroupContentConfig
+
+	// UnixSocket is the path of a unix domain socket which is dialed instead of the
+	// host from the request URL, eg. when the server is exposed to a sidecar over a socket.
+	UnixSocket string
+
+	// LocalAddr is the source IP address of the connections to the server, eg. on a multi-homed
+	// host whose firewall only lets the IAM traffic out of one interface. The system picks it if
+	// not set.
+	LocalAddr string
+
+	// PreserveBasePath keeps the path of Host verbatim, eg. when a proxy in front of the server
+	// tells apart /prefix from /prefix/ or routes on repeated slashes. By default the base path
+	// is cleaned and joined with the API path.
+	PreserveBasePath bool
+
+	// Priority is the default priority level sent with every request, eg. "batch" for jobs
+	// which must not starve interactive traffic. Request.Priority overrides it per request.
+	Priority string
+
+	// PriorityHeader is the name of the header carrying the priority level.
+	// If not set, DefaultPriorityHeader is used.
+	PriorityHeader string
+
+	// DeadlineHeader is the name of the header carrying the time left before the deadline of the
+	// context of a request, eg. "4.998s", so that the server can skip the work of its downstream
+	// calls which could not complete in time. The header is omitted when the context has no
+	// deadline. If not set, DefaultDeadlineHeader is used.
+	DeadlineHeader string
+
+	// Headers are sent with every request, eg. baggage identifying the tenant to the tracing of
+	// the server. The headers set on a request override them.
+	Headers http.Header
+
+	// Logger receives the diagnostic messages of the client. If not set, they are discarded.
+	Logger Logger
+
+	// Namespace scopes every request of a resource to a namespace, eg. a tenant, unless a
+	// request sets its own with Request.Namespace. The paths set with Request.AbsPath or
+	// Request.RequestURI, eg. /healthz, are not scoped. Requests are not scoped if not set.
+	Namespace string
+
+	// NamespaceResource is the path segment introducing the namespace in the request path.
+	// If not set, DefaultNamespaceResource is used.
+	NamespaceResource string
+
+	// EnableResponseCache keeps the GET responses carrying a Last-Modified or ETag header, eg.
+	// lists, and revalidates them on the next request for the same resource, selectors and Accept
+	// header. When the server answers 304 Not Modified, the cached response is returned instead.
+	EnableResponseCache bool
+	// ResponseCacheSize is the number of responses kept by the response cache, the least
+	// recently used one being evicted first. Defaults to DefaultResponseCacheSize.
+	ResponseCacheSize int
+
 	// Server requires Basic authentication
 	Username string
 	Password string
@@ -48,15 +115,131 @@ type Config struct {
 	// The last successfully read value takes precedence over BearerToken.
 	BearerTokenFile string
 
+	// CheckTokenExpiry makes requests fail without being sent when the bearer token is a JWT
+	// whose exp claim is in the past, instead of being rejected by the server with a 401.
+	// Tokens which are not JWTs are sent as is.
+	CheckTokenExpiry bool
+
+	// AuthScheme is the scheme of the Authorization header sent with the bearer token or the
+	// token signed with SecretID/SecretKey, eg. "Token" for gateways which expect
+	// "Authorization: Token <x>". Defaults to DefaultAuthScheme.
+	AuthScheme string
+
+	// ProbeAuth lets the config carry several credentials, eg. both a bearer token and a
+	// secretID/secretKey pair, for fleets where servers accept either. The first request tries
+	// them in turn, token first, with a GET of AuthProbePath, and the first one accepted by the
+	// server is used from then on. A credential is only skipped when the server rejects it with
+	// 401 or 403, other failures are returned and the next request probes again. Without it,
+	// setting several credentials is an error.
+	ProbeAuth bool
+	// AuthProbePath is the endpoint the credentials are probed against, with a query if needed.
+	// Defaults to DefaultAuthProbePath.
+	AuthProbePath string
+
 	// TLSClientConfig contains settings to enable transport layer security
 	TLSClientConfig
 
 	// UserAgent is an optional field that specifies the caller of this request.
 	UserAgent string
 	// The maximum length of time to wait before giving up on a server request. A value of zero means no timeout.
-	Timeout       time.Duration
-	MaxRetries    int
-	RetryInterval time.Duration
+	Timeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake of the connections to the server, so that a
+	// stuck handshake fails fast. A value of zero means no timeout.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for the response headers once the request is sent.
+	// Unlike Timeout it does not cover the reading of the response body, so a slow download is
+	// not interrupted. A value of zero means no timeout.
+	ResponseHeaderTimeout time.Duration
+	MaxRetries            int
+	RetryInterval         time.Duration
+	// RetryableStatusCodes are the status codes of the responses which are retried, up to
+	// MaxRetries times, after RetryInterval or after the delay the server asks for with the
+	// Retry-After header. Defaults to DefaultRetryableStatusCodes.
+	RetryableStatusCodes []int
+	// RetryJitter spreads the retries of clients failing together, by randomly shortening or
+	// lengthening every retry interval by up to this fraction of it, eg. 0.2 for ±20%.
+	// Defaults to DefaultRetryJitter, a negative value disables the jitter.
+	RetryJitter float64
+	// MaxRetryAfter caps the delay the server asks for with the Retry-After header, so that a
+	// server asking for hours doesn't park the caller. Defaults to DefaultMaxRetryAfter, a
+	// negative value disables the cap. Whatever the delay, a request is not retried when its
+	// context would be done before the next attempt.
+	MaxRetryAfter time.Duration
+	// OnRetry is called before every retry with the number of the failed attempt, its error
+	// and the delay before the next attempt, eg. to alert on server trouble. Optional.
+	OnRetry func(attempt int, err error, nextDelay time.Duration)
+	// RetryBudgetHeader is the response header through which the server grants the number of
+	// retries currently permitted. A budget of zero stops the retries of a request regardless
+	// of MaxRetries. Defaults to DefaultRetryBudgetHeader.
+	RetryBudgetHeader string
+
+	// StatusReasons overrides the meaning of the status codes of the error responses, as reported
+	// by IsValidationError, IsNotFound, IsConflict and IsUnauthorized, for deployments which differ from
+	// DefaultStatusReasons, eg. {422: StatusReasonValidation}. StatusReasonUnknown removes the
+	// default reason of a status code. Optional.
+	StatusReasons map[int]StatusReason
+
+	// AdaptiveThrottle makes the client wait for the rate limit advertised by the server to be
+	// reset when few requests remain, instead of being answered 429 Too Many Requests. Optional.
+	AdaptiveThrottle *AdaptiveThrottle
+
+	// ReadOnly makes the client refuse to send requests other than GET, HEAD and OPTIONS, which
+	// fail with ErrReadOnly without reaching the server, eg. for audit tools which must not
+	// modify anything.
+	ReadOnly bool
+
+	// WarningHandler is called with the text of the Warning headers of the responses, eg. the
+	// deprecation notices of the server, once per distinct warning. The resources announced as
+	// deprecated by the Deprecation and Sunset headers are reported too. The warnings are logged
+	// with Logger if not set. See Result.Warnings and Result.Deprecation for a single response.
+	// Optional.
+	WarningHandler func(warning string)
+
+	// OnRequest is called once every request sent with Do completed, eg. to record metrics about
+	// the requests, tagged with the labels set with WithMetricsLabels. Optional.
+	OnRequest func(metric RequestMetric)
+
+	// OnTrace is called after every attempt of the requests sent with Do with its timing
+	// breakdown, eg. the DNS lookup, the connection, the TLS handshake and the time to the first
+	// response byte, for latency analysis. The requests are not traced if not set. Optional.
+	OnTrace func(trace RequestTrace)
+
+	// TimeoutParamFormat is the format of the timeout query parameter sent with Request.Timeout:
+	// TimeoutParamFormatDuration, the default, or TimeoutParamFormatSeconds for servers which
+	// expect a whole number of seconds.
+	TimeoutParamFormat string
+
+	// BodyEncoders are the encoders of the request bodies, keyed by media type, eg. YAMLEncoder
+	// for YAMLContentType. The encoder registered for ContentType encodes the objects passed to
+	// Request.Body, which are encoded to JSON otherwise. Optional.
+	BodyEncoders map[string]runtime.Encoder
+
+	// WatchBackoff makes the watchers returned by Request.Watch reconnect with backoff when their
+	// stream drops, instead of closing their result channel. A reconnection refused with a client
+	// error, eg. 403 Forbidden, other than 408, 410 and 429 ends the watch with an Error event.
+	// Optional.
+	WatchBackoff *WatchBackoff
+
+	// MaxRequestBytes makes Do fail without sending the request when the encoded body is larger,
+	// eg. to avoid a certain 413 Request Entity Too Large from the server. No limit if not set.
+	MaxRequestBytes int64
+
+	// MaxConcurrentRequests bounds the number of requests sent with Do which a client has in
+	// flight at once, eg. to stay within the connection limits of the server. Do waits for one of
+	// them to complete, or for its context to be done, when the limit is reached. Each client
+	// built from the config has its own limit. No limit if not set.
+	MaxConcurrentRequests int
+
+	// Transport sends the requests in place of a transport of the client's own. Clients built
+	// from configs carrying the same Transport share its connection pool, while each applies its
+	// own credentials to its requests. The Transport is used as is, so it cannot be combined with
+	// the TLS options, a unix socket, TLSHandshakeTimeout or ResponseHeaderTimeout. Optional.
+	Transport http.RoundTripper
+
+	// Recorder records the interactions with the server to a file, or replays them from it.
+	// Meant for deterministic tests. The response bodies are saved verbatim unless
+	// RecorderConfig.RedactBody is set. Optional.
+	Recorder *RecorderConfig
 }
 
 // ContentConfig defines config for content.
@@ -66,6 +249,60 @@ type ContentConfig struct {
 	ContentType        string
 	GroupVersion       *scheme.GroupVersion
 	Negotiator         runtime.ClientNegotiator
+	// StrictDecoding makes Result.Into fail when a JSON response contains fields unknown
+	// to the target object. Useful in development to catch schema drift, lenient by default.
+	StrictDecoding bool
+	// UseNumber makes Result.Into decode the numbers of JSON responses as json.Number instead of
+	// float64 when the target is untyped, eg. a map[string]interface{}, so that int64 values like
+	// IDs above 2^53 keep their precision. Typed targets are not affected. Off by default.
+	UseNumber bool
+	// ResponseEnvelope is a JSON pointer (RFC 6901) to the payload of the responses, for servers
+	// which wrap it in an envelope, eg. "/data" for {"data":{"items":[...]}}. Result.Into
+	// decodes the payload found at the pointer and fails if it is missing. The whole body is
+	// decoded if not set.
+	ResponseEnvelope string
+	// ResponseMeta is a JSON pointer (RFC 6901) to the metadata of the responses wrapped in an
+	// envelope, eg. "/meta" for {"data":{...},"meta":{"total":1}}, returned by Result.Meta.
+	// Set ResponseEnvelope to JSONAPIEnvelope and ResponseMeta to JSONAPIMeta for JSON:API
+	// servers.
+	ResponseMeta string
+}
+
+// GroupContentConfig overrides the content configuration of the clients of an API group.
+// Only the non-empty fields are overridden.
+type GroupContentConfig struct {
+	AcceptContentTypes string
+	ContentType        string
+	Negotiator         runtime.ClientNegotiator
+}
+
+// groupContentFor returns config with the content configuration overrides of its group applied.
+// config itself is returned when there are none.
+func groupContentFor(config *Config) *Config {
+	if config.GroupVersion == nil {
+		return config
+	}
+
+	override, ok := config.GroupContent[config.GroupVersion.Group]
+	if !ok {
+		return config
+	}
+
+	config = CopyConfig(config)
+
+	if len(override.AcceptContentTypes) != 0 {
+		config.AcceptContentTypes = override.AcceptContentTypes
+	}
+
+	if len(override.ContentType) != 0 {
+		config.ContentType = override.ContentType
+	}
+
+	if override.Negotiator != nil {
+		config.Negotiator = override.Negotiator
+	}
+
+	return config
 }
 
 type sanitizedConfig *Config
@@ -115,23 +352,42 @@ type TLSClientConfig struct {
 	KeyFile string
 	// Trusted root certificates for server
 	CAFile string
+	// CAFiles lists more files of trusted root certificates, eg. for servers signed by
+	// different internal CAs. They are trusted in addition to CAFile.
+	CAFiles []string
 
-	// CertData holds PEM-encoded bytes (typically read from a client certificate file).
-	// CertData takes precedence over CertFile
+	// CertData holds PEM-encoded bytes (typically read from a client certificate file), or their
+	// base64 encoding. CertData takes precedence over CertFile
 	CertData []byte
-	// KeyData holds PEM-encoded bytes (typically read from a client certificate key file).
-	// KeyData takes precedence over KeyFile
+	// KeyData holds PEM-encoded bytes (typically read from a client certificate key file), or their
+	// base64 encoding. KeyData takes precedence over KeyFile
 	KeyData []byte
-	// CAData holds PEM-encoded bytes (typically read from a root certificates bundle).
-	// CAData takes precedence over CAFile
+	// CAData holds PEM-encoded bytes (typically read from a root certificates bundle), or their
+	// base64 encoding. CAData takes precedence over CAFile
 	CAData []byte
 
+	// PFXFile is the path of a PKCS#12 bundle holding the client certificate and key, as an
+	// alternative to CertFile and KeyFile. Only bundles encrypted with the legacy PKCS#12
+	// algorithms (3DES or RC2 with SHA-1) are supported.
+	PFXFile string
+	// PFXData holds the bytes of a PKCS#12 bundle, base64-encoded like the other data fields.
+	// PFXData takes precedence over PFXFile
+	PFXData []byte
+	// PFXPassword is the passphrase the PKCS#12 bundle is encrypted with.
+	PFXPassword string
+
 	// NextProtos is a list of supported application level protocols, in order of preference.
 	// Used to populate tls.Config.NextProtos.
 	// To indicate to the server http/1.1 is preferred over http/2, set to ["http/1.1", "h2"] (though the server is free
 	// to ignore that preference).
 	// To use only http/1.1, set to ["http/1.1"].
 	NextProtos []string
+
+	// PinnedCertSHA256 is a list of base64 or hex encoded SHA-256 digests of the server leaf
+	// certificate's SubjectPublicKeyInfo. When set, connections to a server whose certificate
+	// matches none of the pins are rejected. Pinning is applied in addition to the normal
+	// certificate verification, not instead of it.
+	PinnedCertSHA256 []string
 }
 
 var (
@@ -157,10 +413,17 @@ func (c TLSClientConfig) String() string {
 		CertFile:   c.CertFile,
 		KeyFile:    c.KeyFile,
 		CAFile:     c.CAFile,
+		CAFiles:    c.CAFiles,
 		CertData:   c.CertData,
 		KeyData:    c.KeyData,
 		CAData:     c.CAData,
 		NextProtos: c.NextProtos,
+
+		PFXFile:     c.PFXFile,
+		PFXData:     c.PFXData,
+		PFXPassword: c.PFXPassword,
+
+		PinnedCertSHA256: c.PinnedCertSHA256,
 	}
 	// Explicitly mark non-empty credential fields as redacted.
 	if len(cc.CertData) != 0 {
@@ -171,17 +434,45 @@ func (c TLSClientConfig) String() string {
 		cc.KeyData = []byte("--- REDACTED ---")
 	}
 
+	if len(cc.PFXData) != 0 {
+		cc.PFXData = []byte("--- REDACTED ---")
+	}
+
+	if len(cc.PFXPassword) != 0 {
+		cc.PFXPassword = "--- REDACTED ---"
+	}
+
 	return fmt.Sprintf("%#v", cc)
 }
 
 // HasCA returns whether the configuration has a certificate authority or not.
 func (c TLSClientConfig) HasCA() bool {
-	return len(c.CAData) > 0 || len(c.CAFile) > 0
+	return len(c.CAData) > 0 || len(c.CAFile) > 0 || len(c.CAFiles) > 0
 }
 
 // HasCertAuth returns whether the configuration has certificate authentication or not.
 func (c TLSClientConfig) HasCertAuth() bool {
-	return (len(c.CertData) != 0 || len(c.CertFile) != 0) && (len(c.KeyData) != 0 || len(c.KeyFile) != 0)
+	return ((len(c.CertData) != 0 || len(c.CertFile) != 0) && (len(c.KeyData) != 0 || len(c.KeyFile) != 0)) ||
+		c.hasPFX()
+}
+
+// AuthMethod returns the label of the authentication method of the config, one of "token",
+// "secret", "basic" or "none", without exposing any secret. It is meant for diagnostics.
+func (c *Config) AuthMethod() string {
+	content := ClientContentConfig{
+		Username:        c.Username,
+		SecretID:        c.SecretID,
+		SecretKey:       c.SecretKey,
+		BearerToken:     c.BearerToken,
+		BearerTokenFile: c.BearerTokenFile,
+	}
+
+	return content.AuthMethod()
+}
+
+// hasPFX returns whether the client certificate and key are provided as a PKCS#12 bundle.
+func (c TLSClientConfig) hasPFX() bool {
+	return len(c.PFXData) != 0 || len(c.PFXFile) != 0
 }
 
 // RESTClientFor returns a RESTClient that satisfies the requested attributes on a client Config
@@ -189,14 +480,43 @@ func (c TLSClientConfig) HasCertAuth() bool {
 // A RESTClient created by this method is generic - it expects to operate on an API that follows
 // the IAM conventions, but may not be the IAM API.
 func RESTClientFor(config *Config) (*RESTClient, error) {
+	return RESTClientForWithContext(context.Background(), config)
+}
+
+// RESTClientForWithContext is like RESTClientFor, but every request of the returned client is
+// also bound to ctx: cancelling it cancels all in-flight requests, eg. on shutdown.
+func RESTClientForWithContext(ctx context.Context, config *Config) (*RESTClient, error) {
 	if config.GroupVersion == nil {
 		return nil, fmt.Errorf("GroupVersion is required when initializing a RESTClient")
 	}
 
+	config = groupContentFor(config)
+
 	if config.Negotiator == nil {
 		return nil, fmt.Errorf("NegotiatedSerializer is required when initializing a RESTClient")
 	}
 
+	for _, segment := range []string{config.Namespace, config.NamespaceResource} {
+		if msgs := IsValidPathSegmentName(segment); len(segment) != 0 && len(msgs) != 0 {
+			return nil, fmt.Errorf("invalid namespace path segment %q: %v", segment, msgs)
+		}
+	}
+
+	for key, values := range config.Headers {
+		if err := validateHeader(key, values...); err != nil {
+			return nil, err
+		}
+	}
+
+	switch config.TimeoutParamFormat {
+	case "", TimeoutParamFormatDuration, TimeoutParamFormatSeconds:
+	default:
+		return nil, fmt.Errorf("invalid timeout param format %q, expected %q or %q",
+			config.TimeoutParamFormat, TimeoutParamFormatDuration, TimeoutParamFormatSeconds)
+	}
+
+	config, socket := unixSocketFor(config)
+
 	baseURL, versionedAPIPath, err := defaultServerURLFor(config)
 	if err != nil {
 		return nil, err
@@ -208,38 +528,152 @@ func RESTClientFor(config *Config) (*RESTClient, error) {
 		return nil, err
 	}
 
+	if config.Transport != nil && (tlsConfig != nil || len(socket) != 0 || len(config.LocalAddr) != 0 ||
+		config.TLSHandshakeTimeout != 0 || config.ResponseHeaderTimeout != 0) {
+		return nil, fmt.Errorf("using a custom transport with TLS options, a unix socket, a local address or " +
+			"transport timeouts is not allowed, configure the transport itself instead")
+	}
+
+	var localAddr net.IP
+	if len(config.LocalAddr) != 0 {
+		if localAddr = net.ParseIP(config.LocalAddr); localAddr == nil {
+			return nil, fmt.Errorf("invalid local address %q, expected an IP address", config.LocalAddr)
+		}
+
+		if len(socket) != 0 {
+			return nil, fmt.Errorf("a local address can not be used with a unix socket")
+		}
+	}
+
+	if config.Insecure && config.Logger != nil {
+		config.Logger.Warn("server certificate verification is disabled, the connection is insecure",
+			"host", config.Host)
+	}
+
 	// Only retry when get a server side error.
+	retryableStatusCodes := config.RetryableStatusCodes
+	if len(retryableStatusCodes) == 0 {
+		retryableStatusCodes = DefaultRetryableStatusCodes
+	}
+
 	client := gorequest.New().TLSClientConfig(tlsConfig).Timeout(config.Timeout).
-		Retry(config.MaxRetries, config.RetryInterval, http.StatusInternalServerError)
+		Retry(config.MaxRetries, config.RetryInterval, retryableStatusCodes...)
 	// NOTICE: must set DoNotClearSuperAgent to true, or the client will clean header befor http.Do
 	client.DoNotClearSuperAgent = true
 
+	if len(socket) != 0 {
+		client.Transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	if localAddr != nil {
+		dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: localAddr}}
+		client.Transport.DialContext = dialer.DialContext
+	}
+
+	client.Transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	client.Transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+
+	var transport http.RoundTripper = client.Transport
+	if config.Transport != nil {
+		transport = config.Transport
+		client.RoundTripper = transport
+	}
+
+	if config.Recorder != nil {
+		recorder, err := newRecorder(config.Recorder, transport)
+		if err != nil {
+			return nil, err
+		}
+
+		client.RoundTripper = recorder
+	}
+
 	var gv scheme.GroupVersion
 	if config.GroupVersion != nil {
 		gv = *config.GroupVersion
 	}
 
 	clientContent := ClientContentConfig{
-		Username:           config.Username,
-		Password:           config.Password,
-		SecretID:           config.SecretID,
-		SecretKey:          config.SecretKey,
-		BearerToken:        config.BearerToken,
-		BearerTokenFile:    config.BearerTokenFile,
-		TLSClientConfig:    config.TLSClientConfig,
-		AcceptContentTypes: config.AcceptContentTypes,
-		ContentType:        config.ContentType,
-		GroupVersion:       gv,
-		Negotiator:         config.Negotiator,
-	}
-
-	return NewRESTClient(baseURL, versionedAPIPath, clientContent, client)
+		Username:              config.Username,
+		Password:              config.Password,
+		SecretID:              config.SecretID,
+		SecretKey:             config.SecretKey,
+		BearerToken:           config.BearerToken,
+		BearerTokenFile:       config.BearerTokenFile,
+		CheckTokenExpiry:      config.CheckTokenExpiry,
+		AuthScheme:            config.AuthScheme,
+		ProbeAuth:             config.ProbeAuth,
+		AuthProbePath:         config.AuthProbePath,
+		TLSClientConfig:       config.TLSClientConfig,
+		AcceptContentTypes:    config.AcceptContentTypes,
+		ContentType:           config.ContentType,
+		GroupVersion:          gv,
+		Negotiator:            config.Negotiator,
+		StrictDecoding:        config.StrictDecoding,
+		UseNumber:             config.UseNumber,
+		PreserveBasePath:      config.PreserveBasePath,
+		Priority:              config.Priority,
+		PriorityHeader:        config.PriorityHeader,
+		DeadlineHeader:        config.DeadlineHeader,
+		Headers:               config.Headers,
+		Logger:                config.Logger,
+		ResponseEnvelope:      config.ResponseEnvelope,
+		ResponseMeta:          config.ResponseMeta,
+		OnRetry:               config.OnRetry,
+		RetryBudgetHeader:     config.RetryBudgetHeader,
+		RetryJitter:           config.RetryJitter,
+		MaxRetryAfter:         config.MaxRetryAfter,
+		OnRequest:             config.OnRequest,
+		OnTrace:               config.OnTrace,
+		StatusReasons:         config.StatusReasons,
+		WarningHandler:        config.WarningHandler,
+		ReadOnly:              config.ReadOnly,
+		AdaptiveThrottle:      config.AdaptiveThrottle,
+		MaxRequestBytes:       config.MaxRequestBytes,
+		MaxConcurrentRequests: config.MaxConcurrentRequests,
+		TimeoutParamFormat:    config.TimeoutParamFormat,
+		BodyEncoders:          config.BodyEncoders,
+		WatchBackoff:          config.WatchBackoff,
+		Namespace:             config.Namespace,
+		NamespaceResource:     config.NamespaceResource,
+		EnableResponseCache:   config.EnableResponseCache,
+		ResponseCacheSize:     config.ResponseCacheSize,
+	}
+
+	restClient, err := NewRESTClient(baseURL, versionedAPIPath, clientContent, client)
+	if err != nil {
+		return nil, err
+	}
+
+	restClient.ctx = ctx
+	restClient.streamTransport = config.Transport
+
+	return restClient, nil
+}
+
+// unixSocketFor returns the unix domain socket the client should dial, taken from UnixSocket or
+// from a unix:// Host. For the latter a copy of config is returned whose Host is a plain http
+// URL, since requests over the socket are still ordinary HTTP requests.
+func unixSocketFor(config *Config) (*Config, string) {
+	if !strings.HasPrefix(config.Host, "unix://") {
+		return config, config.UnixSocket
+	}
+
+	socket := strings.TrimPrefix(config.Host, "unix://")
+	config = CopyConfig(config)
+	config.Host = "http://localhost"
+
+	return config, socket
 }
 
 // TLSConfigFor returns a tls.Config that will provide the transport level security defined
 // by the provided Config. Will return nil if no transport level security is requested.
 func TLSConfigFor(c *Config) (*tls.Config, error) {
-	if !(c.HasCA() || c.HasCertAuth() || c.Insecure || len(c.ServerName) > 0) {
+	if !(c.HasCA() || c.HasCertAuth() || c.Insecure || len(c.ServerName) > 0 || len(c.PinnedCertSHA256) > 0) {
 		return nil, nil
 	}
 
@@ -266,6 +700,15 @@ func TLSConfigFor(c *Config) (*tls.Config, error) {
 		tlsConfig.RootCAs = rootCertPool(c.CAData)
 	}
 
+	if len(c.PinnedCertSHA256) > 0 {
+		verifyPins, err := verifyPinnedCertificate(c.PinnedCertSHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.VerifyPeerCertificate = verifyPins
+	}
+
 	var staticCert *tls.Certificate
 	// Treat cert as static if either key or cert was data, not a file
 	if c.HasCertAuth() {
@@ -297,6 +740,48 @@ func TLSConfigFor(c *Config) (*tls.Config, error) {
 	return tlsConfig, nil
 }
 
+// verifyPinnedCertificate returns a tls.Config.VerifyPeerCertificate callback which rejects a
+// server whose leaf certificate SubjectPublicKeyInfo digest matches none of the given pins.
+// The callback runs after the standard chain verification, so pinning never weakens it.
+func verifyPinnedCertificate(pins []string) (func([][]byte, [][]*x509.Certificate) error, error) {
+	digests := make([][]byte, 0, len(pins))
+
+	for _, pin := range pins {
+		pin = strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+
+		digest, err := hex.DecodeString(pin)
+		if err != nil || len(digest) != sha256.Size {
+			digest, err = base64.StdEncoding.DecodeString(pin)
+		}
+
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("invalid pinned certificate SHA-256 digest %q", pin)
+		}
+
+		digests = append(digests, digest)
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("server presented no certificates to match against the pinned digests")
+		}
+
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+		for _, digest := range digests {
+			if subtle.ConstantTimeCompare(sum[:], digest) == 1 {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("server certificate for %q does not match any pinned SHA-256 digest", leaf.Subject.CommonName)
+	}, nil
+}
+
 // rootCertPool returns nil if caData is empty.  When passed along, this will mean "use system CAs".
 // When caData is not empty, it will be the ONLY information used in the CertPool.
 func rootCertPool(caData []byte) *x509.CertPool {
@@ -318,15 +803,35 @@ func rootCertPool(caData []byte) *x509.CertPool {
 
 // LoadTLSFiles copies the data from the CertFile, KeyFile, and CAFile fields into the CertData,
 // KeyData, and CAFile fields, or returns an error. If no error is returned, all three fields are
-// either populated or were empty to start.
+// either populated or were empty to start. The content of CAFiles is appended to the one of
+// CAFile, unless CAData was set. A PKCS#12 bundle is decoded into CertData and KeyData, unless
+// those were set.
 func LoadTLSFiles(c *Config) error {
 	var err error
 
+	hasCAData := len(c.CAData) > 0
+
 	c.CAData, err = dataFromSliceOrFile(c.CAData, c.CAFile)
 	if err != nil {
 		return err
 	}
 
+	if !hasCAData {
+		for _, file := range c.CAFiles {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			// keep PEM blocks of consecutive files apart, in case a file lacks a final newline
+			if len(c.CAData) > 0 && c.CAData[len(c.CAData)-1] != '\n' {
+				c.CAData = append(c.CAData, '\n')
+			}
+
+			c.CAData = append(c.CAData, data...)
+		}
+	}
+
 	c.CertData, err = dataFromSliceOrFile(c.CertData, c.CertFile)
 	if err != nil {
 		return err
@@ -337,13 +842,74 @@ func LoadTLSFiles(c *Config) error {
 		return err
 	}
 
+	if c.hasPFX() && len(c.CertData) == 0 && len(c.KeyData) == 0 {
+		pfxData, err := dataFromSliceOrFile(c.PFXData, c.PFXFile)
+		if err != nil {
+			return err
+		}
+
+		c.CertData, c.KeyData, err = decodePFX(pfxData, c.PFXPassword)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// decodePFX decodes a PKCS#12 bundle into PEM-encoded certificates and private key. The
+// certificate of the private key is returned first, followed by the rest of the chain.
+func decodePFX(pfxData []byte, password string) (certData, keyData []byte, err error) {
+	blocks, err := pkcs12.ToPEM(pfxData, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode PKCS#12 bundle: %w", err)
+	}
+
+	var keyID string
+
+	var leaf, chain []byte
+
+	for _, block := range blocks {
+		if block.Type == "PRIVATE KEY" {
+			if len(keyData) != 0 {
+				return nil, nil, fmt.Errorf("decode PKCS#12 bundle: more than one private key found")
+			}
+
+			keyID = block.Headers["localKeyId"]
+			keyData = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})
+		}
+	}
+
+	for _, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		data := pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})
+		if len(leaf) == 0 && keyID != "" && block.Headers["localKeyId"] == keyID {
+			leaf = data
+		} else {
+			chain = append(chain, data...)
+		}
+	}
+
+	if len(keyData) == 0 || len(leaf)+len(chain) == 0 {
+		return nil, nil, fmt.Errorf("decode PKCS#12 bundle: a certificate and a private key are required")
+	}
+
+	return append(leaf, chain...), keyData, nil
+}
+
 // dataFromSliceOrFile returns data from the slice (if non-empty), or from the file,
-// or an error if an error occurred reading the file.
+// or an error if an error occurred reading the file. The slice holds either PEM blocks, returned
+// as is, or base64-encoded data.
 func dataFromSliceOrFile(data []byte, file string) ([]byte, error) {
 	if len(data) > 0 {
+		// '-' is not part of the base64 alphabet, so encoded data never looks like PEM
+		if bytes.Contains(data, []byte("-----BEGIN ")) {
+			return data, nil
+		}
+
 		return base64.StdEncoding.DecodeString(string(data))
 	}
 
@@ -432,27 +998,73 @@ func AddUserAgent(config *Config, userAgent string) *Config {
 // CopyConfig returns a copy of the given config.
 func CopyConfig(config *Config) *Config {
 	return &Config{
-		Host:            config.Host,
-		APIPath:         config.APIPath,
-		ContentConfig:   config.ContentConfig,
-		Username:        config.Username,
-		Password:        config.Password,
-		SecretID:        config.SecretID,
-		SecretKey:       config.SecretKey,
-		BearerToken:     config.BearerToken,
-		BearerTokenFile: config.BearerTokenFile,
+		Host:                config.Host,
+		APIPath:             config.APIPath,
+		ContentConfig:       config.ContentConfig,
+		GroupContent:        config.GroupContent,
+		UnixSocket:          config.UnixSocket,
+		LocalAddr:           config.LocalAddr,
+		PreserveBasePath:    config.PreserveBasePath,
+		Priority:            config.Priority,
+		PriorityHeader:      config.PriorityHeader,
+		DeadlineHeader:      config.DeadlineHeader,
+		Headers:             config.Headers,
+		Logger:              config.Logger,
+		Namespace:           config.Namespace,
+		NamespaceResource:   config.NamespaceResource,
+		EnableResponseCache: config.EnableResponseCache,
+		ResponseCacheSize:   config.ResponseCacheSize,
+		Username:            config.Username,
+		Password:            config.Password,
+		SecretID:            config.SecretID,
+		SecretKey:           config.SecretKey,
+		BearerToken:         config.BearerToken,
+		BearerTokenFile:     config.BearerTokenFile,
+		CheckTokenExpiry:    config.CheckTokenExpiry,
+		AuthScheme:          config.AuthScheme,
+		ProbeAuth:           config.ProbeAuth,
+		AuthProbePath:       config.AuthProbePath,
 		TLSClientConfig: TLSClientConfig{
 			Insecure:   config.TLSClientConfig.Insecure,
 			ServerName: config.TLSClientConfig.ServerName,
 			CertFile:   config.TLSClientConfig.CertFile,
 			KeyFile:    config.TLSClientConfig.KeyFile,
 			CAFile:     config.TLSClientConfig.CAFile,
+			CAFiles:    config.TLSClientConfig.CAFiles,
 			CertData:   config.TLSClientConfig.CertData,
 			KeyData:    config.TLSClientConfig.KeyData,
 			CAData:     config.TLSClientConfig.CAData,
 			NextProtos: config.TLSClientConfig.NextProtos,
+
+			PFXFile:     config.TLSClientConfig.PFXFile,
+			PFXData:     config.TLSClientConfig.PFXData,
+			PFXPassword: config.TLSClientConfig.PFXPassword,
+
+			PinnedCertSHA256: config.TLSClientConfig.PinnedCertSHA256,
 		},
-		UserAgent: config.UserAgent,
-		Timeout:   config.Timeout,
+		UserAgent:             config.UserAgent,
+		Timeout:               config.Timeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		MaxRetries:            config.MaxRetries,
+		RetryInterval:         config.RetryInterval,
+		RetryableStatusCodes:  config.RetryableStatusCodes,
+		OnRetry:               config.OnRetry,
+		RetryBudgetHeader:     config.RetryBudgetHeader,
+		RetryJitter:           config.RetryJitter,
+		MaxRetryAfter:         config.MaxRetryAfter,
+		OnRequest:             config.OnRequest,
+		OnTrace:               config.OnTrace,
+		StatusReasons:         config.StatusReasons,
+		WarningHandler:        config.WarningHandler,
+		ReadOnly:              config.ReadOnly,
+		AdaptiveThrottle:      config.AdaptiveThrottle,
+		TimeoutParamFormat:    config.TimeoutParamFormat,
+		BodyEncoders:          config.BodyEncoders,
+		WatchBackoff:          config.WatchBackoff,
+		MaxRequestBytes:       config.MaxRequestBytes,
+		MaxConcurrentRequests: config.MaxConcurrentRequests,
+		Transport:             config.Transport,
+		Recorder:              config.Recorder,
 	}
 }
@@ -0,0 +1,548 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+	"github.com/marmotedu/component-base/pkg/scheme"
+)
+
+func TestPinnedCertSHA256(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	otherSum := sha256.Sum256([]byte("another key"))
+
+	tests := []struct {
+		name    string
+		pin     string
+		wantErr bool
+	}{
+		{"matching pin", base64.StdEncoding.EncodeToString(sum[:]), false},
+		{"non-matching pin", base64.StdEncoding.EncodeToString(otherSum[:]), true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.CAData = []byte(base64.StdEncoding.EncodeToString(caPEM))
+				c.PinnedCertSHA256 = []string{tc.pin}
+			})
+
+			err := client.Get().Resource("users").Do(context.TODO()).Error()
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "pinned") {
+					t.Errorf("expected a pinning error, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPinnedCertSHA256Invalid(t *testing.T) {
+	_, err := TLSConfigFor(&Config{TLSClientConfig: TLSClientConfig{PinnedCertSHA256: []string{"not-a-digest"}}})
+	if err == nil {
+		t.Error("expected an error for a malformed pin")
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	// t.TempDir can exceed the maximum length of a socket path, keep it short.
+	dir, err := os.MkdirTemp("", "iam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "iam.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"unix socket field", func(c *Config) {
+			c.Host = "http://iam.api.marmotedu.com"
+			c.UnixSocket = socket
+		}},
+		{"unix host scheme", func(c *Config) {
+			c.Host = "unix://" + socket
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var obj testObject
+			if err := testRESTClient(t, srv, tc.modify).Get().Resource("users").Do(context.TODO()).Into(&obj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if obj.Name != "colin" {
+				t.Errorf("expected name colin, got %q", obj.Name)
+			}
+		})
+	}
+}
+
+func TestLocalAddr(t *testing.T) {
+	var remoteAddr string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		remoteAddr = req.RemoteAddr
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// the whole 127.0.0.0/8 block is bound to the loopback interface on Linux, not everywhere
+	const source = "127.0.0.2"
+	if conn, err := (&net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(source)}}).Dial("tcp",
+		srv.Listener.Addr().String()); err != nil {
+		t.Skipf("%s is not a usable source address: %v", source, err)
+	} else {
+		conn.Close()
+	}
+
+	client := testRESTClient(t, srv, func(c *Config) { c.LocalAddr = source })
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if host, _, _ := net.SplitHostPort(remoteAddr); host != source {
+		t.Errorf("expected the connection to come from %s, got %s", source, remoteAddr)
+	}
+
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.LocalAddr = "eth0" },
+		func(c *Config) { c.LocalAddr, c.UnixSocket = source, "/var/run/iam.sock" },
+		func(c *Config) { c.LocalAddr, c.Transport = source, http.DefaultTransport },
+	} {
+		config := &Config{
+			Host: srv.URL,
+			ContentConfig: ContentConfig{
+				GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+				Negotiator:   runtime.NewSimpleClientNegotiator(),
+			},
+		}
+		modify(config)
+
+		if _, err := RESTClientFor(config); err == nil || !strings.Contains(err.Error(), "local address") {
+			t.Errorf("expected a local address error for %q, got %v", config.LocalAddr, err)
+		}
+	}
+}
+
+func TestBasePathJoin(t *testing.T) {
+	tests := []struct {
+		host     string
+		apiPath  string
+		preserve bool
+		want     string
+	}{
+		{"http://localhost", "", false, "/v1/users"},
+		{"http://localhost/", "", false, "/v1/users"},
+		{"http://localhost/proxy", "", false, "/proxy/v1/users"},
+		{"http://localhost/proxy/", "/", false, "/proxy/v1/users"},
+		{"http://localhost/proxy/", "/api/", false, "/proxy/api/v1/users"},
+		{"http://localhost/proxy//iam", "api", false, "/proxy/iam/api/v1/users"},
+		{"http://localhost", "", true, "/v1/users"},
+		{"http://localhost/", "/", true, "/v1/users"},
+		{"http://localhost/proxy", "", true, "/proxy/v1/users"},
+		{"http://localhost/proxy/", "/api/", true, "/proxy/api/v1/users"},
+		{"http://localhost/proxy//iam", "api", true, "/proxy//iam/api/v1/users"},
+	}
+
+	for _, tc := range tests {
+		config := &Config{
+			Host:             tc.host,
+			APIPath:          tc.apiPath,
+			PreserveBasePath: tc.preserve,
+			ContentConfig: ContentConfig{
+				GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+				Negotiator:   runtime.NewSimpleClientNegotiator(),
+			},
+		}
+
+		client, err := RESTClientFor(config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := client.Get().Resource("users").URL().Path; got != tc.want {
+			t.Errorf("host %q, apiPath %q, preserve %t: expected path %q, got %q",
+				tc.host, tc.apiPath, tc.preserve, tc.want, got)
+		}
+	}
+}
+
+func TestPreserveBasePathAbsPath(t *testing.T) {
+	client, err := RESTClientFor(&Config{
+		Host:             "http://localhost/proxy//iam/",
+		PreserveBasePath: true,
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := client.Get().AbsPath("/healthz").URL().Path; got != "/proxy//iam/healthz" {
+		t.Errorf("expected path %q, got %q", "/proxy//iam/healthz", got)
+	}
+
+	if got := client.Get().RequestURI("/version").URL().Path; got != "/version" {
+		t.Errorf("expected path %q, got %q", "/version", got)
+	}
+}
+
+// newSelfSignedTLSServer starts a TLS server whose certificate is signed by its own CA, and
+// returns the server with the PEM encoded CA certificate.
+func newSelfSignedTLSServer(t *testing.T, name string) (*httptest.Server, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCAFiles(t *testing.T) {
+	first, firstCA := newSelfSignedTLSServer(t, "first internal CA")
+	second, secondCA := newSelfSignedTLSServer(t, "second internal CA")
+	untrusted, _ := newSelfSignedTLSServer(t, "untrusted CA")
+
+	dir := t.TempDir()
+	firstFile := filepath.Join(dir, "first.crt")
+	secondFile := filepath.Join(dir, "second.crt")
+
+	if err := os.WriteFile(firstFile, firstCA, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// drop the final newline to make sure the files are kept apart
+	if err := os.WriteFile(secondFile, bytes.TrimSpace(secondCA), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		srv    *httptest.Server
+		modify func(*Config)
+		ok     bool
+	}{
+		{"single CAFile", first, func(c *Config) { c.CAFile = firstFile }, true},
+		{"first of CAFiles", first, func(c *Config) { c.CAFiles = []string{secondFile, firstFile} }, true},
+		{"second of CAFiles", second, func(c *Config) { c.CAFiles = []string{firstFile, secondFile} }, true},
+		{"CAFile and CAFiles", second, func(c *Config) {
+			c.CAFile = firstFile
+			c.CAFiles = []string{secondFile}
+		}, true},
+		{"untrusted", untrusted, func(c *Config) { c.CAFiles = []string{firstFile, secondFile} }, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := testRESTClient(t, tc.srv, tc.modify).Get().Resource("users").Do(context.TODO()).Error()
+			if tc.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Error("expected a certificate verification error")
+			}
+		})
+	}
+}
+
+func TestPFX(t *testing.T) {
+	const (
+		pfxFile     = "testdata/client.p12"
+		pfxPassword = "marmotedu"
+		commonName  = "marmotedu-sdk-go client"
+	)
+
+	pfxData, err := os.ReadFile(pfxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(req.TLS.PeerCertificates) == 0 || req.TLS.PeerCertificates[0].Subject.CommonName != commonName {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"PFXFile", func(c *Config) { c.PFXFile = pfxFile }},
+		{"PFXData", func(c *Config) { c.PFXData = []byte(base64.StdEncoding.EncodeToString(pfxData)) }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.Insecure = true
+				c.PFXPassword = pfxPassword
+				tc.modify(c)
+			})
+
+			if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	config := &Config{TLSClientConfig: TLSClientConfig{PFXFile: pfxFile, PFXPassword: "wrong"}}
+	if _, err := TLSConfigFor(config); err == nil {
+		t.Error("expected an error decoding a bundle with the wrong password")
+	}
+
+	if s := (TLSClientConfig{PFXData: []byte("secret"), PFXPassword: "secret"}).String(); strings.Contains(s, "secret") {
+		t.Errorf("expected the bundle and its password to be redacted, got %s", s)
+	}
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	// a listener accepting connections without ever answering the TLS handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client, err := RESTClientFor(&Config{
+		Host: "https://" + ln.Addr().String(),
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+		TLSClientConfig:     TLSClientConfig{Insecure: true},
+		TLSHandshakeTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = client.Get().Resource("users").Do(ctx).Error()
+	if err == nil || !strings.Contains(err.Error(), "handshake timeout") {
+		t.Errorf("expected a TLS handshake timeout, got %v", err)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v1/users/stalled" {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		// a slow body must not be interrupted once the headers are received
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) { c.ResponseHeaderTimeout = 100 * time.Millisecond })
+
+	err := client.Get().Resource("users").Name("stalled").Do(context.TODO()).Error()
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Errorf("expected a response header timeout, got %v", err)
+	}
+
+	obj := &testObject{}
+	if err := client.Get().Resource("users").Name("slow-body").Do(context.TODO()).Into(obj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if obj.Name != "colin" {
+		t.Errorf("expected name colin, got %q", obj.Name)
+	}
+}
+
+func TestSharedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"` + req.Header.Get("Authorization") + `"}`))
+	}))
+	defer srv.Close()
+
+	var dials int32
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	tenantA := testRESTClient(t, srv, func(c *Config) {
+		c.Transport = transport
+		c.BearerToken = "token-a"
+	})
+	tenantB := testRESTClient(t, srv, func(c *Config) {
+		c.Transport = transport
+		c.BearerToken = "token-b"
+	})
+
+	for i := 0; i < 3; i++ {
+		for client, want := range map[*RESTClient]string{tenantA: "Bearer token-a", tenantB: "Bearer token-b"} {
+			obj := &testObject{}
+			if err := client.Get().Resource("users").Do(context.TODO()).Into(obj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if obj.Name != want {
+				t.Errorf("expected the server to receive %q, got %q", want, obj.Name)
+			}
+		}
+	}
+
+	if got := atomic.LoadInt32(&dials); got != 1 {
+		t.Errorf("expected the clients to share a single connection, got %d dials", got)
+	}
+
+	_, err := RESTClientFor(&Config{
+		Host: srv.URL,
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+		TLSClientConfig: TLSClientConfig{Insecure: true},
+		Transport:       transport,
+	})
+	if err == nil {
+		t.Error("expected an error combining a custom transport with TLS options")
+	}
+}
+
+func TestAuthMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"token", Config{BearerToken: "token"}, AuthMethodToken},
+		{"token file", Config{BearerTokenFile: "/var/run/secrets/iam/token"}, AuthMethodToken},
+		{"secret", Config{SecretID: "id", SecretKey: "key"}, AuthMethodSecret},
+		{"secret id only", Config{SecretID: "id"}, AuthMethodNone},
+		{"basic", Config{Username: "colin", Password: "secret"}, AuthMethodBasic},
+		{"none", Config{}, AuthMethodNone},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.config.AuthMethod(); got != tc.want {
+				t.Errorf("expected config auth method %q, got %q", tc.want, got)
+			}
+
+			config := tc.config
+			config.Host = "https://iam.api.marmotedu.com"
+			config.GroupVersion = &scheme.GroupVersion{Group: "iam.api", Version: "v1"}
+			config.Negotiator = runtime.NewSimpleClientNegotiator()
+
+			client, err := RESTClientFor(&config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := client.AuthMethod(); got != tc.want {
+				t.Errorf("expected client auth method %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
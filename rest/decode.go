@@ -0,0 +1,17 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+// Decode decodes the result into a new object of type T and returns it.
+//
+//	user, err := rest.Decode[v1.User](client.Get().Resource("users").Name("colin").Do(ctx))
+func Decode[T any](r Result) (*T, error) {
+	obj := new(T)
+	if err := r.Into(obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
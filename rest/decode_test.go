@@ -0,0 +1,28 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	defer srv.Close()
+
+	obj, err := Decode[testObject](testRESTClient(t, srv).Get().Resource("users").Do(context.TODO()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if obj.Name != "colin" {
+		t.Errorf("expected name colin, got %q", obj.Name)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deprecation is the deprecation of a resource, as announced by the server in the Deprecation
+// and Sunset headers of its responses (RFC 9745 and RFC 8594).
+type Deprecation struct {
+	// Deprecated is set when the response has a Deprecation header.
+	Deprecated bool
+	// Date is when the resource was, or will be, deprecated. It is zero when the server didn't
+	// tell, eg. for "Deprecation: true".
+	Date time.Time
+	// Sunset is when the resource is expected to become unavailable, zero if unknown.
+	Sunset time.Time
+}
+
+// parseDeprecation returns the deprecation announced by the headers of a response, or nil if
+// there is none. The Deprecation header may hold a structured date, eg. "@1688169599", an
+// HTTP-date or "true", the Sunset header an HTTP-date.
+func parseDeprecation(header http.Header) *Deprecation {
+	var d Deprecation
+
+	if value := strings.TrimSpace(header.Get("Deprecation")); len(value) != 0 {
+		switch {
+		case value == "true" || value == "?1":
+			d.Deprecated = true
+		case strings.HasPrefix(value, "@"):
+			if seconds, err := strconv.ParseInt(value[1:], 10, 64); err == nil {
+				d.Deprecated, d.Date = true, time.Unix(seconds, 0).UTC()
+			}
+		default:
+			if date, err := http.ParseTime(value); err == nil {
+				d.Deprecated, d.Date = true, date
+			}
+		}
+	}
+
+	if value := strings.TrimSpace(header.Get("Sunset")); len(value) != 0 {
+		if date, err := http.ParseTime(value); err == nil {
+			d.Sunset = date
+		}
+	}
+
+	if !d.Deprecated && d.Sunset.IsZero() {
+		return nil
+	}
+
+	return &d
+}
+
+// warning returns the text reported to the warning handler for the deprecation of a resource,
+// eg. `"users" is deprecated since Sat, 01 Jul 2023 00:00:00 GMT and will be removed after
+// Mon, 01 Jul 2024 00:00:00 GMT`.
+func (d *Deprecation) warning(resource string, now time.Time) string {
+	var parts []string
+
+	switch {
+	case d.Deprecated && d.Date.IsZero():
+		parts = append(parts, "is deprecated")
+	case d.Deprecated && d.Date.After(now):
+		parts = append(parts, "will be deprecated on "+d.Date.UTC().Format(http.TimeFormat))
+	case d.Deprecated:
+		parts = append(parts, "is deprecated since "+d.Date.UTC().Format(http.TimeFormat))
+	}
+
+	if !d.Sunset.IsZero() {
+		parts = append(parts, "will be removed after "+d.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	return fmt.Sprintf("%q %s", resource, strings.Join(parts, " and "))
+}
+
+// deprecatedResource returns the name of the resource of the request in the deprecation
+// warnings, eg. "users" or "users/status", or the path of the request when it has no resource,
+// so that a warning is reported once per resource rather than once per object.
+func (r *Request) deprecatedResource() string {
+	switch {
+	case len(r.resource) == 0:
+		return r.URL().Path
+	case len(r.subresource) != 0:
+		return r.resource + "/" + r.subresource
+	default:
+		return r.resource
+	}
+}
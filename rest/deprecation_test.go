@@ -0,0 +1,113 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeprecation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v1/users/colin" || req.URL.Path == "/v1/users/lingfei" {
+			w.Header().Set("Deprecation", "@1688169600")
+			w.Header().Set("Sunset", "Mon, 01 Jul 2024 00:00:00 GMT")
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var handled []string
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.APIPath = "/"
+		c.WarningHandler = func(warning string) { handled = append(handled, warning) }
+	})
+
+	want := &Deprecation{
+		Deprecated: true,
+		Date:       time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for _, name := range []string{"colin", "lingfei"} {
+		result := client.Get().Resource("users").Name(name).Do(context.TODO())
+		if err := result.Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if d := result.Deprecation(); !reflect.DeepEqual(d, want) {
+			t.Errorf("expected the deprecation %+v, got %+v", want, d)
+		}
+	}
+
+	result := client.Get().Resource("secrets").Do(context.TODO())
+	if err := result.Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d := result.Deprecation(); d != nil {
+		t.Errorf("expected no deprecation, got %+v", d)
+	}
+
+	wantHandled := []string{`"users" is deprecated since Sat, 01 Jul 2023 00:00:00 GMT and will be ` +
+		`removed after Mon, 01 Jul 2024 00:00:00 GMT`}
+	if !reflect.DeepEqual(handled, wantHandled) {
+		t.Errorf("expected the handler to be called once per resource with %q, got %q", wantHandled, handled)
+	}
+}
+
+func TestParseDeprecation(t *testing.T) {
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	date := time.Date(2022, time.November, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		deprecation string
+		sunset      string
+		want        *Deprecation
+		wantWarning string
+	}{
+		{"none", "", "", nil, ""},
+		{"flag", "true", "", &Deprecation{Deprecated: true}, `"users" is deprecated`},
+		{"structured flag", "?1", "", &Deprecation{Deprecated: true}, `"users" is deprecated`},
+		{"structured date", "@1667260800", "", &Deprecation{Deprecated: true, Date: date},
+			`"users" is deprecated since Tue, 01 Nov 2022 00:00:00 GMT`},
+		{"http date", "Tue, 01 Nov 2022 00:00:00 GMT", "", &Deprecation{Deprecated: true, Date: date},
+			`"users" is deprecated since Tue, 01 Nov 2022 00:00:00 GMT`},
+		{"future date", "@1677628800", "", &Deprecation{Deprecated: true, Date: later},
+			`"users" will be deprecated on Wed, 01 Mar 2023 00:00:00 GMT`},
+		{"sunset only", "", "Wed, 01 Mar 2023 00:00:00 GMT", &Deprecation{Sunset: later},
+			`"users" will be removed after Wed, 01 Mar 2023 00:00:00 GMT`},
+		{"invalid", "soon", "later", nil, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if len(tc.deprecation) != 0 {
+				header.Set("Deprecation", tc.deprecation)
+			}
+			if len(tc.sunset) != 0 {
+				header.Set("Sunset", tc.sunset)
+			}
+
+			d := parseDeprecation(header)
+			if !reflect.DeepEqual(d, tc.want) {
+				t.Fatalf("expected %+v, got %+v", tc.want, d)
+			}
+
+			if d != nil {
+				if warning := d.warning("users", now); warning != tc.wantWarning {
+					t.Errorf("expected the warning %q, got %q", tc.wantWarning, warning)
+				}
+			}
+		})
+	}
+}
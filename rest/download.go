@@ -0,0 +1,44 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"io"
+)
+
+// Download sends the request and copies the response body to w as it is received, without
+// buffering it, eg. to save a large exported report to a file. It returns the number of bytes
+// written to w, which is only part of the body when an error interrupted the download.
+// The download is bounded by ctx and the request timeout rather than the client timeout.
+func (r *Request) Download(ctx context.Context, w io.Writer) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+
+		defer cancel()
+	}
+
+	ctx, cancel := r.withClientContext(ctx)
+	defer cancel()
+
+	resp, err := r.stream(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	written, err := io.Copy(w, resp.Body)
+	if err != nil && ctx.Err() != nil {
+		// report the cancellation rather than the read error it caused
+		return written, ctx.Err()
+	}
+
+	return written, err
+}
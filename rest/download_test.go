@@ -0,0 +1,97 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// chunkWriter fails when it receives more than max bytes at once, which happens if the body
+// was buffered before being written.
+type chunkWriter struct {
+	max     int
+	written int64
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		return 0, errors.New("body was buffered")
+	}
+
+	w.written += int64(len(p))
+
+	return len(p), nil
+}
+
+func TestDownload(t *testing.T) {
+	// 8MiB of CSV rows
+	const chunks = 2048
+
+	chunk := bytes.Repeat([]byte("a,b,c\n"), 682)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+
+		for i := 0; i < chunks; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	w := &chunkWriter{max: 64 << 10}
+
+	written, err := testRESTClient(t, srv).Get().AbsPath("/v1/reports/audit").Download(context.TODO(), w)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := int64(chunks * len(chunk))
+	if written != want || w.written != want {
+		t.Errorf("expected %d bytes written, got %d (writer received %d)", want, written, w.written)
+	}
+}
+
+func TestDownloadCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("first chunk\n"))
+		w.(http.Flusher).Flush()
+		cancel()
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+
+	written, err := testRESTClient(t, srv).Get().AbsPath("/v1/reports/audit").Download(ctx, &buf)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the download to be canceled, got %v", err)
+	}
+
+	if written != int64(buf.Len()) {
+		t.Errorf("expected %d bytes reported, got %d", buf.Len(), written)
+	}
+}
+
+func TestDownloadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":110001,"message":"Report not found"}`))
+	}))
+	defer srv.Close()
+
+	written, err := testRESTClient(t, srv).Get().AbsPath("/v1/reports/audit").Download(context.TODO(), io.Discard)
+	if err == nil || written != 0 {
+		t.Fatalf("expected an error and nothing written, got %d bytes and %v", written, err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+)
+
+// The JSON pointers to the payload and the metadata of JSON:API (https://jsonapi.org) documents,
+// for ContentConfig.ResponseEnvelope and ContentConfig.ResponseMeta.
+const (
+	JSONAPIEnvelope = "/data"
+	JSONAPIMeta     = "/meta"
+)
+
+// envelopeDecoder unwraps the payload found at a JSON pointer (RFC 6901) of a response body
+// before passing it to the underlying decoder.
+type envelopeDecoder struct {
+	pointer string
+	decoder runtime.Decoder
+}
+
+// Decode implements runtime.Decoder.
+func (d envelopeDecoder) Decode(data []byte, v interface{}) error {
+	payload, err := resolvePointer(data, d.pointer)
+	if err != nil {
+		return fmt.Errorf("unable to unwrap response envelope %q: %w", d.pointer, err)
+	}
+
+	return d.decoder.Decode(payload, v)
+}
+
+// resolvePointer returns the JSON value the pointer refers to in data. The empty pointer
+// refers to the whole document.
+func resolvePointer(data []byte, pointer string) ([]byte, error) {
+	if len(pointer) == 0 {
+		return data, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer must start with a slash")
+	}
+
+	value := json.RawMessage(data)
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err == nil {
+			next, ok := object[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+
+			value = next
+
+			continue
+		}
+
+		var array []json.RawMessage
+		if err := json.Unmarshal(value, &array); err != nil {
+			return nil, fmt.Errorf("can not resolve %q in a JSON scalar", token)
+		}
+
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index >= len(array) {
+			return nil, fmt.Errorf("invalid array index %q", token)
+		}
+
+		value = array[index]
+	}
+
+	return value, nil
+}
@@ -0,0 +1,254 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldError describes why the value of a single field was rejected by the server.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when the server rejects a request because of invalid fields,
+// eg. {"errors":[{"field":"email","message":"invalid"}]}.
+type ValidationError struct {
+	Code    int          `json:"code,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Errors  []FieldError `json:"errors"`
+
+	// StatusCode is the status code of the response.
+	StatusCode int `json:"-"`
+	// Reason is the meaning of StatusCode for the client, see Config.StatusReasons, or
+	// StatusReasonValidation when the status code has none.
+	Reason StatusReason `json:"-"`
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		fields = append(fields, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+
+	message := e.Message
+	if len(message) == 0 {
+		message = "validation failed"
+	}
+
+	return fmt.Sprintf("%s (%s)", message, strings.Join(fields, ", "))
+}
+
+// Fields returns the rejected fields.
+func (e *ValidationError) Fields() []FieldError {
+	return e.Errors
+}
+
+// IsValidationError returns true if err, or an error it wraps, is a ValidationError, or a
+// StatusError whose status code means a validation failure, see Config.StatusReasons.
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return true
+	}
+
+	return ReasonForError(err) == StatusReasonValidation
+}
+
+// FieldErrors returns the rejected fields of the ValidationError err is, or wraps.
+// It returns nil for other errors.
+func FieldErrors(err error) []FieldError {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+
+	return validationErr.Fields()
+}
+
+// newValidationError decodes a validation error response body, and returns nil when the body
+// does not list any rejected field. Its reason is looked up in reasons, see responseReason.
+func newValidationError(resp *http.Response, body []byte, reasons map[int]StatusReason) *ValidationError {
+	validationErr := &ValidationError{}
+	if err := json.Unmarshal(body, validationErr); err != nil || len(validationErr.Errors) == 0 {
+		return nil
+	}
+
+	validationErr.StatusCode = resp.StatusCode
+	if validationErr.Reason = responseReason(resp, reasons); validationErr.Reason == StatusReasonUnknown {
+		validationErr.Reason = StatusReasonValidation
+	}
+
+	if reason, ok := codeReasons[validationErr.Code]; ok {
+		validationErr.Reason = reason
+	}
+
+	return validationErr
+}
+
+// StatusReason tells what the status code of an error response means, see Config.StatusReasons.
+type StatusReason string
+
+// The reasons of the error responses.
+const (
+	// StatusReasonUnknown is the reason of the status codes without a known meaning.
+	StatusReasonUnknown StatusReason = ""
+	// StatusReasonValidation means the request was rejected because of invalid fields.
+	StatusReasonValidation StatusReason = "Validation"
+	// StatusReasonNotFound means the requested object does not exist.
+	StatusReasonNotFound StatusReason = "NotFound"
+	// StatusReasonConflict means the request conflicts with the current state of the object,
+	// eg. a delete precondition which no longer holds.
+	StatusReasonConflict StatusReason = "Conflict"
+	// StatusReasonAlreadyExists means the object to create already exists. It is the reason of
+	// a conflict answering a POST request, and of the CodeUserAlreadyExist error code.
+	StatusReasonAlreadyExists StatusReason = "AlreadyExists"
+	// StatusReasonUnauthorized means the credentials of the request are missing or invalid.
+	StatusReasonUnauthorized StatusReason = "Unauthorized"
+)
+
+// DefaultStatusReasons returns the standard reasons of the status codes, which
+// Config.StatusReasons overrides.
+func DefaultStatusReasons() map[int]StatusReason {
+	return map[int]StatusReason{
+		http.StatusBadRequest:         StatusReasonValidation,
+		http.StatusUnauthorized:       StatusReasonUnauthorized,
+		http.StatusNotFound:           StatusReasonNotFound,
+		http.StatusConflict:           StatusReasonConflict,
+		http.StatusPreconditionFailed: StatusReasonConflict,
+	}
+}
+
+// CodeUserAlreadyExist is the error code of the IAM API answering the creation of a user which
+// already exists, with 400 Bad Request.
+const CodeUserAlreadyExist = 110002
+
+// codeReasons are the reasons of the error codes of the IAM API which the status code of their
+// response doesn't tell, and which take precedence over it.
+var codeReasons = map[int]StatusReason{
+	CodeUserAlreadyExist: StatusReasonAlreadyExists,
+}
+
+// Status is the body of an error response of the IAM API,
+// eg. {"code":110001,"message":"User not found"}.
+type Status struct {
+	// Code is the error code of the IAM API, more precise than the status code.
+	Code      int    `json:"code"`
+	Message   string `json:"message,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// StatusError is returned when the server answers a request with an error status, unless the
+// answer is a ValidationError.
+type StatusError struct {
+	StatusCode int
+	// Reason is the meaning of StatusCode for the client, see Config.StatusReasons.
+	Reason StatusReason
+	// Message is the body of the response.
+	Message string
+	// Status is the decoded body of the response, or nil if it is not a Status.
+	Status *Status
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// ReasonForError returns the reason of the StatusError or ValidationError err is, or wraps, and
+// StatusReasonUnknown for other errors.
+func ReasonForError(err error) StatusReason {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Reason
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		if validationErr.Reason == StatusReasonUnknown {
+			return StatusReasonValidation
+		}
+
+		return validationErr.Reason
+	}
+
+	return StatusReasonUnknown
+}
+
+// IsNotFound returns true if err, or an error it wraps, reports that the requested object does
+// not exist.
+func IsNotFound(err error) bool {
+	return ReasonForError(err) == StatusReasonNotFound
+}
+
+// IsConflict returns true if err, or an error it wraps, reports that the request conflicts with
+// the current state of the object, including that the object to create already exists.
+func IsConflict(err error) bool {
+	reason := ReasonForError(err)
+
+	return reason == StatusReasonConflict || reason == StatusReasonAlreadyExists
+}
+
+// IsAlreadyExists returns true if err, or an error it wraps, reports that the object to create
+// already exists.
+func IsAlreadyExists(err error) bool {
+	return ReasonForError(err) == StatusReasonAlreadyExists
+}
+
+// IsUnauthorized returns true if err, or an error it wraps, reports that the credentials of the
+// request are missing or invalid.
+func IsUnauthorized(err error) bool {
+	return ReasonForError(err) == StatusReasonUnauthorized
+}
+
+// statusReason returns the reason of statusCode, looked up in reasons and then in the defaults.
+func statusReason(reasons map[int]StatusReason, statusCode int) StatusReason {
+	if reason, ok := reasons[statusCode]; ok {
+		return reason
+	}
+
+	return DefaultStatusReasons()[statusCode]
+}
+
+// responseReason returns the reason of an error response, looked up in reasons, see
+// statusReason. A conflict answering a POST request means that the object to create already
+// exists.
+func responseReason(resp *http.Response, reasons map[int]StatusReason) StatusReason {
+	reason := statusReason(reasons, resp.StatusCode)
+	if reason == StatusReasonConflict && resp.StatusCode == http.StatusConflict &&
+		resp.Request != nil && resp.Request.Method == http.MethodPost {
+		return StatusReasonAlreadyExists
+	}
+
+	return reason
+}
+
+// newStatusError returns the error of a response with an error status, whose reason is looked
+// up in reasons, see responseReason, unless the error code of the body has a reason of its own,
+// eg. CodeUserAlreadyExist.
+func newStatusError(resp *http.Response, body []byte, reasons map[int]StatusReason) *StatusError {
+	statusErr := &StatusError{
+		StatusCode: resp.StatusCode,
+		Reason:     responseReason(resp, reasons),
+		Message:    string(body),
+	}
+
+	status := &Status{}
+	if err := json.Unmarshal(body, status); err == nil && (status.Code != 0 || len(status.Message) != 0) {
+		statusErr.Status = status
+
+		if reason, ok := codeReasons[status.Code]; ok {
+			statusErr.Reason = reason
+		}
+	}
+
+	return statusErr
+}
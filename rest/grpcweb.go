@@ -0,0 +1,144 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+)
+
+// GRPCWebContentType is the content type of the gRPC-Web responses carrying JSON messages, as
+// returned by gRPC-Web gateways. Responses of any application/grpc-web content type are
+// unframed before being decoded.
+const GRPCWebContentType = "application/grpc-web+json"
+
+const (
+	// grpcWebHeaderLen is the length of the header of a gRPC-Web frame: one byte of flags
+	// followed by the length of the frame payload, a 4-byte big-endian integer.
+	grpcWebHeaderLen = 5
+	// grpcWebCompressedFlag marks a frame whose payload is compressed.
+	grpcWebCompressedFlag = 0x01
+	// grpcWebTrailerFlag marks the frame carrying the trailers, after the message frames.
+	grpcWebTrailerFlag = 0x80
+)
+
+// isGRPCWeb returns whether the content type is a gRPC-Web one, and if so whether the frames
+// are base64 encoded, as in the application/grpc-web-text content types.
+func isGRPCWeb(contentType string) (framed, text bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false, false
+	}
+
+	switch {
+	case mediaType == "application/grpc-web-text" || strings.HasPrefix(mediaType, "application/grpc-web-text+"):
+		return true, true
+	case mediaType == "application/grpc-web" || strings.HasPrefix(mediaType, "application/grpc-web+"):
+		return true, false
+	default:
+		return false, false
+	}
+}
+
+// grpcWebDecoder strips the gRPC-Web frames of a response body before passing the message
+// to the underlying decoder. A non-zero grpc-status trailer is reported as an error, which Do
+// also sets on the Result.
+type grpcWebDecoder struct {
+	text    bool
+	decoder runtime.Decoder
+}
+
+// Decode implements runtime.Decoder.
+func (d grpcWebDecoder) Decode(data []byte, v interface{}) error {
+	message, err := d.message(data)
+	if err != nil {
+		return err
+	}
+
+	return d.decoder.Decode(message, v)
+}
+
+// message returns the message carried by a gRPC-Web response body, or the error reported by
+// its trailers.
+func (d grpcWebDecoder) message(data []byte) ([]byte, error) {
+	if d.text {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode gRPC-Web text response: %w", err)
+		}
+
+		data = decoded
+	}
+
+	return unframeGRPCWeb(data)
+}
+
+// unframeGRPCWeb returns the message carried by the data frames of a gRPC-Web response body.
+func unframeGRPCWeb(data []byte) ([]byte, error) {
+	var message []byte
+
+	for len(data) > 0 {
+		if len(data) < grpcWebHeaderLen {
+			return nil, fmt.Errorf("truncated gRPC-Web frame header of %d bytes", len(data))
+		}
+
+		flags := data[0]
+		length := binary.BigEndian.Uint32(data[1:grpcWebHeaderLen])
+
+		if uint64(len(data)-grpcWebHeaderLen) < uint64(length) {
+			return nil, fmt.Errorf("truncated gRPC-Web frame: expected %d bytes, got %d",
+				length, len(data)-grpcWebHeaderLen)
+		}
+
+		payload := data[grpcWebHeaderLen : grpcWebHeaderLen+int(length)]
+		data = data[grpcWebHeaderLen+int(length):]
+
+		switch {
+		case flags&grpcWebTrailerFlag != 0:
+			if err := grpcWebTrailerError(payload); err != nil {
+				return nil, err
+			}
+		case flags&grpcWebCompressedFlag != 0:
+			return nil, fmt.Errorf("compressed gRPC-Web frames are not supported")
+		default:
+			message = append(message, payload...)
+		}
+	}
+
+	return message, nil
+}
+
+// grpcWebTrailerError returns the error reported by the trailers of a gRPC-Web response,
+// which are formatted as HTTP/1 headers.
+func grpcWebTrailerError(payload []byte) error {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(payload, "\r\n"...))))
+
+	trailers, err := reader.ReadMIMEHeader()
+	if err != nil && len(trailers) == 0 {
+		return fmt.Errorf("unable to read gRPC-Web trailers: %w", err)
+	}
+
+	return grpcWebStatusError(http.Header(trailers))
+}
+
+// grpcWebStatusError returns the error reported by the grpc-status and grpc-message fields of
+// the trailers, or of the headers of a trailers-only response, which has no body.
+func grpcWebStatusError(header http.Header) error {
+	status := header.Get("Grpc-Status")
+	if status == "" || status == "0" {
+		return nil
+	}
+
+	return fmt.Errorf("gRPC-Web status %s: %s", status, header.Get("Grpc-Message"))
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func grpcWebFrame(flags byte, payload string) []byte {
+	frame := make([]byte, grpcWebHeaderLen, grpcWebHeaderLen+len(payload))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+
+	return append(frame, payload...)
+}
+
+func TestGRPCWebDecoding(t *testing.T) {
+	message := grpcWebFrame(0, `{"name":"colin","id":1}`)
+	ok := grpcWebFrame(grpcWebTrailerFlag, "grpc-status: 0\r\ngrpc-message: \r\n")
+	denied := grpcWebFrame(grpcWebTrailerFlag, "grpc-status: 7\r\ngrpc-message: permission denied\r\n")
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantErr     string
+	}{
+		{"framed", GRPCWebContentType, append(append([]byte{}, message...), ok...), ""},
+		{"without trailers", "application/grpc-web+json; charset=utf-8", message, ""},
+		{"text", "application/grpc-web-text+json",
+			[]byte(base64.StdEncoding.EncodeToString(append(append([]byte{}, message...), ok...))), ""},
+		{"error status", GRPCWebContentType, append(append([]byte{}, message...), denied...), "permission denied"},
+		{"truncated", GRPCWebContentType, message[:10], "truncated gRPC-Web frame"},
+		{"compressed", GRPCWebContentType, grpcWebFrame(grpcWebCompressedFlag, "x"), "not supported"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				_, _ = w.Write(tc.body)
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv)
+
+			var user struct {
+				Name string `json:"name"`
+				ID   int64  `json:"id"`
+			}
+
+			result := client.Get().Resource("users").Name("colin").Do(context.TODO())
+
+			err := result.Into(&user)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+
+				if err := result.Error(); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected the result error to contain %q, got %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			if err := result.Error(); err != nil {
+				t.Fatalf("unexpected result error: %v", err)
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if user.Name != "colin" || user.ID != 1 {
+				t.Errorf("unexpected decoded user %+v", user)
+			}
+		})
+	}
+}
+
+func TestGRPCWebTrailersOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", GRPCWebContentType)
+		if req.URL.Path == "/v1/users/colin" {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "user not found")
+		} else {
+			w.Header().Set("Grpc-Status", "0")
+		}
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	for _, verb := range []string{"GET", "DELETE"} {
+		err := client.Verb(verb).Resource("users").Name("colin").Do(context.TODO()).Error()
+		if err == nil || err.Error() != "gRPC-Web status 5: user not found" {
+			t.Errorf("%s: expected the status of the headers as error, got %v", verb, err)
+		}
+	}
+
+	if err := client.Delete().Resource("users").Name("lingfei").Do(context.TODO()).Error(); err != nil {
+		t.Errorf("unexpected error for an OK status: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+// Logger receives the diagnostic messages of the client, such as retried requests or an
+// insecure TLS configuration. keysAndValues are alternating keys and values adding
+// structured context to the message.
+type Logger interface {
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+}
+
+// nopLogger discards every message. It is used when no Logger is configured.
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+func (nopLogger) Warn(msg string, keysAndValues ...interface{}) {}
+
+// logger returns the Logger of the client, or a nopLogger when it has none, eg. for the
+// clients built by NewRequestWithClient rather than NewRESTClient.
+func (c *RESTClient) logger() Logger {
+	if c.content.Logger == nil {
+		return nopLogger{}
+	}
+
+	return c.content.Logger
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"time"
+)
+
+// RequestMetric describes a request sent with Do, see Config.OnRequest.
+type RequestMetric struct {
+	Verb     string
+	Resource string
+	// StatusCode is the status of the last response, zero when no response was received.
+	StatusCode int
+	// Duration is the time spent in Do, including retries.
+	Duration time.Duration
+	Err      error
+	// Labels are the labels attached to the context of the request with WithMetricsLabels.
+	Labels map[string]string
+}
+
+type metricsLabelsKey struct{}
+
+// WithMetricsLabels returns a copy of ctx carrying labels, which are passed to Config.OnRequest
+// for the requests sent with the context, eg. {"operation": "reconcile-loop"}. Labels set on ctx
+// are kept unless overridden. Keeping the number of distinct label values bounded is the
+// responsibility of the caller, a request ID would grow the metrics without limit.
+func WithMetricsLabels(ctx context.Context, labels map[string]string) context.Context {
+	parent, _ := ctx.Value(metricsLabelsKey{}).(map[string]string)
+
+	merged := make(map[string]string, len(parent)+len(labels))
+	for key, value := range parent {
+		merged[key] = value
+	}
+
+	for key, value := range labels {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, metricsLabelsKey{}, merged)
+}
+
+// MetricsLabels returns a copy of the labels attached to ctx with WithMetricsLabels, or nil.
+func MetricsLabels(ctx context.Context) map[string]string {
+	labels, ok := ctx.Value(metricsLabelsKey{}).(map[string]string)
+	if !ok {
+		return nil
+	}
+
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+
+	return copied
+}
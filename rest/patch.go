@@ -0,0 +1,19 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+// PatchType is the format of the body of a PATCH request, sent as its Content-Type header.
+type PatchType string
+
+// The patch formats understood by the server.
+const (
+	// JSONPatchType is a list of operations as defined by RFC 6902.
+	JSONPatchType PatchType = "application/json-patch+json"
+	// MergePatchType is a partial object as defined by RFC 7386.
+	MergePatchType PatchType = "application/merge-patch+json"
+	// StrategicMergePatchType is a partial object whose lists are merged by key instead of
+	// being replaced.
+	StrategicMergePatchType PatchType = "application/strategic-merge-patch+json"
+)
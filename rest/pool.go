@@ -0,0 +1,79 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// IntoPooled decodes the response into an object taken from pool instead of a newly allocated
+// one, to reduce the garbage of hot loops decoding many objects, eg. a reconcile loop listing
+// thousands of users. The New function of pool must return a non-nil pointer, eg.
+// func() interface{} { return &v1.UserList{} }.
+//
+// The object is reset before it is decoded, reusing the memory it references: the structs
+// pointed to by its fields and by the elements of its slices, and the backing arrays of its
+// slices. The returned object is owned by the caller until it is put back with pool.Put; after
+// that neither the object nor anything obtained from it, eg. a *v1.User of a list's Items, may
+// be used, as the next call to IntoPooled overwrites them. Copy what must outlive the object
+// before putting it back. On error the object is put back to the pool and nil is returned.
+func (r Result) IntoPooled(pool *sync.Pool) (interface{}, error) {
+	obj := pool.Get()
+
+	v := reflect.ValueOf(obj)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("pool returned %T, expected a non-nil pointer", obj)
+	}
+
+	resetValue(v.Elem())
+
+	if err := r.Into(obj); err != nil {
+		pool.Put(obj)
+
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// resetValue sets v to its zero value, keeping the memory it references for the decoder to
+// reuse: the pointed to values are reset in place and the slices are truncated after their
+// elements, up to their capacity, are reset.
+func resetValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			resetValue(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// structs with unexported fields, eg. time.Time, can only be reset as a whole
+			if !v.Field(i).CanSet() {
+				v.Set(reflect.Zero(v.Type()))
+
+				return
+			}
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			resetValue(v.Field(i))
+		}
+	case reflect.Slice:
+		full := v.Slice(0, v.Cap())
+		for i := 0; i < full.Len(); i++ {
+			resetValue(full.Index(i))
+		}
+
+		v.SetLen(0)
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.Value{})
+		}
+	default:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	"github.com/marmotedu/component-base/pkg/runtime"
+)
+
+func testUserListResult(t testing.TB, names ...string) Result {
+	t.Helper()
+
+	list := &v1.UserList{}
+	list.TotalCount = int64(len(names))
+
+	for _, name := range names {
+		user := &v1.User{Nickname: name, Email: name + "@foxmail.com"}
+		user.Name = name
+		list.Items = append(list.Items, user)
+	}
+
+	body, err := json.Marshal(list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoder, err := runtime.NewSimpleClientNegotiator().Decoder()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return Result{body: body, decoder: decoder}
+}
+
+func TestIntoPooled(t *testing.T) {
+	pool := &sync.Pool{New: func() interface{} { return &v1.UserList{} }}
+
+	obj, err := testUserListResult(t, "colin", "james").IntoPooled(pool)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list := obj.(*v1.UserList)
+	list.Items[1].Phone = "1812884xxxx"
+	list.Items[0].Extend = map[string]interface{}{"stale": true}
+	first := list.Items[0]
+	pool.Put(list)
+
+	// a single pooled object is handed out again, with no leftovers of its previous use
+	pool = &sync.Pool{New: func() interface{} { return list }}
+
+	obj, err = testUserListResult(t, "tony").IntoPooled(pool)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reused := obj.(*v1.UserList)
+	if reused.TotalCount != 1 || len(reused.Items) != 1 || reused.Items[0].Name != "tony" {
+		t.Fatalf("unexpected list %+v", reused)
+	}
+
+	if reused.Items[0] != first {
+		t.Errorf("expected the pooled user to be reused")
+	}
+
+	if len(reused.Items[0].Extend) != 0 || reused.Items[:2][1].Phone != "" {
+		t.Errorf("expected the pooled users to be reset, got %+v %+v", reused.Items[0], reused.Items[:2][1])
+	}
+
+	if _, err := (Result{err: fmt.Errorf("failed")}).IntoPooled(pool); err == nil {
+		t.Errorf("expected the error of the result")
+	}
+
+	if _, err := testUserListResult(t).IntoPooled(&sync.Pool{}); err == nil {
+		t.Errorf("expected an error for a pool without New")
+	}
+}
+
+func BenchmarkInto(b *testing.B) {
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = fmt.Sprintf("user%d", i)
+	}
+
+	result := testUserListResult(b, names...)
+
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if err := result.Into(&v1.UserList{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		pool := &sync.Pool{New: func() interface{} { return &v1.UserList{} }}
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			obj, err := result.IntoPooled(pool)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			pool.Put(obj)
+		}
+	})
+}
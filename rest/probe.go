@@ -0,0 +1,92 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultAuthProbePath is the endpoint the credentials are probed against when
+// ClientContentConfig.AuthProbePath is not set. Listing a single secret of the caller is cheap
+// and allowed to every authenticated user of the IAM API server.
+const DefaultAuthProbePath = "/v1/secrets?limit=1"
+
+// authProber finds out which of the credentials of a client the server accepts, see
+// Config.ProbeAuth.
+type authProber struct {
+	mu     sync.Mutex
+	winner string
+}
+
+// method returns the first of methods whose credentials the server accepts. The next method is
+// only tried when the server rejects the credentials with 401 Unauthorized or 403 Forbidden,
+// other failures, eg. a 503 or a timeout, are returned and the server is probed again by the
+// next request. Once found, the accepted method is cached. Concurrent requests wait for the
+// outcome of a running probe.
+func (p *authProber) method(ctx context.Context, c *RESTClient, methods []string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.winner) != 0 {
+		return p.winner, nil
+	}
+
+	probePath := c.content.AuthProbePath
+	if len(probePath) == 0 {
+		probePath = DefaultAuthProbePath
+	}
+
+	locator, err := url.Parse(probePath)
+	if err != nil {
+		return "", fmt.Errorf("invalid auth probe path %q: %w", probePath, err)
+	}
+
+	errs := make([]string, 0, len(methods))
+
+	for _, method := range methods {
+		req := NewRequest(c).Verb("GET").AbsPath(locator.Path)
+		for key, values := range locator.Query() {
+			for _, value := range values {
+				req.Param(key, value)
+			}
+		}
+
+		req.authMethod = method
+
+		// a single attempt, outside of the concurrency limit held by the probing request
+		resp, _, err := req.do(ctx)
+		if err == nil {
+			c.logger().Info("credentials accepted by the server", "authMethod", method)
+			p.winner = method
+
+			return method, nil
+		}
+
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if resp == nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+			return "", fmt.Errorf("probing the %s credentials against %s: %w", method, probePath, err)
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %v", method, err))
+	}
+
+	return "", fmt.Errorf("none of the credentials was accepted by %s: %s", probePath, strings.Join(errs, "; "))
+}
+
+// accepted returns the method found by the last successful probe, if any.
+func (p *authProber) accepted() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.winner
+}
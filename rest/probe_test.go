@@ -0,0 +1,124 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeAuth(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization := req.Header.Get("Authorization")
+		method := "token"
+		if strings.Count(authorization, ".") == 2 {
+			method = "secret"
+		}
+
+		requests = append(requests, req.URL.RequestURI()+" "+method)
+
+		// this server only accepts signed tokens
+		if method != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":100205,"message":"Token invalid"}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "token"
+		c.SecretID = "id"
+		c.SecretKey = "key"
+		c.ProbeAuth = true
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	want := "[/v1/secrets?limit=1 token /v1/secrets?limit=1 secret /v1/users secret /v1/users secret]"
+	if got := fmt.Sprint(requests); got != want {
+		t.Errorf("expected the requests %s, got %s", want, got)
+	}
+
+	if method := client.AuthMethod(); method != AuthMethodSecret {
+		t.Errorf("expected the accepted method, got %q", method)
+	}
+}
+
+func TestProbeAuthNoneAccepted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "token"
+		c.Username = "colin"
+		c.Password = "Admin@2020"
+		c.ProbeAuth = true
+		c.AuthProbePath = "/v1/users/colin"
+	})
+
+	err := client.Get().Resource("users").Do(context.TODO()).Error()
+	if err == nil || !strings.Contains(err.Error(), "none of the credentials was accepted by /v1/users/colin") {
+		t.Errorf("expected the probe to fail, got %v", err)
+	}
+}
+
+func TestProbeAuthTransientFailure(t *testing.T) {
+	var probes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v1/secrets" {
+			probes++
+			if probes == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "token"
+		c.SecretID = "id"
+		c.SecretKey = "key"
+		c.ProbeAuth = true
+	})
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err == nil {
+		t.Fatal("expected the failure of the probe")
+	}
+
+	if method := client.AuthMethod(); method == AuthMethodSecret {
+		t.Fatalf("expected no method to be cached after a transient failure, got %q", method)
+	}
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method := client.AuthMethod(); method != AuthMethodToken {
+		t.Errorf("expected the token to be accepted, got %q", method)
+	}
+
+	if probes != 2 {
+		t.Errorf("expected the server to be probed again, got %d probes", probes)
+	}
+}
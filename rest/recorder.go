@@ -0,0 +1,311 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RecorderMode tells whether a recorder saves the interactions with the server or serves them.
+type RecorderMode string
+
+const (
+	// RecorderModeRecord sends the requests to the server and saves every request and response
+	// pair to the recorder file.
+	RecorderModeRecord RecorderMode = "record"
+	// RecorderModeReplay serves the responses saved in the recorder file without contacting the
+	// server. A request matching no saved interaction fails.
+	RecorderModeReplay RecorderMode = "replay"
+)
+
+// redactedHeaders are the headers whose values are replaced before the interactions are saved.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// RecorderConfig configures the recording of the interactions with the server, to replay them
+// in deterministic tests afterwards. Interactions are matched on the method, the request URI and
+// the SHA-256 digest of the request body. Watch streams are neither recorded nor replayed.
+//
+// The credentials of the request and response headers are redacted from the saved interactions,
+// but the response bodies are saved as they are received, eg. the secretKey of the secrets: set
+// RedactBody before committing the file of a recording holding sensitive data.
+type RecorderConfig struct {
+	Mode RecorderMode
+	// File is the path of the JSON file holding the interactions. The clients of a process
+	// recording to the same file, eg. the clients of the services of iam.NewForConfig, save
+	// their interactions together.
+	File string
+	// RedactBody returns the body of a response as it is saved to File, eg. with the secrets
+	// replaced. It is called with the body received from the server in record mode, and the
+	// caller still gets the body as received. Optional.
+	RedactBody func(body []byte) []byte
+}
+
+// recordedRequest is the request of a recorded interaction.
+type recordedRequest struct {
+	Method     string      `json:"method"`
+	URI        string      `json:"uri"`
+	Header     http.Header `json:"header,omitempty"`
+	BodySHA256 string      `json:"bodySHA256"`
+}
+
+// recordedResponse is the response of a recorded interaction.
+type recordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// interaction is a request and response pair.
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+// key returns the value interactions are matched on.
+func (r recordedRequest) key() string {
+	return r.Method + " " + r.URI + " " + r.BodySHA256
+}
+
+// recording holds the interactions recorded to a file. It is shared by the recorders of every
+// client of the process recording to the file, which would otherwise overwrite the interactions
+// of each other.
+type recording struct {
+	lock         sync.Mutex
+	interactions []interaction
+}
+
+var (
+	recordingsLock sync.Mutex
+	// recordings are the recordings of the process, by absolute file path.
+	recordings = map[string]*recording{}
+)
+
+// recordingFor returns the recording of the file, which starts empty for the first recorder of
+// the process recording to it.
+func recordingFor(file string) *recording {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+
+	recordingsLock.Lock()
+	defer recordingsLock.Unlock()
+
+	rec, ok := recordings[file]
+	if !ok {
+		rec = &recording{}
+		recordings[file] = rec
+	}
+
+	return rec
+}
+
+// recorder is a http.RoundTripper recording or replaying the interactions with the server.
+type recorder struct {
+	mode       RecorderMode
+	file       string
+	transport  http.RoundTripper
+	redactBody func(body []byte) []byte
+
+	// recording holds the interactions saved to the file in record mode.
+	recording *recording
+
+	// interactions are the interactions loaded from the file in replay mode.
+	lock         sync.Mutex
+	interactions []interaction
+	// served counts the replayed interactions of every key, to replay identical requests in order.
+	served map[string]int
+}
+
+// newRecorder returns a recorder sending the requests through transport in record mode.
+// In replay mode the interactions are loaded from the file right away.
+func newRecorder(config *RecorderConfig, transport http.RoundTripper) (*recorder, error) {
+	if len(config.File) == 0 {
+		return nil, fmt.Errorf("recorder file must be set")
+	}
+
+	r := &recorder{
+		mode:       config.Mode,
+		file:       config.File,
+		transport:  transport,
+		redactBody: config.RedactBody,
+		served:     map[string]int{},
+	}
+
+	switch config.Mode {
+	case RecorderModeRecord:
+		r.recording = recordingFor(config.File)
+	case RecorderModeReplay:
+		data, err := ioutil.ReadFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			return nil, fmt.Errorf("unable to load recorded interactions from %s: %w", config.File, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown recorder mode %q", config.Mode)
+	}
+
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	recorded := recordedRequest{
+		Method:     req.Method,
+		URI:        req.URL.RequestURI(),
+		Header:     redactHeader(req.Header),
+		BodySHA256: hex.EncodeToString(sum[:]),
+	}
+
+	if r.mode == RecorderModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	savedBody := respBody
+	if r.redactBody != nil {
+		savedBody = r.redactBody(append([]byte(nil), respBody...))
+	}
+
+	err = r.record(interaction{
+		Request: recorded,
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       savedBody,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// record appends the interaction to the recorder file, which is rewritten every time so that it
+// is complete even if the process exits without notice.
+func (r *recorder) record(i interaction) error {
+	r.recording.lock.Lock()
+	defer r.recording.lock.Unlock()
+
+	r.recording.interactions = append(r.recording.interactions, i)
+
+	data, err := json.MarshalIndent(r.recording.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.file), 0o755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.file, data, 0o600)
+}
+
+// replay returns the response of the recorded interaction matching the request. Identical
+// requests are served the matching interactions in the order they were recorded, the last one
+// being repeated once they are exhausted.
+func (r *recorder) replay(req *http.Request, recorded recordedRequest) (*http.Response, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := recorded.key()
+
+	var matches []interaction
+
+	for _, i := range r.interactions {
+		if i.Request.key() == key {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", recorded.Method, recorded.URI)
+	}
+
+	n := r.served[key]
+	if n >= len(matches) {
+		n = len(matches) - 1
+	}
+
+	r.served[key]++
+
+	resp := matches[n].Response
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the body of the request, leaving a copy of it in place to be sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// redactHeader returns a copy of the header whose credentials are redacted.
+func redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+
+	redacted := header.Clone()
+	for _, name := range redactedHeaders {
+		if len(redacted.Values(name)) != 0 {
+			redacted.Set(name, "--- REDACTED ---")
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRecordThenReplay(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hits++
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		_ = json.NewEncoder(w).Encode(&testObject{Name: req.Method + " " + req.URL.Path + " " + string(body)})
+	}))
+
+	file := filepath.Join(t.TempDir(), "recordings", "users.json")
+	calls := func(client *RESTClient) []string {
+		var names []string
+		for _, req := range []*Request{
+			client.Get().Resource("users").Name("colin"),
+			client.Post().Resource("users").Body(&testObject{Name: "a"}),
+			client.Post().Resource("users").Body(&testObject{Name: "b"}),
+		} {
+			obj := &testObject{}
+			if err := req.Do(context.TODO()).Into(obj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			names = append(names, obj.Name)
+		}
+
+		return names
+	}
+
+	recorded := calls(testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "top-secret-token"
+		c.Recorder = &RecorderConfig{Mode: RecorderModeRecord, File: file}
+	}))
+	srv.Close()
+
+	if hits != 3 {
+		t.Fatalf("expected 3 requests to the server while recording, got %d", hits)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "top-secret-token") || strings.Contains(string(data), "session=secret") {
+		t.Errorf("expected the credentials to be redacted, got %s", data)
+	}
+
+	var interactions []interaction
+	if err := json.Unmarshal(data, &interactions); err != nil || len(interactions) != 3 {
+		t.Fatalf("expected 3 recorded interactions, got %d (%v)", len(interactions), err)
+	}
+
+	replayClient := testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "another-token"
+		c.Recorder = &RecorderConfig{Mode: RecorderModeReplay, File: file}
+	})
+
+	replayed := calls(replayClient)
+	if strings.Join(replayed, "|") != strings.Join(recorded, "|") {
+		t.Errorf("expected the replayed responses %q to match the recorded ones %q", replayed, recorded)
+	}
+
+	if hits != 3 {
+		t.Errorf("expected no request to the server while replaying, got %d", hits-3)
+	}
+
+	err = replayClient.Post().Resource("users").Body(&testObject{Name: "c"}).Do(context.TODO()).Error()
+	if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("expected an error for a request that was not recorded, got %v", err)
+	}
+}
+
+func TestRecorderSharedFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"` + req.URL.Path + `","secretKey":"top-secret-key"}`))
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "recordings.json")
+	redact := func(body []byte) []byte {
+		return []byte(strings.ReplaceAll(string(body), "top-secret-key", "--- REDACTED ---"))
+	}
+
+	// the clients of two services recording to the same file
+	var clients []*RESTClient
+	for _, apiPath := range []string{"/apiserver", "/authz"} {
+		apiPath := apiPath
+		clients = append(clients, testRESTClient(t, srv, func(c *Config) {
+			c.APIPath = apiPath
+			c.Recorder = &RecorderConfig{Mode: RecorderModeRecord, File: file, RedactBody: redact}
+		}))
+	}
+
+	for _, client := range clients {
+		body, err := client.Get().Resource("secrets").Do(context.TODO()).Raw()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(string(body), "top-secret-key") {
+			t.Errorf("expected the caller to get the body as received, got %s", body)
+		}
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "top-secret-key") {
+		t.Errorf("expected the response bodies to be redacted, got %s", data)
+	}
+
+	for _, apiPath := range []string{"/apiserver", "/authz"} {
+		client := testRESTClient(t, srv, func(c *Config) {
+			c.APIPath = apiPath
+			c.Recorder = &RecorderConfig{Mode: RecorderModeReplay, File: file}
+		})
+
+		obj := &testObject{}
+		if err := client.Get().Resource("secrets").Do(context.TODO()).Into(obj); err != nil {
+			t.Fatalf("expected the interaction of %s to be replayed, got %v", apiPath, err)
+		}
+
+		if want := apiPath + "/v1/secrets"; obj.Name != want {
+			t.Errorf("expected the replayed response of %s, got %q", want, obj.Name)
+		}
+	}
+}
+
+func TestRecorderConfigInvalid(t *testing.T) {
+	for _, config := range []*RecorderConfig{
+		{Mode: RecorderModeRecord},
+		{Mode: "rewind", File: "recordings.json"},
+		{Mode: RecorderModeReplay, File: filepath.Join(t.TempDir(), "missing.json")},
+	} {
+		if _, err := newRecorder(config, http.DefaultTransport); err == nil {
+			t.Errorf("expected an error for recorder config %+v", config)
+		}
+	}
+}
@@ -5,18 +5,25 @@
 package rest
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/marmotedu/component-base/pkg/auth"
 	"github.com/marmotedu/component-base/pkg/runtime"
 
 	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
@@ -30,75 +37,219 @@ type Request struct {
 
 	timeout time.Duration
 
+	// retry overrides the client wide MaxRetries and RetryInterval when retrySet is true
+	retrySet      bool
+	maxRetries    int
+	retryInterval time.Duration
+
 	// generic components accessible via method setters
 	verb       string
 	pathPrefix string
-	subpath    string
-	params     url.Values
-	headers    http.Header
+	// basePath is prepended verbatim to pathPrefix when the client preserves its base path.
+	basePath string
+	subpath  string
+	params   url.Values
+	headers  http.Header
+	trailers http.Header
 
 	// structural elements of the request that are part of the IAM API conventions
-	// namespace    string
-	// namespaceSet bool
+	namespace    string
+	namespaceSet bool
 	resource     string
 	resourceName string
 	subresource  string
 
+	// noAuth suppresses the Authorization header, see NoAuth
+	noAuth bool
+	// authMethod forces the credentials of the request when probing them, see Config.ProbeAuth
+	authMethod string
+	// signGroup overrides the group of the audience of the signed token, see SignGroup
+	signGroup string
+
+	// fullURL replaces the base URL of the client when set, see FullURL
+	fullURL *url.URL
+
 	// output
 	err  error
 	body interface{}
+	// bodySize is the size of the encoded body, see BodySize
+	bodySize int64
+	// encodedBody is the body encoded by a body encoder, sent as is
+	encodedBody []byte
 }
 
 // NewRequest creates a new request helper object for accessing runtime.Objects on a server.
 func NewRequest(c *RESTClient) *Request {
-	var pathPrefix string
-	if c.base != nil {
+	var pathPrefix, basePath string
+
+	switch {
+	case c.base != nil && c.content.PreserveBasePath:
+		basePath = c.base.Path
+		pathPrefix = path.Join("/", c.versionedAPIPath)
+	case c.base != nil:
 		pathPrefix = path.Join("/", c.base.Path, c.versionedAPIPath)
-	} else {
+	default:
 		pathPrefix = path.Join("/", c.versionedAPIPath)
 	}
 
 	r := &Request{
 		c:          c,
 		pathPrefix: pathPrefix,
+		basePath:   basePath,
 	}
 
-	authMethod := 0
+	// set accept content
+	switch {
+	case len(c.content.AcceptContentTypes) > 0:
+		r.SetHeader("Accept", c.content.AcceptContentTypes)
+	case len(c.content.ContentType) > 0:
+		r.SetHeader("Accept", c.content.ContentType+", */*")
+	}
 
-	for _, fn := range []func() bool{c.content.HasBasicAuth, c.content.HasTokenAuth, c.content.HasKeyAuth} {
-		if fn() {
-			authMethod++
-		}
+	r.Headers(c.content.Headers)
+
+	if len(c.content.Priority) > 0 {
+		r.Priority(c.content.Priority)
 	}
 
-	if authMethod > 1 {
-		r.err = fmt.Errorf(
-			"username/password or bearer token or secretID/secretKey may be set, but should use only one of them",
-		)
+	// the client-wide namespace is a default, which Namespace may still override
+	r.namespace = c.content.Namespace
 
-		return r
+	return r
+}
+
+// NoAuth makes the request anonymous: no Authorization header is sent, even when the client
+// has credentials configured. Useful for unauthenticated endpoints such as health checks.
+func (r *Request) NoAuth() *Request {
+	r.noAuth = true
+
+	return r
+}
+
+// SignGroup makes the token signed with SecretID/SecretKey target the audience of the given
+// group instead of the group of the client, eg. "iam.authz" for a cross-service call.
+func (r *Request) SignGroup(group string) *Request {
+	r.signGroup = group
+
+	return r
+}
+
+// authorize sets the Authorization header of the agent from the credentials of the client,
+// unless the request is anonymous. It runs for every attempt, so that tokens are always
+// current, and before the request headers are applied, so that they can override it.
+func (r *Request) authorize(ctx context.Context, client *gorequest.SuperAgent) error {
+	if r.noAuth {
+		return nil
 	}
 
-	switch {
-	case c.content.HasTokenAuth():
-		r.SetHeader("Authorization", fmt.Sprintf("Bearer %s", c.content.BearerToken))
-	case c.content.HasKeyAuth():
-		tokenString := auth.Sign(c.content.SecretID, c.content.SecretKey, "marmotedu-sdk-go", c.group+".marmotedu.com")
-		r.SetHeader("Authorization", fmt.Sprintf("Bearer %s", tokenString))
-	case c.content.HasBasicAuth():
+	method := r.authMethod
+	if len(method) == 0 {
+		methods := r.c.content.authMethods()
+		if len(methods) > 1 && !r.c.content.ProbeAuth {
+			return fmt.Errorf(
+				"username/password or bearer token or secretID/secretKey may be set, but should use only one of them",
+			)
+		}
+
+		method = r.c.content.AuthMethod()
+		if len(methods) > 1 {
+			var err error
+			if method, err = r.c.probe.method(ctx, r.c, methods); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch method {
+	case AuthMethodToken:
+		token := r.c.content.BearerToken
+		if r.c.tokenFile != nil {
+			fileToken, err := r.c.tokenFile.Token()
+			if err != nil && len(token) == 0 {
+				return err
+			}
+
+			if err == nil {
+				token = fileToken
+			}
+		}
+
+		if r.c.content.CheckTokenExpiry {
+			if err := checkTokenExpiry(token, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		authorization := fmt.Sprintf("%s %s", r.authScheme(), token)
+		if !validHeaderValue(authorization) {
+			return fmt.Errorf("invalid bearer token or auth scheme: contains control characters")
+		}
+
+		client.Set("Authorization", authorization)
+	case AuthMethodSecret:
+		group := r.c.group
+		if len(r.signGroup) != 0 {
+			group = r.signGroup
+		}
+
+		tokenString, err := signKeyAuth(r.c.content.SecretID, r.c.content.SecretKey, "marmotedu-sdk-go",
+			group+"."+DefaultDomain)
+		if err != nil {
+			return fmt.Errorf("sign token: %w", err)
+		}
+
+		authorization := fmt.Sprintf("%s %s", r.authScheme(), tokenString)
+		if !validHeaderValue(authorization) {
+			return fmt.Errorf("invalid auth scheme: contains control characters")
+		}
+
+		client.Set("Authorization", authorization)
+	case AuthMethodBasic:
 		// TODO: get token and set header
-		r.SetHeader("Authorization", "Basic "+basicAuth(c.content.Username, c.content.Password))
+		client.Set("Authorization", "Basic "+basicAuth(r.c.content.Username, r.c.content.Password))
 	}
 
-	// set accept content
-	switch {
-	case len(c.content.AcceptContentTypes) > 0:
-		r.SetHeader("Accept", c.content.AcceptContentTypes)
-	case len(c.content.ContentType) > 0:
-		r.SetHeader("Accept", c.content.ContentType+", */*")
+	return nil
+}
+
+// validateHeader returns an error when the name or a value of a header contains characters
+// which are not allowed, eg. CR or LF which would inject other headers.
+func validateHeader(key string, values ...string) error {
+	if !validHeaderName(key) {
+		return fmt.Errorf("invalid header name %q", key)
 	}
 
-	return r
+	for _, value := range values {
+		if !validHeaderValue(value) {
+			return fmt.Errorf("invalid value for header %q: contains control characters", key)
+		}
+	}
+
+	return nil
+}
+
+// validHeaderName returns whether the header name is a token, as required by RFC 7230.
+func validHeaderName(name string) bool {
+	return len(name) != 0 && strings.IndexFunc(name, func(c rune) bool {
+		return c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c)
+	}) < 0
+}
+
+// validHeaderValue returns whether the header value is free of control characters other than
+// horizontal tabs, as required by RFC 7230.
+func validHeaderValue(value string) bool {
+	return strings.IndexFunc(value, func(c rune) bool {
+		return (c < ' ' && c != '\t') || c == 0x7f
+	}) < 0
+}
+
+// authScheme returns the scheme of the Authorization header carrying a token.
+func (r *Request) authScheme() string {
+	if len(r.c.content.AuthScheme) != 0 {
+		return r.c.content.AuthScheme
+	}
+
+	return DefaultAuthScheme
 }
 
 func basicAuth(username, password string) string {
@@ -109,10 +260,16 @@ func basicAuth(username, password string) string {
 // NewRequestWithClient creates a Request with an embedded RESTClient for use in test scenarios.
 func NewRequestWithClient(base *url.URL, versionedAPIPath string,
 	content ClientContentConfig, client *gorequest.SuperAgent) *Request {
+	var tokenFile *cachingTokenFile
+	if len(content.BearerTokenFile) != 0 {
+		tokenFile = newCachingTokenFile(content.BearerTokenFile)
+	}
+
 	return NewRequest(&RESTClient{
 		base:             base,
 		versionedAPIPath: versionedAPIPath,
 		content:          content,
+		tokenFile:        tokenFile,
 		Client:           client,
 	})
 }
@@ -120,9 +277,28 @@ func NewRequestWithClient(base *url.URL, versionedAPIPath string,
 // Verb sets the verb this request will use.
 func (r *Request) Verb(verb string) *Request {
 	r.verb = verb
+
+	if r.c.content.ReadOnly && !isReadOnlyVerb(verb) {
+		r.err = fmt.Errorf("%w: %s requests are not allowed", ErrReadOnly, verb)
+	}
+
 	return r
 }
 
+// ErrReadOnly is returned by the requests of a read-only client which would modify objects,
+// see Config.ReadOnly.
+var ErrReadOnly = errors.New("client is read-only")
+
+// isReadOnlyVerb returns whether requests sent with verb don't modify objects.
+func isReadOnlyVerb(verb string) bool {
+	switch strings.ToUpper(verb) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
 // Prefix adds segments to the relative beginning to the request path. These
 // items will be placed before the optional Namespace, Resource, or Name sections.
 // Setting AbsPath will clear any previously set Prefix segments.
@@ -148,6 +324,31 @@ func (r *Request) Suffix(segments ...string) *Request {
 	return r
 }
 
+// Namespace applies the namespace scope to a request (<tenants>/<namespace>/<resource>/<name>),
+// where the scope segment is the NamespaceResource of the client. An empty namespace clears
+// the client-wide default, making the request unscoped. Unlike the client-wide default, which
+// only scopes the requests of a Resource, it applies to any path.
+func (r *Request) Namespace(namespace string) *Request {
+	if r.err != nil {
+		return r
+	}
+
+	if r.namespaceSet {
+		r.err = fmt.Errorf("namespace already set to %q, cannot change to %q", r.namespace, namespace)
+		return r
+	}
+
+	if msgs := IsValidPathSegmentName(namespace); len(namespace) != 0 && len(msgs) != 0 {
+		r.err = fmt.Errorf("invalid namespace %q: %v", namespace, msgs)
+		return r
+	}
+
+	r.namespaceSet = true
+	r.namespace = namespace
+
+	return r
+}
+
 // Resource sets the resource to access (<resource>/[ns/<namespace>/]<name>).
 func (r *Request) Resource(resource string) *Request {
 	if r.err != nil {
@@ -228,9 +429,14 @@ func (r *Request) AbsPath(segments ...string) *Request {
 		return r
 	}
 
-	r.pathPrefix = path.Join(r.c.base.Path, path.Join(segments...))
+	base := r.c.base.Path
+	if len(r.basePath) != 0 {
+		base = "/"
+	}
+
+	r.pathPrefix = path.Join(base, path.Join(segments...))
 
-	if len(segments) == 1 && (len(r.c.base.Path) > 1 || len(segments[0]) > 1) && strings.HasSuffix(segments[0], "/") {
+	if len(segments) == 1 && (len(base) > 1 || len(segments[0]) > 1) && strings.HasSuffix(segments[0], "/") {
 		// preserve any trailing slashes for legacy behavior
 		r.pathPrefix += "/"
 	}
@@ -252,6 +458,7 @@ func (r *Request) RequestURI(uri string) *Request {
 	}
 
 	r.pathPrefix = locator.Path
+	r.basePath = ""
 
 	if len(locator.Query()) > 0 {
 		if r.params == nil {
@@ -266,6 +473,38 @@ func (r *Request) RequestURI(uri string) *Request {
 	return r
 }
 
+// FullURL makes the request target the given absolute URL instead of the base URL of the
+// client, eg. to follow a Location header or to send a pre-signed URL to another host.
+// Like RequestURI, it overwrites the existing path. The path and query of the URL are sent
+// verbatim, so that a signature computed over them still holds: the namespace of the client is
+// not applied, and the parameters of the request are appended to the query. The request is
+// still authenticated with the credentials of the client unless NoAuth is set as well.
+func (r *Request) FullURL(u *url.URL) *Request {
+	if r.err != nil {
+		return r
+	}
+
+	if u == nil {
+		r.err = fmt.Errorf("full URL is required")
+
+		return r
+	}
+
+	if !u.IsAbs() || len(u.Host) == 0 {
+		r.err = fmt.Errorf("full URL %q must be absolute", u.String())
+
+		return r
+	}
+
+	fullURL := *u
+	fullURL.Fragment = ""
+	r.fullURL = &fullURL
+	r.pathPrefix = u.Path
+	r.basePath = ""
+
+	return r
+}
+
 // Param creates a query parameter with the given string value.
 func (r *Request) Param(paramName, s string) *Request {
 	if r.err != nil {
@@ -285,11 +524,73 @@ func (r *Request) VersionedParams(v interface{}) *Request {
 		return r
 	}
 
-	r.c.Client.Query(v)
+	params, err := queryParams(v)
+	if err != nil {
+		r.err = err
+
+		return r
+	}
+
+	for key, values := range params {
+		for _, value := range values {
+			r.setParam(key, value)
+		}
+	}
 
 	return r
 }
 
+// queryParams converts an options object into query parameters named after its JSON fields.
+// The TypeMeta fields only describe the options object itself, so they are not sent.
+func queryParams(v interface{}) (url.Values, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("unable to convert %T to query parameters: %w", v, err)
+	}
+
+	delete(fields, "kind")
+	delete(fields, "apiVersion")
+
+	params := url.Values{}
+
+	for key, field := range fields {
+		values, ok := field.([]interface{})
+		if !ok {
+			values = []interface{}{field}
+		}
+
+		for _, value := range values {
+			switch value := value.(type) {
+			case nil:
+			case string:
+				params.Add(key, value)
+			case json.Number:
+				params.Add(key, value.String())
+			case bool:
+				params.Add(key, strconv.FormatBool(value))
+			default:
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+
+				params.Add(key, string(encoded))
+			}
+		}
+	}
+
+	return params, nil
+}
+
 func (r *Request) setParam(paramName, value string) *Request {
 	if r.params == nil {
 		r.params = make(url.Values)
@@ -300,8 +601,25 @@ func (r *Request) setParam(paramName, value string) *Request {
 	return r
 }
 
+// Priority sets the priority level of the request, so that a server which supports priority
+// and fairness can keep low priority traffic from starving the rest.
+func (r *Request) Priority(level string) *Request {
+	header := r.c.content.PriorityHeader
+	if len(header) == 0 {
+		header = DefaultPriorityHeader
+	}
+
+	return r.SetHeader(header, level)
+}
+
 // SetHeader set header for a http request.
 func (r *Request) SetHeader(key string, values ...string) *Request {
+	if err := validateHeader(key, values...); err != nil {
+		r.err = err
+
+		return r
+	}
+
 	if r.headers == nil {
 		r.headers = http.Header{}
 	}
@@ -315,6 +633,36 @@ func (r *Request) SetHeader(key string, values ...string) *Request {
 	return r
 }
 
+// Headers sets the given headers of the request, eg. baggage propagated from an incoming
+// request. A header replaces the values previously set for the same name, or removes them when
+// it has no values, other headers are kept.
+func (r *Request) Headers(headers http.Header) *Request {
+	for key, values := range headers {
+		r.SetHeader(key, values...)
+	}
+
+	return r
+}
+
+// Trailer sets a trailer of the request, sent after the body, eg. a checksum of a streamed upload.
+// Trailers require chunked transfer encoding, so the request is sent without Content-Length,
+// and a body: Do fails for a request with trailers but no body.
+func (r *Request) Trailer(key, value string) *Request {
+	if err := validateHeader(key, value); err != nil {
+		r.err = err
+
+		return r
+	}
+
+	if r.trailers == nil {
+		r.trailers = http.Header{}
+	}
+
+	r.trailers.Set(key, value)
+
+	return r
+}
+
 // Timeout makes the request use the given duration as an overall timeout for the
 // request. Additionally, if set passes the value as "timeout" parameter in URL.
 func (r *Request) Timeout(d time.Duration) *Request {
@@ -327,9 +675,29 @@ func (r *Request) Timeout(d time.Duration) *Request {
 	return r
 }
 
+// Retry overrides the client wide MaxRetries and RetryInterval for this request only.
+// A max of zero disables retries for this request, even if the client retries by default.
+func (r *Request) Retry(max int, interval time.Duration) *Request {
+	if r.err != nil {
+		return r
+	}
+
+	r.retrySet = true
+	r.maxRetries = max
+	r.retryInterval = interval
+
+	return r
+}
+
 // URL returns the current working URL.
 func (r *Request) URL() *url.URL {
+	// the namespace of the client only scopes the resources, not the paths set with AbsPath or
+	// RequestURI, eg. /healthz
 	p := r.pathPrefix
+	if len(r.namespace) != 0 && r.fullURL == nil && (r.namespaceSet || len(r.resource) != 0) {
+		p = path.Join(p, r.namespaceResource(), r.namespace)
+	}
+
 	if len(r.resource) != 0 {
 		p = path.Join(p, strings.ToLower(r.resource))
 	}
@@ -340,11 +708,21 @@ func (r *Request) URL() *url.URL {
 	}
 
 	finalURL := &url.URL{}
-	if r.c.base != nil {
+
+	switch {
+	case r.fullURL != nil:
+		*finalURL = *r.fullURL
+		if p != r.fullURL.Path {
+			finalURL.RawPath = ""
+		}
+	case r.c.base != nil:
 		*finalURL = *r.c.base
 	}
 
 	finalURL.Path = p
+	if len(r.basePath) != 0 {
+		finalURL.Path = strings.TrimSuffix(r.basePath, "/") + p
+	}
 
 	query := url.Values{}
 
@@ -356,29 +734,113 @@ func (r *Request) URL() *url.URL {
 
 	// timeout is handled specially here.
 	if r.timeout != 0 {
-		query.Set("timeout", r.timeout.String())
+		query.Set("timeout", r.timeoutParam())
 	}
 
 	finalURL.RawQuery = query.Encode()
 
+	// the query of a full URL is kept as is, eg. for the parameters to stay in the signed order
+	if r.fullURL != nil && len(r.fullURL.RawQuery) != 0 {
+		if len(finalURL.RawQuery) == 0 {
+			finalURL.RawQuery = r.fullURL.RawQuery
+		} else {
+			finalURL.RawQuery = r.fullURL.RawQuery + "&" + finalURL.RawQuery
+		}
+	}
+
 	return finalURL
 }
 
-// Body makes the request use obj as the body. Optional.
+// timeoutParam renders the timeout of the request in the TimeoutParamFormat of the client.
+// Seconds are rounded up, so that a sub-second timeout is not sent as no timeout.
+func (r *Request) timeoutParam() string {
+	if r.c.content.TimeoutParamFormat == TimeoutParamFormatSeconds {
+		return strconv.FormatInt(int64((r.timeout+time.Second-1)/time.Second), 10)
+	}
+
+	return r.timeout.String()
+}
+
+// namespaceResource returns the path segment introducing the namespace of a request.
+func (r *Request) namespaceResource() string {
+	if len(r.c.content.NamespaceResource) != 0 {
+		return r.c.content.NamespaceResource
+	}
+
+	return DefaultNamespaceResource
+}
+
+// Body makes the request use obj as the body. A []byte is sent as is, eg. a patch, other objects
+// are encoded. The Content-Type header of the client is used unless the request has one.
+// Optional.
 func (r *Request) Body(obj interface{}) *Request {
-	if v := reflect.ValueOf(obj); v.Kind() == reflect.Struct {
-		r.SetHeader("Content-Type", r.c.content.ContentType)
+	if len(r.headers.Get("Content-Type")) == 0 {
+		if _, raw := obj.([]byte); raw && len(r.c.content.ContentType) != 0 {
+			r.SetHeader("Content-Type", r.c.content.ContentType)
+		} else if v := reflect.Indirect(reflect.ValueOf(obj)); v.Kind() == reflect.Struct {
+			r.SetHeader("Content-Type", r.c.content.ContentType)
+		}
 	}
 
 	r.body = obj
+	r.encodedBody = nil
+
+	switch body := obj.(type) {
+	case nil:
+		r.bodySize = 0
+	case string:
+		r.bodySize = int64(len(body))
+	case []byte:
+		r.encodedBody = body
+		r.bodySize = int64(len(body))
+	default:
+		data, err := r.encodeBody(obj)
+		if err != nil {
+			r.err = fmt.Errorf("encode request body: %w", err)
+
+			return r
+		}
+
+		r.bodySize = int64(len(data))
+	}
 
 	return r
 }
 
+// BodySize returns the size in bytes of the body set with Body once encoded, eg. to log the
+// size of a large import before sending it.
+func (r *Request) BodySize() int64 {
+	return r.bodySize
+}
+
 // Do formats and executes the request. Returns a Result object for easy response processing.
-func (r *Request) Do(ctx context.Context) Result {
-	client := r.c.Client
-	client.Header = r.headers
+func (r *Request) Do(ctx context.Context) (result Result) {
+	if r.err != nil {
+		return Result{err: r.err}
+	}
+
+	// a watch answer is a never ending stream, which Do would buffer forever
+	if watch, _ := strconv.ParseBool(r.params.Get("watch")); watch {
+		return Result{err: fmt.Errorf("watch requests must be sent with Watch, not Do")}
+	}
+
+	if len(r.trailers) != 0 && r.body == nil {
+		return Result{err: fmt.Errorf("trailers can only be sent after a body")}
+	}
+
+	if limit := r.c.content.MaxRequestBytes; limit > 0 && r.bodySize > limit {
+		r.c.logger().Warn("request body exceeds the limit, the request is not sent",
+			"url", r.URL().String(), "size", r.bodySize, "limit", limit)
+
+		return Result{err: fmt.Errorf("request body of %d bytes exceeds the limit of %d bytes", r.bodySize, limit)}
+	}
+
+	if onRequest := r.c.content.OnRequest; onRequest != nil {
+		defer r.observe(ctx, onRequest, time.Now(), &result)
+	}
+
+	ctx, cancel := r.withClientContext(ctx)
+	defer cancel()
 
 	if r.timeout > 0 {
 		var cancel context.CancelFunc
@@ -387,10 +849,87 @@ func (r *Request) Do(ctx context.Context) Result {
 		defer cancel()
 	}
 
-	client.WithContext(ctx)
+	if r.c.inflight != nil {
+		select {
+		case r.c.inflight <- struct{}{}:
+			defer func() { <-r.c.inflight }()
+		case <-ctx.Done():
+			return Result{err: ctx.Err()}
+		}
+	}
+
+	policy := r.retryPolicy()
+
+	var (
+		resp        gorequest.Response
+		body        []byte
+		err         error
+		attempt     int
+		attemptErrs []error
+	)
+
+	for attempt = 1; ; attempt++ {
+		if r.c.throttle != nil {
+			if err = r.c.throttle.wait(ctx); err != nil {
+				attemptErrs = append(attemptErrs, err)
+
+				break
+			}
+		}
+
+		resp, body, err = r.do(ctx)
+		if resp != nil && r.c.throttle != nil {
+			r.c.throttle.observe(resp.Header)
+		}
+
+		if err == nil {
+			break
+		}
+
+		attemptErrs = append(attemptErrs, &attemptError{attempt: attempt, err: err})
+		if attempt > policy.maxRetries || !policy.retryable(resp) {
+			break
+		}
+
+		delay := policy.nextDelay(resp, time.Now())
+
+		// the context would be done before the next attempt, fail with the error of this one
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+
+		r.c.logger().Warn("retrying request", "verb", r.verb, "url", r.URL().String(),
+			"attempt", attempt, "maxRetries", policy.maxRetries, "status", resp.StatusCode)
+
+		if r.c.content.OnRetry != nil {
+			r.c.content.OnRetry(attempt, err, delay)
+		}
+
+		if waitErr := policy.wait(ctx, delay); waitErr != nil {
+			attemptErrs = append(attemptErrs, waitErr)
+
+			break
+		}
+	}
 
-	resp, body, errs := client.CustomMethod(r.verb, r.URL().String()).Send(r.body).EndBytes()
-	if err := combineErr(resp, body, errs); err != nil {
+	if err != nil && len(attemptErrs) > 1 {
+		err = newRetryError(attemptErrs)
+	}
+
+	if resp != nil && r.c.warnings != nil {
+		warnings := parseWarnings(resp.Header)
+		if d := parseDeprecation(resp.Header); d != nil {
+			warnings = append(warnings, d.warning(r.deprecatedResource(), time.Now()))
+		}
+
+		r.c.warnings.report(warnings)
+	}
+
+	if resp != nil && policy.maxRetries > 0 {
+		resp.Header.Set("Retry-Count", strconv.Itoa(attempt-1))
+	}
+
+	if err != nil {
 		return Result{
 			response: &resp,
 			err:      err,
@@ -399,6 +938,40 @@ func (r *Request) Do(ctx context.Context) Result {
 	}
 
 	decoder, err := r.c.content.Negotiator.Decoder()
+	if err == nil && r.c.content.StrictDecoding {
+		decoder = strictDecoder{}
+	}
+
+	contentType := r.c.content.ContentType
+	if err == nil && r.c.content.UseNumber && (len(contentType) == 0 || strings.Contains(contentType, "json")) {
+		decoder = unstructuredDecoder{decoder: decoder}
+	}
+
+	if err == nil && len(r.c.content.ResponseEnvelope) != 0 {
+		decoder = envelopeDecoder{pointer: r.c.content.ResponseEnvelope, decoder: decoder}
+	}
+
+	// the frames of gRPC-Web gateways wrap the whole response, so they are stripped first
+	responseType := contentType
+	if resp != nil && len(resp.Header.Get("Content-Type")) != 0 {
+		responseType = resp.Header.Get("Content-Type")
+	}
+
+	if framed, text := isGRPCWeb(responseType); err == nil && framed {
+		webDecoder := grpcWebDecoder{text: text, decoder: decoder}
+		decoder = webDecoder
+
+		// a trailers-only reply carries its status in the response headers, without any frame,
+		// other replies in the trailer frame of the body
+		if resp != nil {
+			err = grpcWebStatusError(resp.Header)
+		}
+
+		if err == nil {
+			_, err = webDecoder.message(body)
+		}
+	}
+
 	if err != nil {
 		return Result{
 			response: &resp,
@@ -412,7 +985,186 @@ func (r *Request) Do(ctx context.Context) Result {
 		response: &resp,
 		body:     body,
 		decoder:  decoder,
+		meta:     r.c.content.ResponseMeta,
+	}
+}
+
+// observe reports the completed request to onRequest.
+func (r *Request) observe(ctx context.Context, onRequest func(RequestMetric), start time.Time, result *Result) {
+	metric := RequestMetric{
+		Verb:     r.verb,
+		Resource: r.resource,
+		Duration: time.Since(start),
+		Err:      result.err,
+		Labels:   MetricsLabels(ctx),
+	}
+
+	if result.response != nil && *result.response != nil {
+		metric.StatusCode = (*result.response).StatusCode
+	}
+
+	onRequest(metric)
+}
+
+// withClientContext returns a context which is done when either ctx or the parent context of
+// the client is done.
+func (r *Request) withClientContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	parent := r.c.ctx
+	if parent == nil || parent.Done() == nil {
+		return ctx, cancel
+	}
+
+	go func() {
+		select {
+		case <-parent.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// do sends a single attempt of the request.
+func (r *Request) do(ctx context.Context) (gorequest.Response, []byte, error) {
+	// Work on a copy of the shared agent, so request data never leaks into other requests
+	// issued by the same client. Retries are driven by Do, not by the agent itself.
+	client := r.c.Client.Clone()
+	if err := r.authorize(ctx, client); err != nil {
+		return nil, nil, err
+	}
+
+	r.applyHeaders(client)
+	r.applyDeadline(ctx, client)
+	client.Trailer = r.trailers
+	client.Retryable.Enable = false
+
+	if onTrace := r.c.content.OnTrace; onTrace != nil {
+		tracer := newRequestTracer(r.verb, r.resource)
+		ctx = tracer.withClientTrace(ctx)
+
+		defer func() { onTrace(tracer.finish()) }()
+	}
+
+	client.WithContext(ctx)
+
+	key := r.cacheKey()
+
+	var cached *cachedResponse
+	if len(key) != 0 {
+		if cached = r.c.cache.get(key); cached != nil {
+			cached.revalidate(client)
+		}
+	}
+
+	resp, body, errs := r.sendBody(client.CustomMethod(r.verb, r.URL().String())).EndBytes()
+	if len(errs) == 0 {
+		var err error
+		if body, err = decompressBody(resp, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 && len(key) != 0 {
+		switch {
+		case resp.StatusCode == http.StatusNotModified && cached != nil:
+			body = fromCache(resp, cached)
+		case resp.StatusCode == http.StatusOK:
+			r.c.cache.update(key, resp, body)
+		}
+	}
+
+	return resp, body, combineErr(resp, body, errs, r.c.content.StatusReasons)
+}
+
+// applyHeaders merges the request headers onto the base headers of the agent, with the
+// request headers taking precedence.
+func (r *Request) applyHeaders(client *gorequest.SuperAgent) {
+	if client.Header == nil {
+		client.Header = http.Header{}
+	}
+
+	for key, values := range r.headers {
+		client.Header[key] = append([]string(nil), values...)
+	}
+}
+
+// applyDeadline sends the time left before the deadline of ctx, which shrinks with every
+// attempt, in the DeadlineHeader header.
+func (r *Request) applyDeadline(ctx context.Context, client *gorequest.SuperAgent) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	remaining := time.Until(deadline).Round(time.Millisecond)
+	if remaining <= 0 {
+		return
+	}
+
+	header := r.c.content.DeadlineHeader
+	if len(header) == 0 {
+		header = DefaultDeadlineHeader
 	}
+
+	client.Header.Set(header, remaining.String())
+}
+
+// decompressBody transparently inflates a gzip or deflate encoded response body. Some servers
+// and proxies compress responses even when the client did not ask for it, in which case the
+// transport hands back the still-compressed bytes.
+func decompressBody(resp gorequest.Response, body []byte) ([]byte, error) {
+	if resp == nil || len(body) == 0 {
+		return body, nil
+	}
+
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// RFC 7230 defines deflate as zlib wrapped data, but some servers send raw deflate.
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response body: %w", err)
+	}
+	defer reader.Close()
+
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response body: %w", err)
+	}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = int64(len(decompressed))
+	resp.Body = ioutil.NopCloser(bytes.NewReader(decompressed))
+
+	return decompressed, nil
+}
+
+// strictDecoder decodes JSON and reports fields which are unknown to the target object.
+type strictDecoder struct{}
+
+// Decode implements runtime.Decoder.
+func (strictDecoder) Decode(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
 }
 
 // Result contains the result of calling Request.Do().
@@ -421,36 +1173,160 @@ type Result struct {
 	err      error
 	body     []byte
 	decoder  runtime.Decoder
+	// meta is the JSON pointer to the metadata of the response body.
+	meta string
 }
 
-// Raw returns the raw result.
+// Raw returns the raw result. A gzip or deflate encoded response body is returned decompressed.
 func (r Result) Raw() ([]byte, error) {
 	return r.body, r.err
 }
 
+// Warnings returns the texts of the Warning headers of the response, eg. deprecation notices.
+func (r Result) Warnings() []string {
+	if r.response == nil || *r.response == nil {
+		return nil
+	}
+
+	return parseWarnings((*r.response).Header)
+}
+
+// Deprecation returns the deprecation announced by the Deprecation and Sunset headers of the
+// response, or nil if the resource is not deprecated.
+func (r Result) Deprecation() *Deprecation {
+	if r.response == nil || *r.response == nil {
+		return nil
+	}
+
+	return parseDeprecation((*r.response).Header)
+}
+
+// Meta returns the metadata found at ContentConfig.ResponseMeta in the response body, eg. the
+// "meta" member of a JSON:API document, or nil if the client has no ResponseMeta or the response
+// has no metadata.
+func (r Result) Meta() json.RawMessage {
+	if len(r.meta) == 0 || r.err != nil {
+		return nil
+	}
+
+	meta, err := resolvePointer(r.body, r.meta)
+	if err != nil {
+		return nil
+	}
+
+	return meta
+}
+
+// StatusCode returns the status code of the response, eg. to tell apart 200 OK from 201 Created,
+// or 0 if no response was received.
+func (r Result) StatusCode() int {
+	if r.response == nil || *r.response == nil {
+		return 0
+	}
+
+	return (*r.response).StatusCode
+}
+
+// Header returns the headers of the response, eg. X-Total-Count for a paginated list, or nil if
+// no response was received. They are shared with the Result and must be treated as read-only.
+func (r Result) Header() http.Header {
+	if r.response == nil || *r.response == nil {
+		return nil
+	}
+
+	return (*r.response).Header
+}
+
+// RawResponse returns the HTTP response the result was built from, for what the helpers don't
+// cover, eg. the cookies or the TLS connection state. It returns nil when no response was
+// received. The response body has already been read and closed by the time the Result is
+// returned, so the Body of the returned copy is always empty: use Raw to read the body.
+// Its headers are shared with the Result and must be treated as read-only.
+func (r Result) RawResponse() *http.Response {
+	if r.response == nil || *r.response == nil {
+		return nil
+	}
+
+	resp := *(*r.response)
+	resp.Body = http.NoBody
+
+	return &resp
+}
+
+// Allow returns the methods listed in the Allow header of the response, as returned by
+// the server to an OPTIONS request.
+func (r Result) Allow() []string {
+	if r.response == nil || *r.response == nil {
+		return nil
+	}
+
+	var methods []string
+
+	for _, value := range (*r.response).Header.Values("Allow") {
+		for _, method := range strings.Split(value, ",") {
+			if method = strings.TrimSpace(method); len(method) != 0 {
+				methods = append(methods, method)
+			}
+		}
+	}
+
+	return methods
+}
+
 // Into stores the result into obj, if possible. If obj is nil it is ignored.
+// obj is passed to the decoder as is, so it must be a pointer to the target object.
+// Numbers decoded into an untyped target, eg. a map[string]interface{}, are json.Number when
+// Config.UseNumber is set.
+// An empty successful response leaves obj untouched, unless it answers a GET request with 200 OK
+// in which case a body was expected.
 func (r Result) Into(v interface{}) error {
 	if r.err != nil {
 		return r.Error()
 	}
 
+	if v == nil {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(r.body)) == 0 {
+		if r.bodyExpected() {
+			return fmt.Errorf("empty response body")
+		}
+
+		return nil
+	}
+
 	if r.decoder == nil {
 		return fmt.Errorf("serializer doesn't exist")
 	}
 
-	if err := r.decoder.Decode(r.body, &v); err != nil {
+	if err := r.decoder.Decode(r.body, v); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// bodyExpected returns whether the response should carry a body: only a 200 OK answering a GET
+// request is expected to, other successful responses like 204 No Content may be empty.
+func (r Result) bodyExpected() bool {
+	if r.response == nil || *r.response == nil {
+		return false
+	}
+
+	resp := *r.response
+
+	return resp.StatusCode == http.StatusOK && resp.Request != nil && resp.Request.Method == http.MethodGet
+}
+
 // Error implements the error interface.
 func (r Result) Error() error {
 	return r.err
 }
 
-func combineErr(resp gorequest.Response, body []byte, errs []error) error {
+// combineErr returns the error of a response, or of its attempt when errs is not empty. The
+// reasons of the status codes are looked up in reasons, see Config.StatusReasons.
+func combineErr(resp gorequest.Response, body []byte, errs []error, reasons map[int]StatusReason) error {
 	var e, sep string
 
 	if len(errs) > 0 {
@@ -462,8 +1338,12 @@ func combineErr(resp gorequest.Response, body []byte, errs []error) error {
 		return errors.New(e)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(string(body))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		if validationErr := newValidationError(resp, body, reasons); validationErr != nil {
+			return validationErr
+		}
+
+		return newStatusError(resp, body, reasons)
 	}
 
 	return nil
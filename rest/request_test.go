@@ -0,0 +1,2270 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/component-base/pkg/runtime"
+	"github.com/marmotedu/component-base/pkg/scheme"
+	utilerrors "github.com/marmotedu/errors"
+
+	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
+)
+
+func testRESTClient(t *testing.T, srv *httptest.Server, modify ...func(*Config)) *RESTClient {
+	t.Helper()
+
+	config := &Config{
+		Host: srv.URL,
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+	}
+	for _, fn := range modify {
+		fn(config)
+	}
+
+	client, err := RESTClientFor(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return client
+}
+
+type testObject struct {
+	Name string `json:"name"`
+}
+
+func TestDoDecompressesBody(t *testing.T) {
+	payload := []byte(`{"name":"sdk"}`)
+
+	tests := []struct {
+		encoding string
+		compress func([]byte) []byte
+	}{
+		{"gzip", func(data []byte) []byte {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			_, _ = w.Write(data)
+			_ = w.Close()
+
+			return buf.Bytes()
+		}},
+		{"deflate", func(data []byte) []byte {
+			var buf bytes.Buffer
+			w := zlib.NewWriter(&buf)
+			_, _ = w.Write(data)
+			_ = w.Close()
+
+			return buf.Bytes()
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.encoding, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", tc.encoding)
+				_, _ = w.Write(tc.compress(payload))
+			}))
+			defer srv.Close()
+
+			// An explicit Accept-Encoding stops net/http from transparently handling gzip itself.
+			result := testRESTClient(t, srv).Get().
+				Resource("users").
+				Name("sdk").
+				SetHeader("Accept-Encoding", "identity").
+				Do(context.TODO())
+
+			raw, err := result.Raw()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(raw, payload) {
+				t.Errorf("expected raw body %q, got %q", payload, raw)
+			}
+
+			obj := &testObject{}
+			if err := result.Into(obj); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if obj.Name != "sdk" {
+				t.Errorf("expected name %q, got %q", "sdk", obj.Name)
+			}
+		})
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"sdk","nmae":"typo"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		strict  bool
+		wantErr bool
+	}{
+		{strict: false, wantErr: false},
+		{strict: true, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		client := testRESTClient(t, srv, func(c *Config) {
+			c.StrictDecoding = tc.strict
+		})
+
+		obj := &testObject{}
+		err := client.Get().Resource("users").Name("sdk").Do(context.TODO()).Into(obj)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("strict=%v: expected error %v, got %v", tc.strict, tc.wantErr, err)
+		}
+
+		if !tc.wantErr && obj.Name != "sdk" {
+			t.Errorf("strict=%v: expected name %q, got %q", tc.strict, "sdk", obj.Name)
+		}
+	}
+}
+
+func TestRequestRetryOverride(t *testing.T) {
+	tests := []struct {
+		name          string
+		clientRetries int
+		retry         func(*Request) *Request
+		wantErr       bool
+		wantHits      int
+	}{
+		{
+			name:          "client default",
+			clientRetries: 3,
+			retry:         func(r *Request) *Request { return r },
+			wantHits:      3,
+		},
+		{
+			name:          "zero disables client retries",
+			clientRetries: 3,
+			retry:         func(r *Request) *Request { return r.Retry(0, 0) },
+			wantErr:       true,
+			wantHits:      1,
+		},
+		{
+			name:          "more retries than client default",
+			clientRetries: 0,
+			retry:         func(r *Request) *Request { return r.Retry(5, time.Millisecond) },
+			wantHits:      3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var hits int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				// fail the first two attempts
+				if atomic.AddInt32(&hits, 1) <= 2 {
+					w.WriteHeader(http.StatusInternalServerError)
+
+					return
+				}
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.MaxRetries = tc.clientRetries
+				c.RetryInterval = time.Millisecond
+			})
+
+			err := tc.retry(client.Get().Resource("users")).Do(context.TODO()).Error()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if got := int(atomic.LoadInt32(&hits)); got != tc.wantHits {
+				t.Errorf("expected %d attempts, got %d", tc.wantHits, got)
+			}
+		})
+	}
+}
+
+func TestRetryErrorsAggregateAttempts(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "failure %d", atomic.AddInt32(&hits, 1))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.MaxRetries = 2
+		c.RetryInterval = time.Millisecond
+	})
+
+	err := client.Get().Resource("users").Do(context.TODO()).Error()
+
+	var agg utilerrors.Aggregate
+	if !errors.As(err, &agg) {
+		t.Fatalf("expected an aggregate error, got %v", err)
+	}
+
+	if len(agg.Errors()) != 3 {
+		t.Fatalf("expected 3 attempt errors, got %d: %v", len(agg.Errors()), err)
+	}
+
+	for i, attemptErr := range agg.Errors() {
+		if want := fmt.Sprintf("attempt %d: failure %d", i+1, i+1); attemptErr.Error() != want {
+			t.Errorf("expected %q, got %q", want, attemptErr.Error())
+		}
+	}
+
+	if last := errors.Unwrap(err); last == nil || !strings.HasSuffix(last.Error(), "failure 3") {
+		t.Errorf("expected the error to unwrap to the last attempt, got %v", last)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodOptions {
+			t.Errorf("expected method %s, got %s", http.MethodOptions, req.Method)
+		}
+		w.Header().Set("Allow", "GET, POST,OPTIONS")
+	}))
+	defer srv.Close()
+
+	result := testRESTClient(t, srv).Options().Resource("users").Do(context.TODO())
+	if err := result.Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if allow := result.Allow(); fmt.Sprint(allow) != "[GET POST OPTIONS]" {
+		t.Errorf("expected allowed methods [GET POST OPTIONS], got %v", allow)
+	}
+}
+
+func TestRequestHeadersMergeWithClientHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "token"
+	})
+	client.Client.Set("X-Base", "base")
+	client.Client.Set("X-Overridden", "base")
+
+	err := client.Get().
+		Resource("users").
+		SetHeader("X-Caller", "caller").
+		SetHeader("X-Overridden", "caller").
+		Do(context.TODO()).
+		Error()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]string{
+		"Authorization": "Bearer token",
+		"X-Base":        "base",
+		"X-Caller":      "caller",
+		"X-Overridden":  "caller",
+	} {
+		if values := got.Values(key); len(values) != 1 || values[0] != want {
+			t.Errorf("expected header %s to be [%s], got %v", key, want, values)
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	defaults := http.Header{
+		"Baggage":      {"tenant=marmotedu"},
+		"X-Region":     {"cn-north"},
+		"X-Overridden": {"default"},
+		"X-Removed":    {"default"},
+	}
+	client := testRESTClient(t, srv, func(c *Config) { c.Headers = defaults })
+
+	err := client.Get().
+		Resource("users").
+		Headers(http.Header{
+			"Baggage":      {"tenant=marmotedu", "user=colin"},
+			"X-Overridden": {"request"},
+			"X-Removed":    nil,
+		}).
+		Headers(http.Header{"X-Request-Id": {"1"}}).
+		Do(context.TODO()).
+		Error()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, want := range map[string][]string{
+		"Baggage":      {"tenant=marmotedu", "user=colin"},
+		"X-Region":     {"cn-north"},
+		"X-Overridden": {"request"},
+		"X-Request-Id": {"1"},
+		"X-Removed":    nil,
+	} {
+		if values := got.Values(key); !reflect.DeepEqual(values, want) {
+			t.Errorf("expected header %s to be %v, got %v", key, want, values)
+		}
+	}
+
+	// the defaults are not modified by the requests
+	if values := defaults.Values("X-Overridden"); len(values) != 1 || values[0] != "default" {
+		t.Errorf("expected the default headers to be kept, got %v", defaults)
+	}
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if values := got.Values("X-Overridden"); len(values) != 1 || values[0] != "default" {
+		t.Errorf("expected the default header on the next request, got %v", values)
+	}
+
+	config := &Config{
+		Host:    srv.URL,
+		Headers: http.Header{"X-Region": {"cn-north\r\nX-Admin: true"}},
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+	}
+	if _, err := RESTClientFor(config); err == nil || !strings.Contains(err.Error(), "invalid value for header") {
+		t.Errorf("expected an invalid default header error, got %v", err)
+	}
+}
+
+func TestVersionedParams(t *testing.T) {
+	// getOptions mirrors the GetOptions of the typed clients, which extend metav1.GetOptions.
+	type getOptions struct {
+		metav1.GetOptions `json:",inline"`
+		ResourceVersion   string `json:"resourceVersion,omitempty"`
+	}
+
+	limit := int64(10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	tests := []struct {
+		name    string
+		options interface{}
+		want    string
+	}{
+		{"get options", getOptions{
+			GetOptions:      metav1.GetOptions{TypeMeta: metav1.TypeMeta{Kind: "GetOptions"}},
+			ResourceVersion: "123",
+		}, "resourceVersion=123"},
+		{"empty get options", metav1.GetOptions{}, ""},
+		{"list options", metav1.ListOptions{LabelSelector: "app=iam", Limit: &limit}, "labelSelector=app%3Diam&limit=10"},
+		{"create options", metav1.CreateOptions{DryRun: []string{"All"}}, "dryRun=All"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := client.Get().Resource("users").VersionedParams(tc.options)
+			if r.err != nil {
+				t.Fatalf("unexpected error: %v", r.err)
+			}
+
+			if got := r.URL().RawQuery; got != tc.want {
+				t.Errorf("expected query %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRequestPriority(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		level  string
+		header string
+		want   string
+	}{
+		{"no priority", func(c *Config) {}, "", DefaultPriorityHeader, ""},
+		{"client default", func(c *Config) { c.Priority = "batch" }, "", DefaultPriorityHeader, "batch"},
+		{"request override", func(c *Config) { c.Priority = "batch" }, "interactive", DefaultPriorityHeader, "interactive"},
+		{"custom header", func(c *Config) {
+			c.Priority = "batch"
+			c.PriorityHeader = "X-IAM-Priority"
+		}, "", "X-IAM-Priority", "batch"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := testRESTClient(t, srv, tc.modify).Get().Resource("users")
+			if len(tc.level) != 0 {
+				r.Priority(tc.level)
+			}
+
+			if err := r.Do(context.TODO()).Error(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value := got.Get(tc.header); value != tc.want {
+				t.Errorf("expected %s header %q, got %q", tc.header, tc.want, value)
+			}
+		})
+	}
+}
+
+func TestDeadlineHeader(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if value, ok := got[DefaultDeadlineHeader]; ok {
+		t.Errorf("expected no deadline header without a deadline, got %q", value)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Get().Resource("users").Do(ctx).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remaining, err := time.ParseDuration(got.Get(DefaultDeadlineHeader))
+	if err != nil || remaining <= 4*time.Second || remaining > 5*time.Second {
+		t.Errorf("expected the time left before the deadline, got %q", got.Get(DefaultDeadlineHeader))
+	}
+
+	// the shortest of the context deadline and the request timeout is sent, in a custom header
+	client = testRESTClient(t, srv, func(c *Config) { c.DeadlineHeader = "X-IAM-Deadline" })
+	if err := client.Get().Resource("users").Timeout(time.Second).Do(ctx).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remaining, err = time.ParseDuration(got.Get("X-IAM-Deadline"))
+	if err != nil || remaining <= 0 || remaining > time.Second {
+		t.Errorf("expected the time left before the request timeout, got %q", got.Get("X-IAM-Deadline"))
+	}
+}
+
+// recordingLogger records the messages it receives as "level msg key=value ...".
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.record("info", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.record("warn", msg, keysAndValues)
+}
+
+func (l *recordingLogger) record(level, msg string, keysAndValues []interface{}) {
+	message := level + " " + msg
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		message += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+
+	l.messages = append(l.messages, message)
+}
+
+func TestRetryLogsWarning(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	logger := &recordingLogger{}
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.MaxRetries = 2
+		c.RetryInterval = time.Millisecond
+		c.Logger = logger
+	})
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(logger.messages) != 1 {
+		t.Fatalf("expected a single log message, got %q", logger.messages)
+	}
+
+	if msg := logger.messages[0]; !strings.HasPrefix(msg, "warn retrying request") ||
+		!strings.Contains(msg, " attempt=1 ") || !strings.Contains(msg, " status=500") {
+		t.Errorf("expected a retry warning with the attempt count, got %q", msg)
+	}
+}
+
+func TestRetryWithoutLogger(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	// requests built without a client have no Logger
+	base, _ := url.Parse(srv.URL)
+	content := ClientContentConfig{ContentType: "application/json", Negotiator: runtime.NewSimpleClientNegotiator()}
+	agent := gorequest.New().Retry(1, time.Millisecond, http.StatusInternalServerError)
+
+	if err := NewRequestWithClient(base, "/v1", content, agent).Verb("GET").Resource("users").
+		Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hits != 2 {
+		t.Errorf("expected the request to be retried once, got %d attempts", hits)
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	type testObjectList struct {
+		TotalCount int64         `json:"totalCount"`
+		Items      []*testObject `json:"items"`
+	}
+
+	tests := []struct {
+		name     string
+		envelope string
+		body     string
+		wantErr  bool
+	}{
+		{"bare list", "", `{"totalCount":1,"items":[{"name":"colin"}]}`, false},
+		{"enveloped list", "/data", `{"code":0,"data":{"totalCount":1,"items":[{"name":"colin"}]}}`, false},
+		{"nested envelope", "/result/0/a~1b", `{"result":[{"a/b":{"totalCount":1,"items":[{"name":"colin"}]}}]}`, false},
+		{"missing envelope", "/data", `{"totalCount":1,"items":[{"name":"colin"}]}`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.ResponseEnvelope = tc.envelope
+			})
+
+			list := &testObjectList{}
+			err := client.Get().Resource("users").Do(context.TODO()).Into(list)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got list %+v", list)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if list.TotalCount != 1 || len(list.Items) != 1 || list.Items[0].Name != "colin" {
+				t.Errorf("unexpected list %+v", list)
+			}
+		})
+	}
+}
+
+func TestJSONAPIEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		into     func(result Result) ([]*testObject, error)
+		wantMeta string
+	}{
+		{
+			name: "single resource",
+			body: `{"data":{"name":"colin"},"meta":{"requestID":"abc"}}`,
+			into: func(result Result) ([]*testObject, error) {
+				object := &testObject{}
+				err := result.Into(object)
+
+				return []*testObject{object}, err
+			},
+			wantMeta: `{"requestID":"abc"}`,
+		},
+		{
+			name: "collection",
+			body: `{"data":[{"name":"colin"}],"meta":{"totalCount":1}}`,
+			into: func(result Result) ([]*testObject, error) {
+				var objects []*testObject
+				err := result.Into(&objects)
+
+				return objects, err
+			},
+			wantMeta: `{"totalCount":1}`,
+		},
+		{
+			name: "no metadata",
+			body: `{"data":[{"name":"colin"}]}`,
+			into: func(result Result) ([]*testObject, error) {
+				var objects []*testObject
+				err := result.Into(&objects)
+
+				return objects, err
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/vnd.api+json")
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.ResponseEnvelope = JSONAPIEnvelope
+				c.ResponseMeta = JSONAPIMeta
+			})
+
+			result := client.Get().Resource("users").Do(context.TODO())
+
+			objects, err := tc.into(result)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(objects) != 1 || objects[0].Name != "colin" {
+				t.Errorf("unexpected objects %+v", objects)
+			}
+
+			if meta := string(result.Meta()); meta != tc.wantMeta {
+				t.Errorf("expected meta %q, got %q", tc.wantMeta, meta)
+			}
+		})
+	}
+}
+
+// typeSwitchDecoder only decodes into *testObject, like decoders which type-switch on the target.
+type typeSwitchDecoder struct{}
+
+func (typeSwitchDecoder) Decode(data []byte, v interface{}) error {
+	obj, ok := v.(*testObject)
+	if !ok {
+		return fmt.Errorf("unsupported target %T", v)
+	}
+
+	obj.Name = string(data)
+
+	return nil
+}
+
+type typeSwitchNegotiator struct {
+	runtime.ClientNegotiator
+}
+
+func (typeSwitchNegotiator) Decoder() (runtime.Decoder, error) {
+	return typeSwitchDecoder{}, nil
+}
+
+func TestIntoPassesTargetToDecoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`colin`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.Negotiator = typeSwitchNegotiator{runtime.NewSimpleClientNegotiator()}
+	})
+
+	obj := &testObject{}
+	if err := client.Get().Resource("users").Do(context.TODO()).Into(obj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if obj.Name != "colin" {
+		t.Errorf("expected name colin, got %q", obj.Name)
+	}
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Into(nil); err != nil {
+		t.Errorf("expected a nil object to be ignored, got %v", err)
+	}
+}
+
+func TestIntoUnstructuredKeepsInt64Precision(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"metadata":{"id":9007199254740993},"expires":9223372036854775807}`))
+	}))
+	defer srv.Close()
+
+	var lossy map[string]interface{}
+	if err := testRESTClient(t, srv).Get().Resource("secrets").Name("sdk").Do(context.TODO()).Into(&lossy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := lossy["expires"].(float64); !ok {
+		t.Errorf("expected expires to be a float64 without UseNumber, got %T", lossy["expires"])
+	}
+
+	client := testRESTClient(t, srv, func(c *Config) { c.UseNumber = true })
+
+	object := map[string]interface{}{}
+	if err := client.Get().Resource("secrets").Name("sdk").Do(context.TODO()).Into(&object); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id, ok := object["metadata"].(map[string]interface{})["id"].(json.Number)
+	if !ok || id.String() != "9007199254740993" {
+		t.Errorf("expected id 9007199254740993, got %#v", object["metadata"])
+	}
+
+	if expires, err := object["expires"].(json.Number).Int64(); err != nil || expires != math.MaxInt64 {
+		t.Errorf("expected expires %d, got %v (%v)", int64(math.MaxInt64), object["expires"], err)
+	}
+
+	var untyped interface{}
+	if err := client.Get().Resource("secrets").Name("sdk").Do(context.TODO()).Into(&untyped); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := untyped.(map[string]interface{})["expires"].(json.Number); !ok {
+		t.Errorf("expected expires to be a json.Number, got %T", untyped.(map[string]interface{})["expires"])
+	}
+
+	typed := &struct {
+		Expires int64 `json:"expires"`
+	}{}
+	if err := client.Get().Resource("secrets").Name("sdk").Do(context.TODO()).Into(typed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if typed.Expires != math.MaxInt64 {
+		t.Errorf("expected expires %d, got %d", int64(math.MaxInt64), typed.Expires)
+	}
+}
+
+func TestHead(t *testing.T) {
+	var method string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method = req.Method
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "16")
+	}))
+	defer srv.Close()
+
+	result := testRESTClient(t, srv).Head().Resource("users").Name("colin").Do(context.TODO())
+
+	var user testObject
+	if err := result.Into(&user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method != http.MethodHead {
+		t.Errorf("expected a HEAD request, got %s", method)
+	}
+
+	if code := result.StatusCode(); code != http.StatusOK {
+		t.Fatalf("expected a successful response, got %d", code)
+	}
+
+	if length := result.Header().Get("Content-Length"); length != "16" {
+		t.Errorf("expected the Content-Length of the object, got %q", length)
+	}
+
+	if body, _ := result.Raw(); len(body) != 0 {
+		t.Errorf("expected no body, got %q", body)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	var method, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method = req.Method
+		contentType = req.Header.Get("Content-Type")
+		data, _ := ioutil.ReadAll(req.Body)
+		body = string(data)
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		pt   PatchType
+		body interface{}
+		want string
+	}{
+		{JSONPatchType, []byte(`[{"op":"replace","path":"/nickname","value":"colin"}]`),
+			`[{"op":"replace","path":"/nickname","value":"colin"}]`},
+		{MergePatchType, []byte(`{"nickname":"colin","phone":null}`), `{"nickname":"colin","phone":null}`},
+		{MergePatchType, &testObject{Name: "colin"}, `{"name":"colin"}`},
+		{StrategicMergePatchType, []byte(`{"nickname":"colin"}`), `{"nickname":"colin"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.pt), func(t *testing.T) {
+			var user testObject
+			err := testRESTClient(t, srv).Patch(tc.pt).Resource("users").Name("colin").
+				Body(tc.body).Do(context.TODO()).Into(&user)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if method != "PATCH" || contentType != string(tc.pt) {
+				t.Errorf("expected a PATCH request of %s, got %s of %s", tc.pt, method, contentType)
+			}
+
+			if body != tc.want {
+				t.Errorf("expected the patch %s to be sent as is, got %s", tc.want, body)
+			}
+
+			if user.Name != "colin" {
+				t.Errorf("unexpected result %+v", user)
+			}
+		})
+	}
+
+	// requests built without a client keep the patch type over the content type of the client
+	base, _ := url.Parse(srv.URL)
+	content := ClientContentConfig{ContentType: "application/json", Negotiator: runtime.NewSimpleClientNegotiator()}
+	err := NewRequestWithClient(base, "/v1", content, gorequest.New()).Verb("PATCH").
+		SetHeader("Content-Type", string(MergePatchType)).Resource("users").Name("colin").
+		Body(&testObject{Name: "colin"}).Do(context.TODO()).Error()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method != "PATCH" || contentType != string(MergePatchType) || body != `{"name":"colin"}` {
+		t.Errorf("unexpected %s request of %s: %s", method, contentType, body)
+	}
+}
+
+func TestCustomVerb(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := ioutil.ReadAll(req.Body)
+		method, path, body = req.Method, req.URL.Path, string(data)
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	defer srv.Close()
+
+	var client Interface = testRESTClient(t, srv)
+
+	var obj testObject
+	err := client.Verb("REPORT").
+		Resource("users").
+		Name("colin").
+		SubResource("activity").
+		Body(&testObject{Name: "logins"}).
+		Do(context.TODO()).
+		Into(&obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if method != "REPORT" || path != "/v1/users/colin/activity" || body != `{"name":"logins"}` {
+		t.Errorf("unexpected %s request of %s: %s", method, path, body)
+	}
+
+	if obj.Name != "colin" {
+		t.Errorf("expected the response to be decoded, got %+v", obj)
+	}
+
+	readOnly := testRESTClient(t, srv, func(c *Config) { c.ReadOnly = true })
+	if err := readOnly.Verb("REPORT").Resource("users").Do(context.TODO()).Error(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected a custom verb to be refused by a read-only client, got %v", err)
+	}
+}
+
+func TestRawResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "d6a4b1e0"})
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	result := client.Get().Resource("users").Name("colin").Do(context.TODO())
+
+	resp := result.RawResponse()
+	if resp == nil {
+		t.Fatal("expected the raw response")
+	}
+
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "d6a4b1e0" {
+		t.Errorf("expected the session cookie, got %v", cookies)
+	}
+
+	if body, _ := ioutil.ReadAll(resp.Body); len(body) != 0 {
+		t.Errorf("expected the consumed body to be empty, got %q", body)
+	}
+
+	if body, _ := result.Raw(); string(body) != `{"name":"colin"}` {
+		t.Errorf("expected the body to be available from Raw, got %q", body)
+	}
+
+	if resp := (Result{}).RawResponse(); resp != nil {
+		t.Errorf("expected no raw response without a response, got %v", resp)
+	}
+}
+
+func TestResultStatusCodeAndHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			w.Header().Set("Location", "/v1/users/colin")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"name":"colin"}`))
+
+			return
+		}
+
+		w.Header().Set("X-Total-Count", "42")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`user colin already exists`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	result := client.Post().Resource("users").Body(&testObject{Name: "colin"}).Do(context.TODO())
+	if code := result.StatusCode(); code != http.StatusCreated {
+		t.Errorf("expected the status code %d, got %d", http.StatusCreated, code)
+	}
+
+	if location := result.Header().Get("Location"); location != "/v1/users/colin" {
+		t.Errorf("expected the Location header, got %q", location)
+	}
+
+	var obj testObject
+	if err := result.Into(&obj); err != nil || obj.Name != "colin" {
+		t.Errorf("expected the created object to be decoded, got %+v, %v", obj, err)
+	}
+
+	result = client.Get().Resource("users").Do(context.TODO())
+	if code := result.StatusCode(); code != http.StatusConflict {
+		t.Errorf("expected the status code %d, got %d", http.StatusConflict, code)
+	}
+
+	if count := result.Header().Get("X-Total-Count"); count != "42" {
+		t.Errorf("expected the X-Total-Count header, got %q", count)
+	}
+
+	var statusErr *StatusError
+	if err := result.Error(); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		t.Errorf("expected a status error, got %v", err)
+	}
+
+	if code, header := (Result{}).StatusCode(), (Result{}).Header(); code != 0 || header != nil {
+		t.Errorf("expected no status code and headers without a response, got %d, %v", code, header)
+	}
+}
+
+func TestIntoEmptyBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/users/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1/users/empty":
+			w.WriteHeader(http.StatusOK)
+		default:
+			_, _ = w.Write([]byte(`{"name":"colin"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	tests := []struct {
+		name     string
+		req      *Request
+		wantName string
+		wantErr  bool
+	}{
+		{"empty 204", client.Delete().Resource("users").Name("no-content"), "untouched", false},
+		{"empty 200 to DELETE", client.Delete().Resource("users").Name("empty"), "untouched", false},
+		{"empty 200 to GET", client.Get().Resource("users").Name("empty"), "untouched", true},
+		{"non-empty 200", client.Get().Resource("users").Name("colin"), "colin", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &testObject{Name: "untouched"}
+
+			err := tc.req.Do(context.TODO()).Into(obj)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if obj.Name != tc.wantName {
+				t.Errorf("expected name %q, got %q", tc.wantName, obj.Name)
+			}
+		})
+	}
+}
+
+func TestDoRejectsWatch(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	watchOptions := struct {
+		metav1.ListOptions `json:",inline"`
+		Watch              bool `json:"watch,omitempty"`
+	}{Watch: true}
+
+	for _, req := range []*Request{
+		client.Get().Resource("users").Param("watch", "true"),
+		client.Get().Resource("users").VersionedParams(watchOptions),
+	} {
+		err := req.Do(context.TODO()).Error()
+		if err == nil || !strings.Contains(err.Error(), "must be sent with Watch") {
+			t.Errorf("expected an error directing to Watch, got %v", err)
+		}
+	}
+
+	if hits != 0 {
+		t.Errorf("expected no request to be sent, got %d", hits)
+	}
+
+	if err := client.Get().Resource("users").Param("watch", "false").Do(context.TODO()).Error(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClientContextCancelsInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := RESTClientForWithContext(ctx, &Config{
+		Host: srv.URL,
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- client.Get().Resource("users").Do(context.TODO()).Error()
+		}()
+	}
+
+	<-started
+	<-started
+	cancel()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+				t.Errorf("expected a context canceled error, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("in-flight request did not return after the client context was cancelled")
+		}
+	}
+}
+
+func TestNoAuth(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{"bearer token", func(c *Config) { c.BearerToken = "token" }},
+		{"secret key", func(c *Config) {
+			c.SecretID = "id"
+			c.SecretKey = "key"
+		}},
+		{"conflicting credentials", func(c *Config) {
+			c.BearerToken = "token"
+			c.Username = "colin"
+			c.Password = "secret"
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testRESTClient(t, srv, tc.modify)
+
+			if err := client.Get().AbsPath("/healthz").NoAuth().Do(context.TODO()).Error(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if auth, ok := got["Authorization"]; ok {
+				t.Errorf("expected no Authorization header, got %q", auth)
+			}
+		})
+	}
+
+	client := testRESTClient(t, srv, tests[2].modify)
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err == nil {
+		t.Error("expected an error for conflicting credentials on an authenticated request")
+	}
+}
+
+func TestFullURL(t *testing.T) {
+	base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request to the client base %s", req.URL)
+	}))
+	defer base.Close()
+
+	var got *http.Request
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer external.Close()
+
+	client := testRESTClient(t, base, func(c *Config) { c.BearerToken = "token" })
+
+	u, _ := url.Parse(external.URL + "/downloads/report?signature=abc")
+	if err := client.Get().FullURL(u).Param("expires", "60").NoAuth().Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got == nil {
+		t.Fatal("expected a request to the external host")
+	}
+
+	if got.URL.Path != "/downloads/report" || got.URL.Query().Get("signature") != "abc" ||
+		got.URL.Query().Get("expires") != "60" {
+		t.Errorf("unexpected request URL %s", got.URL)
+	}
+
+	if auth := got.Header.Get("Authorization"); auth != "" {
+		t.Errorf("expected no Authorization header, got %q", auth)
+	}
+
+	if err := client.Get().FullURL(u).Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if auth := got.Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("expected the client credentials without NoAuth, got %q", auth)
+	}
+
+	if err := client.Get().FullURL(&url.URL{Path: "/relative"}).Do(context.TODO()).Error(); err == nil {
+		t.Error("expected an error for a relative URL")
+	}
+
+	// a pre-signed URL is sent verbatim, whatever the namespace of the client
+	namespaced := testRESTClient(t, base, func(c *Config) { c.Namespace = "marmotedu" })
+	signed, _ := url.Parse(external.URL + "/downloads/a%2Fb?z=1&a=x%20y&signature=abc")
+
+	if err := namespaced.Get().FullURL(signed).Param("expires", "60").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if uri, want := got.URL.RequestURI(), "/downloads/a%2Fb?z=1&a=x%20y&signature=abc&expires=60"; uri != want {
+		t.Errorf("expected the request URI %q, got %q", want, uri)
+	}
+}
+
+func TestMaxRequestBytes(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) { c.MaxRequestBytes = 64 })
+
+	small := map[string]string{"name": "colin"}
+	req := client.Post().Resource("policies").Body(small)
+
+	if size := req.BodySize(); size != int64(len(`{"name":"colin"}`)) {
+		t.Errorf("expected the encoded body size, got %d", size)
+	}
+
+	if err := req.Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error for a body under the limit: %v", err)
+	}
+
+	large := map[string]string{"name": strings.Repeat("colin", 20)}
+	err := client.Post().Resource("policies").Body(large).Do(context.TODO()).Error()
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 64 bytes") {
+		t.Fatalf("expected a body size error, got %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("expected the large body not to be sent, got %d requests", requests)
+	}
+
+	// requests built without a client have no Logger
+	base, _ := url.Parse(srv.URL)
+	content := ClientContentConfig{
+		ContentType:     "application/json",
+		Negotiator:      runtime.NewSimpleClientNegotiator(),
+		MaxRequestBytes: 64,
+	}
+	err = NewRequestWithClient(base, "/v1", content, gorequest.New()).Verb("POST").Resource("policies").
+		Body(large).Do(context.TODO()).Error()
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 64 bytes") {
+		t.Fatalf("expected a body size error, got %v", err)
+	}
+}
+
+func TestTimeoutParamFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	tests := []struct {
+		format  string
+		timeout time.Duration
+		want    string
+	}{
+		{"", 90 * time.Second, "1m30s"},
+		{TimeoutParamFormatDuration, 90 * time.Second, "1m30s"},
+		{TimeoutParamFormatSeconds, 90 * time.Second, "90"},
+		{TimeoutParamFormatSeconds, 500 * time.Millisecond, "1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format+" "+tc.timeout.String(), func(t *testing.T) {
+			client := testRESTClient(t, srv, func(c *Config) { c.TimeoutParamFormat = tc.format })
+
+			if got := client.Get().Resource("users").Timeout(tc.timeout).URL().Query().Get("timeout"); got != tc.want {
+				t.Errorf("expected timeout param %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	config := &Config{
+		Host:               srv.URL,
+		TimeoutParamFormat: "minutes",
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+	}
+	if _, err := RESTClientFor(config); err == nil {
+		t.Error("expected an error for an unknown timeout param format")
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// trailers are only available once the body is read
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"body":             string(body),
+			"checksum":         req.Trailer.Get("X-Checksum"),
+			"transferEncoding": req.TransferEncoding,
+		})
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+
+	var echo struct {
+		Body             string   `json:"body"`
+		Checksum         string   `json:"checksum"`
+		TransferEncoding []string `json:"transferEncoding"`
+	}
+
+	err := client.Post().Resource("policies").Body(`{"name":"colin"}`).
+		Trailer("X-Checksum", "sha256=8f3c").
+		Do(context.TODO()).
+		Into(&echo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if echo.Body != `{"name":"colin"}` || echo.Checksum != "sha256=8f3c" {
+		t.Errorf("expected the body and the trailer to be echoed, got %+v", echo)
+	}
+
+	if len(echo.TransferEncoding) != 1 || echo.TransferEncoding[0] != "chunked" {
+		t.Errorf("expected a chunked request, got transfer encoding %v", echo.TransferEncoding)
+	}
+
+	if err := client.Get().Resource("policies").Trailer("X-Checksum", "x").Do(context.TODO()).Error(); err == nil {
+		t.Error("expected an error for trailers without a body")
+	}
+}
+
+func TestSignGroup(t *testing.T) {
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.SecretID = "id"
+		c.SecretKey = "key"
+	})
+
+	audience := func() string {
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			t.Fatalf("expected a signed JWT, got %q", token)
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var claims struct {
+			Audience string `json:"aud"`
+		}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return claims.Audience
+	}
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := audience(); got != "iam.api."+DefaultDomain {
+		t.Errorf("expected the audience of the client group, got %q", got)
+	}
+
+	if err := client.Post().Resource("authz").SignGroup("iam.authz").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := audience(); got != "iam.authz."+DefaultDomain {
+		t.Errorf("expected the audience of the overridden group, got %q", got)
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		request func(*RESTClient) *Request
+		wantErr string
+	}{
+		{"header value", nil, func(c *RESTClient) *Request {
+			return c.Get().Resource("users").SetHeader("X-Request-Id", "1\r\nX-Admin: true")
+		}, "invalid value for header"},
+		{"header value with a bare newline", nil, func(c *RESTClient) *Request {
+			return c.Get().Resource("users").SetHeader("X-Request-Id", "1\nX-Admin: true")
+		}, "invalid value for header"},
+		{"header name", nil, func(c *RESTClient) *Request {
+			return c.Get().Resource("users").SetHeader("X-Admin: true\r\nX-Request-Id", "1")
+		}, "invalid header name"},
+		{"trailer value", nil, func(c *RESTClient) *Request {
+			return c.Post().Resource("users").Body(`{}`).Trailer("X-Checksum", "x\r\nX-Admin: true")
+		}, "invalid value for header"},
+		{"bearer token", func(c *Config) { c.BearerToken = "token\r\nX-Admin: true" }, func(c *RESTClient) *Request {
+			return c.Get().Resource("users")
+		}, "invalid bearer token"},
+		{"auth scheme", func(c *Config) {
+			c.SecretID = "id"
+			c.SecretKey = "key"
+			c.AuthScheme = "Bearer\nX-Admin: true\n"
+		}, func(c *RESTClient) *Request {
+			return c.Get().Resource("users")
+		}, "invalid auth scheme"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests = 0
+
+			var modify []func(*Config)
+			if tc.modify != nil {
+				modify = append(modify, tc.modify)
+			}
+
+			client := testRESTClient(t, srv, modify...)
+
+			err := tc.request(client).Do(context.TODO()).Error()
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+			}
+
+			if requests != 0 {
+				t.Errorf("expected the request not to be sent, got %d requests", requests)
+			}
+		})
+	}
+
+	client := testRESTClient(t, srv)
+	if err := client.Get().Resource("users").SetHeader("X-Note", "tab\tseparated").Do(context.TODO()).Error(); err != nil {
+		t.Errorf("unexpected error for a value with a tab: %v", err)
+	}
+}
+
+func TestAuthScheme(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		modify     func(*Config)
+		wantPrefix string
+	}{
+		{"default", func(c *Config) { c.BearerToken = "token" }, "Bearer token"},
+		{"custom token scheme", func(c *Config) {
+			c.BearerToken = "token"
+			c.AuthScheme = "Token"
+		}, "Token token"},
+		{"custom key scheme", func(c *Config) {
+			c.SecretID = "id"
+			c.SecretKey = "key"
+			c.AuthScheme = "IAM"
+		}, "IAM ey"},
+		{"basic auth ignores scheme", func(c *Config) {
+			c.Username = "colin"
+			c.Password = "secret"
+			c.AuthScheme = "Token"
+		}, "Basic "},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testRESTClient(t, srv, tc.modify)
+
+			if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.HasPrefix(got, tc.wantPrefix) {
+				t.Errorf("expected an Authorization header starting with %q, got %q", tc.wantPrefix, got)
+			}
+		})
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// fail the first two attempts
+		if atomic.AddInt32(&hits, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("flaky"))
+
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var calls []string
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.MaxRetries = 3
+		c.RetryInterval = time.Millisecond
+		// the exact delays are asserted below
+		c.RetryJitter = -1
+		c.OnRetry = func(attempt int, err error, nextDelay time.Duration) {
+			calls = append(calls, fmt.Sprintf("%d %v %s", attempt, err, nextDelay))
+		}
+	})
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "[1 flaky 1ms 2 flaky 1ms]"; fmt.Sprint(calls) != want {
+		t.Errorf("expected retry callbacks %s, got %v", want, calls)
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	const interval = 10 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		jitter   float64
+		min, max time.Duration
+	}{
+		{"default", 0, 8 * time.Millisecond, 12 * time.Millisecond},
+		{"half", 0.5, 5 * time.Millisecond, 15 * time.Millisecond},
+		{"disabled", -1, interval, interval},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := testRESTClient(t, srv, func(c *Config) { c.RetryJitter = tc.jitter })
+			policy := client.Get().Retry(3, interval).retryPolicy()
+
+			distinct := map[time.Duration]bool{}
+
+			for i := 0; i < 100; i++ {
+				delay := policy.delay()
+				if delay < tc.min || delay > tc.max {
+					t.Fatalf("expected delays within [%s, %s], got %s", tc.min, tc.max, delay)
+				}
+
+				distinct[delay] = true
+			}
+
+			if tc.min != tc.max && len(distinct) < 2 {
+				t.Errorf("expected successive delays to vary, got %v", distinct)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.MaxRetries = 1
+		c.RetryInterval = 10 * time.Millisecond
+		c.OnRetry = func(attempt int, err error, delay time.Duration) { delays = append(delays, delay) }
+	})
+
+	start := time.Now()
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("expected the client to wait for the Retry-After delay, waited %s", elapsed)
+	}
+
+	if len(delays) != 1 || delays[0] != 2*time.Second {
+		t.Errorf("expected a single retry after 2s, got %v", delays)
+	}
+
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Thu, 01 Oct 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 01 Oct 2020 11:59:30 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tc := range tests {
+		delay, ok := retryAfter(http.Header{"Retry-After": []string{tc.value}}, now)
+		if delay != tc.want || ok != tc.wantOK {
+			t.Errorf("Retry-After %q: expected %s, %t, got %s, %t", tc.value, tc.want, tc.wantOK, delay, ok)
+		}
+	}
+}
+
+func TestRetryAfterCap(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 || req.URL.Path == "/v1/secrets" {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.MaxRetries = 1
+		c.MaxRetryAfter = 10 * time.Millisecond
+		c.OnRetry = func(attempt int, err error, delay time.Duration) { delays = append(delays, delay) }
+	})
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(delays) != 1 || delays[0] != 10*time.Millisecond {
+		t.Errorf("expected a single retry after the capped delay, got %v", delays)
+	}
+
+	// the request is not retried when its context would be done before the next attempt
+	atomic.StoreInt32(&requests, 0)
+	client = testRESTClient(t, srv, func(c *Config) { c.MaxRetries = 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Get().Resource("secrets").Do(ctx).Error()
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the error of the attempt, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 1 || time.Since(start) > time.Second {
+		t.Errorf("expected a single attempt without waiting, got %d attempts in %s", n, time.Since(start))
+	}
+
+	header := http.Header{"Retry-After": []string{"86400"}}
+	for _, tc := range []struct {
+		maxRetryAfter time.Duration
+		want          time.Duration
+	}{
+		{0, time.Minute},
+		{time.Second, time.Second},
+		{-1, 24 * time.Hour},
+	} {
+		maxRetryAfter := tc.maxRetryAfter
+		policy := testRESTClient(t, srv, func(c *Config) { c.MaxRetryAfter = maxRetryAfter }).Get().retryPolicy()
+
+		if delay := policy.nextDelay(&http.Response{Header: header}, time.Now()); delay != tc.want {
+			t.Errorf("MaxRetryAfter %s: expected a delay of %s, got %s", tc.maxRetryAfter, tc.want, delay)
+		}
+	}
+}
+
+func TestRetryableStatusCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCodes  []int
+		status       int
+		wantRequests int32
+	}{
+		{"default 429", nil, http.StatusTooManyRequests, 2},
+		{"default 503", nil, http.StatusServiceUnavailable, 2},
+		{"default 502", nil, http.StatusBadGateway, 1},
+		{"custom 502", []int{http.StatusBadGateway}, http.StatusBadGateway, 2},
+		{"custom without 500", []int{http.StatusBadGateway}, http.StatusInternalServerError, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.MaxRetries = 1
+				c.RetryInterval = time.Millisecond
+				c.RetryableStatusCodes = tc.statusCodes
+			})
+
+			if err := client.Get().Resource("users").Do(context.TODO()).Error(); err == nil {
+				t.Fatalf("expected an error")
+			}
+
+			if requests != tc.wantRequests {
+				t.Errorf("expected %d requests, got %d", tc.wantRequests, requests)
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		modify   func(*Config)
+		budget   string
+		wantHits int32
+	}{
+		{"zero budget", DefaultRetryBudgetHeader, nil, "0", 1},
+		{"remaining budget", DefaultRetryBudgetHeader, nil, "5", 4},
+		{"invalid budget", DefaultRetryBudgetHeader, nil, "unknown", 4},
+		{"custom header", "X-Retries-Left", func(c *Config) { c.RetryBudgetHeader = "X-Retries-Left" }, "0", 1},
+		{"other header ignored", DefaultRetryBudgetHeader, func(c *Config) { c.RetryBudgetHeader = "X-Retries-Left" }, "0", 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var hits int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				w.Header().Set(tc.header, tc.budget)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.MaxRetries = 3
+				c.RetryInterval = time.Millisecond
+				if tc.modify != nil {
+					tc.modify(c)
+				}
+			})
+
+			if err := client.Get().Resource("users").Do(context.TODO()).Error(); err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if got := atomic.LoadInt32(&hits); got != tc.wantHits {
+				t.Errorf("expected %d attempts, got %d", tc.wantHits, got)
+			}
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"code":100101,"message":"Validation failed","errors":[` +
+			`{"field":"email","message":"invalid"},{"field":"nickname","message":"required"}]}`))
+	}))
+	defer srv.Close()
+
+	err := testRESTClient(t, srv).Post().Resource("users").Body(&testObject{}).Do(context.TODO()).Error()
+	if !IsValidationError(err) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	fields := FieldErrors(fmt.Errorf("create user: %w", err))
+	if fmt.Sprint(fields) != "[{email invalid} {nickname required}]" {
+		t.Errorf("unexpected field errors %v", fields)
+	}
+
+	if want := "Validation failed (email: invalid, nickname: required)"; err.Error() != want {
+		t.Errorf("expected message %q, got %q", want, err.Error())
+	}
+
+	if other := errors.New(`{"code":110001,"message":"User not found"}`); IsValidationError(other) || FieldErrors(other) != nil {
+		t.Error("expected other errors not to be validation errors")
+	}
+}
+
+func TestValidationErrorStatus(t *testing.T) {
+	body := `{"code":100101,"message":"Request rejected","errors":[{"field":"name","message":"taken"}]}`
+
+	tests := []struct {
+		verb       string
+		status     int
+		wantReason StatusReason
+		check      func(error) bool
+	}{
+		{http.MethodPost, http.StatusUnprocessableEntity, StatusReasonValidation, IsValidationError},
+		{http.MethodGet, http.StatusNotFound, StatusReasonNotFound, IsNotFound},
+		{http.MethodPut, http.StatusConflict, StatusReasonConflict, IsConflict},
+		{http.MethodPost, http.StatusConflict, StatusReasonAlreadyExists, IsAlreadyExists},
+		{http.MethodGet, http.StatusUnauthorized, StatusReasonUnauthorized, IsUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s %d", tc.verb, tc.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			err := testRESTClient(t, srv).Verb(tc.verb).Resource("users").Name("colin").Do(context.TODO()).Error()
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.StatusCode != tc.status {
+				t.Fatalf("expected a validation error with the status code %d, got %#v", tc.status, err)
+			}
+
+			err = fmt.Errorf("%s user: %w", tc.verb, err)
+			if reason := ReasonForError(err); reason != tc.wantReason || !tc.check(err) {
+				t.Errorf("expected the reason %q, got %q", tc.wantReason, reason)
+			}
+
+			if fields := FieldErrors(err); len(fields) != 1 {
+				t.Errorf("expected the field errors to be kept, got %v", fields)
+			}
+		})
+	}
+}
+
+func TestStatusReasons(t *testing.T) {
+	tests := []struct {
+		name              string
+		verb              string
+		status            int
+		code              int
+		reasons           map[int]StatusReason
+		wantValidation    bool
+		wantNotFound      bool
+		wantConflict      bool
+		wantAlreadyExists bool
+		wantUnauthorized  bool
+	}{
+		{name: "default 400", status: http.StatusBadRequest, wantValidation: true},
+		{name: "default 422", status: http.StatusUnprocessableEntity},
+		{
+			name:           "422 mapped to validation",
+			status:         http.StatusUnprocessableEntity,
+			reasons:        map[int]StatusReason{http.StatusUnprocessableEntity: StatusReasonValidation},
+			wantValidation: true,
+		},
+		{name: "default 404", status: http.StatusNotFound, wantNotFound: true},
+		{
+			name:    "404 default removed",
+			status:  http.StatusNotFound,
+			reasons: map[int]StatusReason{http.StatusNotFound: StatusReasonUnknown},
+		},
+		{name: "default 409", status: http.StatusConflict, wantConflict: true},
+		{
+			name:              "409 on create",
+			verb:              http.MethodPost,
+			status:            http.StatusConflict,
+			wantConflict:      true,
+			wantAlreadyExists: true,
+		},
+		{name: "412 on create", verb: http.MethodPost, status: http.StatusPreconditionFailed, wantConflict: true},
+		{
+			name:              "400 user already exists",
+			verb:              http.MethodPost,
+			status:            http.StatusBadRequest,
+			code:              CodeUserAlreadyExist,
+			wantConflict:      true,
+			wantAlreadyExists: true,
+		},
+		{name: "default 401", status: http.StatusUnauthorized, wantUnauthorized: true},
+		{name: "default 500", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code := tc.code
+			if code == 0 {
+				code = 100101
+			}
+
+			body := fmt.Sprintf(`{"code":%d,"message":"Request rejected"}`, code)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			client := testRESTClient(t, srv, func(c *Config) { c.StatusReasons = tc.reasons })
+
+			verb := tc.verb
+			if len(verb) == 0 {
+				verb = http.MethodGet
+			}
+
+			err := client.Verb(verb).Resource("users").Name("colin").Do(context.TODO()).Error()
+			if err == nil || err.Error() != body {
+				t.Fatalf("expected the response body as error, got %v", err)
+			}
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("expected a status error, got %T", err)
+			}
+
+			if want := (&Status{Code: code, Message: "Request rejected"}); !reflect.DeepEqual(statusErr.Status, want) {
+				t.Errorf("expected the decoded status %+v, got %+v", want, statusErr.Status)
+			}
+
+			err = fmt.Errorf("%s user: %w", verb, err)
+			if IsValidationError(err) != tc.wantValidation || IsNotFound(err) != tc.wantNotFound ||
+				IsConflict(err) != tc.wantConflict || IsAlreadyExists(err) != tc.wantAlreadyExists ||
+				IsUnauthorized(err) != tc.wantUnauthorized {
+				t.Errorf("unexpected reason %q for status %d", ReasonForError(err), tc.status)
+			}
+		})
+	}
+}
+
+func TestRequestNamespace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		request func(*Request) *Request
+		want    string
+	}{
+		{"unscoped", func(c *Config) {}, func(r *Request) *Request { return r }, "/v1/users/colin"},
+		{"request scope", func(c *Config) {}, func(r *Request) *Request { return r.Namespace("marmotedu") },
+			"/v1/tenants/marmotedu/users/colin"},
+		{"client default", func(c *Config) { c.Namespace = "marmotedu" }, func(r *Request) *Request { return r },
+			"/v1/tenants/marmotedu/users/colin"},
+		{"request overrides default", func(c *Config) { c.Namespace = "marmotedu" },
+			func(r *Request) *Request { return r.Namespace("iam") }, "/v1/tenants/iam/users/colin"},
+		{"request clears default", func(c *Config) { c.Namespace = "marmotedu" },
+			func(r *Request) *Request { return r.Namespace("") }, "/v1/users/colin"},
+		{"custom segment", func(c *Config) {
+			c.Namespace = "marmotedu"
+			c.NamespaceResource = "orgs"
+		}, func(r *Request) *Request { return r }, "/v1/orgs/marmotedu/users/colin"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := tc.request(testRESTClient(t, srv, tc.modify).Get()).Resource("users").Name("colin")
+			if r.err != nil {
+				t.Fatalf("unexpected error: %v", r.err)
+			}
+
+			if got := r.URL().Path; got != tc.want {
+				t.Errorf("expected path %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	// the client-wide namespace doesn't scope the paths which are not resources
+	namespaced := testRESTClient(t, srv, func(c *Config) { c.Namespace = "marmotedu" })
+	for _, r := range []*Request{
+		namespaced.Get().AbsPath("/healthz"),
+		namespaced.Get().AbsPath("/apis", "iam.api"),
+		namespaced.Get().RequestURI("/version?verbose=true"),
+	} {
+		if got := r.URL().Path; strings.Contains(got, "marmotedu") {
+			t.Errorf("expected %s not to be scoped to the client namespace", got)
+		}
+	}
+
+	if got := namespaced.Get().AbsPath("/healthz").Namespace("iam").URL().Path; got != "/healthz/tenants/iam" {
+		t.Errorf("expected an explicit namespace to scope any path, got %q", got)
+	}
+
+	client := testRESTClient(t, srv)
+	if r := client.Get().Namespace("a/b"); r.err == nil {
+		t.Error("expected an error for an invalid namespace")
+	}
+
+	if r := client.Get().Namespace("a").Namespace("b"); r.err == nil {
+		t.Error("expected an error when setting the namespace twice")
+	}
+
+	if _, err := RESTClientFor(&Config{
+		Host: srv.URL,
+		ContentConfig: ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+		Namespace: "..",
+	}); err == nil {
+		t.Error("expected an error for an invalid default namespace")
+	}
+}
+
+func TestResponseCacheRevalidatesLists(t *testing.T) {
+	lastModified := "Wed, 21 Oct 2020 07:28:00 GMT"
+	items := `{"totalCount":1,"items":[{"name":"colin"}]}`
+
+	var conditional []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		since := req.Header.Get("If-Modified-Since")
+		conditional = append(conditional, req.URL.RawQuery+" "+since)
+
+		if since == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(items))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.EnableResponseCache = true
+	})
+
+	type testObjectList struct {
+		Items []*testObject `json:"items"`
+	}
+
+	list := func(selector string) (*testObjectList, Result) {
+		result := client.Get().Resource("users").Param("labelSelector", selector).Do(context.TODO())
+
+		list := &testObjectList{}
+		if err := result.Into(list); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return list, result
+	}
+
+	list("app=iam")
+
+	// unchanged list: the server answers 304 and the cached list is returned
+	cachedList, result := list("app=iam")
+	if len(cachedList.Items) != 1 || cachedList.Items[0].Name != "colin" {
+		t.Errorf("expected the cached list, got %+v", cachedList)
+	}
+
+	if (*result.response).Header.Get(FromCacheHeader) != "true" {
+		t.Errorf("expected the response to be served from the cache")
+	}
+
+	// a different selector is cached apart
+	list("app=authz")
+
+	// changed list: the server answers 200 with the new list
+	lastModified = "Thu, 22 Oct 2020 07:28:00 GMT"
+	items = `{"totalCount":1,"items":[{"name":"sdk"}]}`
+
+	changedList, result := list("app=iam")
+	if len(changedList.Items) != 1 || changedList.Items[0].Name != "sdk" {
+		t.Errorf("expected the changed list, got %+v", changedList)
+	}
+
+	if (*result.response).Header.Get(FromCacheHeader) != "" {
+		t.Errorf("expected a fresh response")
+	}
+
+	want := []string{
+		"labelSelector=app%3Diam ",
+		"labelSelector=app%3Diam Wed, 21 Oct 2020 07:28:00 GMT",
+		"labelSelector=app%3Dauthz ",
+		"labelSelector=app%3Diam Wed, 21 Oct 2020 07:28:00 GMT",
+	}
+	if fmt.Sprint(conditional) != fmt.Sprint(want) {
+		t.Errorf("expected requests %q, got %q", want, conditional)
+	}
+}
+
+func TestResponseCacheSize(t *testing.T) {
+	var conditional []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		etag := `"` + req.URL.Path + `"`
+		conditional = append(conditional, req.URL.Path+" "+req.Header.Get("If-None-Match"))
+
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(`{"name":"colin"}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.APIPath = "/"
+		c.EnableResponseCache = true
+		c.ResponseCacheSize = 2
+	})
+
+	for _, name := range []string{"colin", "lingfei", "colin", "sdk", "lingfei", "colin"} {
+		if err := client.Get().Resource("users").Name(name).Do(context.TODO()).Into(&testObject{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the least recently used response is evicted: lingfei when sdk is cached, then colin
+	want := []string{
+		"/v1/users/colin ",
+		"/v1/users/lingfei ",
+		`/v1/users/colin "/v1/users/colin"`,
+		"/v1/users/sdk ",
+		"/v1/users/lingfei ",
+		"/v1/users/colin ",
+	}
+	if fmt.Sprint(conditional) != fmt.Sprint(want) {
+		t.Errorf("expected requests %q, got %q", want, conditional)
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	const limit = 3
+
+	var inflight, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) { c.MaxConcurrentRequests = limit })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Errorf("expected at most %d requests in flight, got %d", limit, peak)
+	}
+
+	// a request waiting for a slot gives up when its context is done
+	client.inflight <- struct{}{}
+	client.inflight <- struct{}{}
+	client.inflight <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := client.Get().Resource("users").Do(ctx).Error(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out waiting for a slot, got %v", err)
+	}
+}
+
+func TestMetricsLabels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var metrics []RequestMetric
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.OnRequest = func(metric RequestMetric) { metrics = append(metrics, metric) }
+	})
+
+	ctx := WithMetricsLabels(context.TODO(), map[string]string{"operation": "reconcile-loop", "shard": "1"})
+	ctx = WithMetricsLabels(ctx, map[string]string{"shard": "2"})
+
+	MetricsLabels(ctx)["shard"] = "3"
+
+	if err := client.Get().Resource("users").Do(ctx).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.Get().Resource("secrets").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+
+	labeled := metrics[0]
+	if labeled.Verb != "GET" || labeled.Resource != "users" || labeled.StatusCode != http.StatusOK {
+		t.Errorf("unexpected metric %+v", labeled)
+	}
+
+	want := map[string]string{"operation": "reconcile-loop", "shard": "2"}
+	if !reflect.DeepEqual(labeled.Labels, want) {
+		t.Errorf("expected labels %v, got %v", want, labeled.Labels)
+	}
+
+	labeled.Labels["operation"] = "other"
+	if !reflect.DeepEqual(MetricsLabels(ctx), want) {
+		t.Errorf("expected the labels of ctx to be left unchanged, got %v", MetricsLabels(ctx))
+	}
+
+	if metrics[1].Resource != "secrets" || metrics[1].Labels != nil {
+		t.Errorf("expected an unlabeled metric, got %+v", metrics[1])
+	}
+}
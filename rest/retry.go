@@ -0,0 +1,190 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	utilerrors "github.com/marmotedu/errors"
+
+	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
+)
+
+// retryPolicy decides whether and when a failed request attempt is retried.
+type retryPolicy struct {
+	maxRetries    int
+	interval      time.Duration
+	jitter        float64
+	statuses      []int
+	budgetHeader  string
+	maxRetryAfter time.Duration
+}
+
+// retryPolicy returns the retry policy of the request, which is the client wide policy unless
+// it was overridden by Request.Retry.
+func (r *Request) retryPolicy() retryPolicy {
+	retryable := r.c.Client.Retryable
+
+	policy := retryPolicy{statuses: retryable.RetryableStatus, budgetHeader: r.c.content.RetryBudgetHeader}
+	if len(policy.budgetHeader) == 0 {
+		policy.budgetHeader = DefaultRetryBudgetHeader
+	}
+
+	switch maxRetryAfter := r.c.content.MaxRetryAfter; {
+	case maxRetryAfter == 0:
+		policy.maxRetryAfter = DefaultMaxRetryAfter
+	case maxRetryAfter > 0:
+		policy.maxRetryAfter = maxRetryAfter
+	}
+
+	switch jitter := r.c.content.RetryJitter; {
+	case jitter == 0:
+		policy.jitter = DefaultRetryJitter
+	case jitter > 0:
+		policy.jitter = math.Min(jitter, 1)
+	}
+
+	if retryable.Enable {
+		policy.maxRetries = retryable.RetryerCount
+		policy.interval = retryable.RetryerTime
+	}
+
+	if r.retrySet {
+		policy.maxRetries = r.maxRetries
+		policy.interval = r.retryInterval
+	}
+
+	return policy
+}
+
+// retryable returns whether the response of a failed attempt may be retried. Only responses
+// with one of the configured status codes are retried, transport errors are not, and neither are
+// responses telling the retry budget of the server is exhausted.
+func (p retryPolicy) retryable(resp gorequest.Response) bool {
+	if resp == nil {
+		return false
+	}
+
+	if budget := resp.Header.Get(p.budgetHeader); len(budget) != 0 {
+		if n, err := strconv.Atoi(strings.TrimSpace(budget)); err == nil && n <= 0 {
+			return false
+		}
+	}
+
+	for _, status := range p.statuses {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay returns the retry interval, randomly shortened or lengthened by up to the jitter fraction.
+func (p retryPolicy) delay() time.Duration {
+	if p.jitter <= 0 || p.interval <= 0 {
+		return p.interval
+	}
+
+	//nolint: gosec
+	return time.Duration(float64(p.interval) * (1 + p.jitter*(2*rand.Float64()-1)))
+}
+
+// retryAfter returns the delay before the next attempt the server asks for with the Retry-After
+// header, eg. of a 429 or 503 response, given either as a number of seconds or as an HTTP date,
+// and whether it is set. It replaces the retry interval.
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+
+	return 0, true
+}
+
+// nextDelay returns the delay before retrying the failed attempt of resp: the delay the server
+// asks for with the Retry-After header, capped by maxRetryAfter, or the retry interval.
+func (p retryPolicy) nextDelay(resp gorequest.Response, now time.Time) time.Duration {
+	after, ok := retryAfter(resp.Header, now)
+	if !ok {
+		return p.delay()
+	}
+
+	if p.maxRetryAfter > 0 && after > p.maxRetryAfter {
+		return p.maxRetryAfter
+	}
+
+	return after
+}
+
+// wait sleeps for delay, or returns early with an error when ctx is done.
+func (p retryPolicy) wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// attemptError records the error of a single request attempt.
+type attemptError struct {
+	attempt int
+	err     error
+}
+
+// Error implements the error interface.
+func (e *attemptError) Error() string {
+	return fmt.Sprintf("attempt %d: %v", e.attempt, e.err)
+}
+
+// Unwrap returns the error of the attempt.
+func (e *attemptError) Unwrap() error {
+	return e.err
+}
+
+// retryError aggregates the errors of every attempt of a retried request. It unwraps to the
+// error of the last attempt, so that error inspection keeps working against the final status.
+type retryError struct {
+	utilerrors.Aggregate
+}
+
+func newRetryError(errs []error) error {
+	return &retryError{utilerrors.NewAggregate(errs)}
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *retryError) Unwrap() error {
+	errs := e.Errors()
+
+	return errs[len(errs)-1]
+}
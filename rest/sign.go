@@ -0,0 +1,80 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// signedTokenTTL is how long a token signed with the secret key is valid.
+const signedTokenTTL = time.Minute
+
+// signKeyAuth signs a token for the secretID/secretKey authentication, which is valid for
+// signedTokenTTL. Every token carries a random nonce in its jti claim, along with the time it
+// was issued at in its iat claim, so that servers can reject replayed requests: a server
+// remembers the jti of the tokens it accepted until they expire, and refuses a token whose
+// jti it has already seen.
+func signKeyAuth(secretID, secretKey, iss, aud string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := keyAuthClaims{
+		Audience:  aud,
+		ExpiresAt: now.Add(signedTokenTTL).Unix(),
+		IssuedAt:  now.Unix(),
+		Issuer:    iss,
+		ID:        hex.EncodeToString(nonce),
+		NotBefore: now.Unix(),
+	}
+
+	return signHS256(keyAuthHeader{Algorithm: "HS256", KeyID: secretID, Type: "JWT"}, claims, []byte(secretKey))
+}
+
+// keyAuthHeader is the header of the tokens signed by signKeyAuth.
+type keyAuthHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// keyAuthClaims are the claims of the tokens signed by signKeyAuth.
+type keyAuthClaims struct {
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+	Issuer    string `json:"iss"`
+	ID        string `json:"jti"`
+	NotBefore int64  `json:"nbf"`
+}
+
+// signHS256 returns the JWT (RFC 7519) made of header and claims, signed with HMAC SHA-256.
+func signHS256(header, claims interface{}, key []byte) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyAuthNonce(t *testing.T) {
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tokens = append(tokens, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.SecretID = "id"
+		c.SecretKey = "key"
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	nonces := map[string]bool{}
+
+	for _, token := range tokens {
+		var header keyAuthHeader
+		var claims keyAuthClaims
+		parseHS256(t, token, []byte("key"), &header, &claims)
+
+		if header.Algorithm != "HS256" || header.KeyID != "id" {
+			t.Errorf("expected an HS256 token with the secretID as key ID, got %+v", header)
+		}
+
+		if claims.IssuedAt == 0 || claims.ExpiresAt-claims.IssuedAt != int64(signedTokenTTL/time.Second) {
+			t.Errorf("expected an issued-at claim and a token valid for %s, got %+v", signedTokenTTL, claims)
+		}
+
+		if len(claims.ID) == 0 {
+			t.Fatalf("expected a nonce, got %+v", claims)
+		}
+
+		nonces[claims.ID] = true
+	}
+
+	if len(nonces) != 2 {
+		t.Errorf("expected the requests to carry different nonces, got %v", nonces)
+	}
+}
+
+// parseHS256 verifies the HMAC SHA-256 signature of a JWT and decodes its header and claims.
+func parseHS256(t *testing.T, token string, key []byte, header, claims interface{}) {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT, got %q", token)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		t.Fatalf("expected a valid signature, got %q", token)
+	}
+
+	for i, v := range []interface{}{header, claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The response headers through which the server advertises its rate limit.
+const (
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// Defaults of AdaptiveThrottle.
+const (
+	DefaultThrottleThreshold = 1
+	DefaultThrottleMaxDelay  = time.Minute
+)
+
+// AdaptiveThrottle makes a client slow down before the server rate limits it: once a response
+// tells that no more than Threshold requests remain, the following requests wait for the rate
+// limit to be reset. The reset is either a Unix time or a number of seconds from now.
+type AdaptiveThrottle struct {
+	// Threshold is the number of remaining requests at which the client starts waiting.
+	// Defaults to DefaultThrottleThreshold.
+	Threshold int64
+	// MaxDelay caps the wait for a reset. Defaults to DefaultThrottleMaxDelay.
+	MaxDelay time.Duration
+}
+
+// throttle delays the requests of a client until the rate limit advertised by the server is
+// reset, see AdaptiveThrottle.
+type throttle struct {
+	config AdaptiveThrottle
+	now    func() time.Time
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+func newThrottle(config AdaptiveThrottle) *throttle {
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultThrottleThreshold
+	}
+
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultThrottleMaxDelay
+	}
+
+	return &throttle{config: config, now: time.Now}
+}
+
+// observe records the rate limit advertised by the headers of a response.
+func (t *throttle) observe(header http.Header) {
+	remaining, err := strconv.ParseInt(strings.TrimSpace(header.Get(RateLimitRemainingHeader)), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if remaining > t.config.Threshold {
+		t.until = time.Time{}
+
+		return
+	}
+
+	reset, err := strconv.ParseInt(strings.TrimSpace(header.Get(RateLimitResetHeader)), 10, 64)
+	if err != nil || reset <= 0 {
+		return
+	}
+
+	now := t.now()
+
+	// a Unix time is much larger than any sensible number of seconds
+	until := now.Add(time.Duration(reset) * time.Second)
+	if reset > 1e9 {
+		until = time.Unix(reset, 0)
+	}
+
+	if max := now.Add(t.config.MaxDelay); until.After(max) {
+		until = max
+	}
+
+	t.until = until
+}
+
+// wait returns once the rate limit is reset, or early with an error when ctx is done.
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	delay := t.until.Sub(t.now())
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveThrottle(t *testing.T) {
+	const reset = 200 * time.Millisecond
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		remaining := 3 - n
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(int(remaining)))
+		w.Header().Set(RateLimitResetHeader, "1")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	get := func(client *RESTClient) time.Duration {
+		start := time.Now()
+		if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return time.Since(start)
+	}
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.AdaptiveThrottle = &AdaptiveThrottle{Threshold: 1, MaxDelay: reset}
+	})
+
+	// remaining 2, then 1: the requests are not delayed
+	for i := 0; i < 2; i++ {
+		if d := get(client); d >= reset {
+			t.Fatalf("request %d unexpectedly throttled for %v", i+1, d)
+		}
+	}
+
+	// remaining 1 reached the threshold: the next request waits for the reset, capped by MaxDelay
+	if d := get(client); d < reset-20*time.Millisecond {
+		t.Errorf("expected the request to wait for the rate limit reset, waited %v", d)
+	}
+
+	// a throttled request gives up when its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	before := atomic.LoadInt32(&requests)
+	if err := client.Get().Resource("users").Do(ctx).Error(); err == nil {
+		t.Errorf("expected an error when the context is done while throttled")
+	}
+
+	if after := atomic.LoadInt32(&requests); after != before {
+		t.Errorf("expected no request to be sent, got %d", after-before)
+	}
+
+	// throttling is opt-in
+	atomic.StoreInt32(&requests, 10)
+	plain := testRESTClient(t, srv)
+	for i := 0; i < 3; i++ {
+		if d := get(plain); d >= reset {
+			t.Errorf("request unexpectedly throttled for %v without AdaptiveThrottle", d)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenFileCachePeriod is how long a token read from BearerTokenFile is used before the file
+// is read again.
+const tokenFileCachePeriod = time.Minute
+
+// cachingTokenFile reads a bearer token from a file and re-reads it periodically, so that
+// rotated tokens are picked up without rebuilding the client.
+type cachingTokenFile struct {
+	path   string
+	period time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newCachingTokenFile(path string) *cachingTokenFile {
+	return &cachingTokenFile{
+		path:   path,
+		period: tokenFileCachePeriod,
+		now:    time.Now,
+	}
+}
+
+// Token returns the cached token, re-reading the file once the cache period has elapsed.
+// The last successfully read token keeps being used when the file can not be read.
+func (ts *cachingTokenFile) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := ts.now()
+	if len(ts.token) != 0 && now.Before(ts.expires) {
+		return ts.token, nil
+	}
+
+	if err := ts.load(now); err != nil {
+		if len(ts.token) != 0 {
+			return ts.token, nil
+		}
+
+		return "", err
+	}
+
+	return ts.token, nil
+}
+
+// Refresh re-reads the token file right away, regardless of the cache period. Unlike Token,
+// a failure is reported even when an older token is cached; the older token is kept.
+func (ts *cachingTokenFile) Refresh() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.load(ts.now())
+}
+
+// load reads the token file and caches its content until now plus the cache period.
+func (ts *cachingTokenFile) load(now time.Time) error {
+	data, err := ioutil.ReadFile(ts.path)
+	if err != nil {
+		return fmt.Errorf("failed to read token file %q: %w", ts.path, err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if len(token) == 0 {
+		return fmt.Errorf("read empty token from file %q", ts.path)
+	}
+
+	ts.token = token
+	ts.expires = now.Add(ts.period)
+
+	return nil
+}
+
+// TokenExpiry returns the expiry time carried by the exp claim of a JWT bearer token. The
+// signature is not verified, the expiry is only meant to fail fast on an expired token.
+// A zero time is returned for a JWT without exp claim, an error for a token which is not a JWT.
+func TokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a JWT: expected 3 parts, got %d", len(parts))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("token is not a JWT: decode claims: %w", err)
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("token is not a JWT: decode claims: %w", err)
+	}
+
+	if claims.Exp == nil {
+		return time.Time{}, nil
+	}
+
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid exp claim %q: %w", claims.Exp.String(), err)
+	}
+
+	return time.Unix(int64(exp), 0), nil
+}
+
+// checkTokenExpiry returns an error when the token is a JWT which expired by now. Tokens which
+// are not JWTs, or carry no expiry, are left to the server to judge.
+func checkTokenExpiry(token string, now time.Time) error {
+	expiry, err := TokenExpiry(token)
+	if err != nil || expiry.IsZero() {
+		return nil
+	}
+
+	if !now.Before(expiry) {
+		return fmt.Errorf("bearer token expired at %s", expiry.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBearerTokenFileReload(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.BearerToken = "static"
+		c.BearerTokenFile = tokenFile
+	})
+
+	now := time.Now()
+	client.tokenFile.now = func() time.Time { return now }
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "Bearer first" {
+		t.Errorf("expected token read from file, got %q", got)
+	}
+
+	if err := ioutil.WriteFile(tokenFile, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client.Get().Resource("users").Do(context.TODO())
+	if got != "Bearer first" {
+		t.Errorf("expected cached token before the cache period elapsed, got %q", got)
+	}
+
+	now = now.Add(tokenFileCachePeriod)
+	client.Get().Resource("users").Do(context.TODO())
+	if got != "Bearer second" {
+		t.Errorf("expected reloaded token, got %q", got)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("revoked"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.BearerTokenFile = tokenFile
+	})
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := ioutil.WriteFile(tokenFile, []byte("reissued"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.RefreshToken(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "Bearer reissued" {
+		t.Errorf("expected the refreshed token, got %q", got)
+	}
+
+	if err := os.Remove(tokenFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.RefreshToken(context.TODO()); err == nil {
+		t.Error("expected an error refreshing from a missing token file")
+	}
+
+	client.Get().Resource("users").Do(context.TODO())
+	if got != "Bearer reissued" {
+		t.Errorf("expected the last good token to be kept, got %q", got)
+	}
+}
+
+func testJWT(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	return encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(claims)) + ".signature"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"valid", testJWT(`{"sub":"colin","exp":4102444800}`), time.Unix(4102444800, 0), false},
+		{"expired", testJWT(`{"sub":"colin","exp":1577836800}`), time.Unix(1577836800, 0), false},
+		{"no expiry", testJWT(`{"sub":"colin"}`), time.Time{}, false},
+		{"opaque token", "d6a4b1e0c3f2", time.Time{}, true},
+		{"invalid claims", "a.!!!.c", time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TokenExpiry(tc.token)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if !got.Equal(tc.want) {
+				t.Errorf("expected expiry %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckTokenExpiry(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", testJWT(`{"exp":4102444800}`), false},
+		{"expired", testJWT(`{"exp":1577836800}`), true},
+		{"opaque token", "d6a4b1e0c3f2", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests = 0
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.BearerToken = tc.token
+				c.CheckTokenExpiry = true
+			})
+
+			err := client.Get().Resource("users").Do(context.TODO()).Error()
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "bearer token expired at 2020-01-01") {
+					t.Fatalf("expected an expired token error, got %v", err)
+				}
+
+				if requests != 0 {
+					t.Errorf("expected the request not to be sent, got %d requests", requests)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
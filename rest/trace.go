@@ -0,0 +1,106 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTrace is the timing breakdown of a single attempt of a request sent with Do, see
+// Config.OnTrace. The phases which did not happen, eg. the DNS lookup of an IP address or the
+// connection of a reused connection, are zero.
+type RequestTrace struct {
+	Verb     string
+	Resource string
+	// DNS is the time spent resolving the host name.
+	DNS time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent in the TLS handshake.
+	TLSHandshake time.Duration
+	// TimeToFirstByte is the time from the start of the attempt to the first response byte.
+	TimeToFirstByte time.Duration
+	// Total is the time from the start of the attempt to the end of the response body.
+	Total time.Duration
+	// ConnReused tells whether the request was sent on a previously used connection.
+	ConnReused bool
+}
+
+// requestTracer records the timings of an attempt through the hooks of httptrace.
+type requestTracer struct {
+	mu    sync.Mutex
+	start time.Time
+	trace RequestTrace
+
+	dnsStart, connectStart, tlsStart time.Time
+}
+
+func newRequestTracer(verb, resource string) *requestTracer {
+	return &requestTracer{
+		start: time.Now(),
+		trace: RequestTrace{Verb: verb, Resource: resource},
+	}
+}
+
+// withClientTrace returns a copy of ctx which reports the requests sent with it to the tracer.
+func (t *requestTracer) withClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.record(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(func() { t.trace.DNS = since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.record(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			t.record(func() { t.trace.Connect = since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.record(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(func() { t.trace.TLSHandshake = since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func() { t.trace.ConnReused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func() { t.trace.TimeToFirstByte = time.Since(t.start) })
+		},
+	})
+}
+
+// record runs fn under the lock of the tracer, the hooks may be called concurrently.
+func (t *requestTracer) record(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn()
+}
+
+// finish returns the trace of the completed attempt.
+func (t *requestTracer) finish() RequestTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.trace.Total = time.Since(t.start)
+
+	return t.trace
+}
+
+// since returns the time elapsed since start, or zero if start is not set.
+func since(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+
+	return time.Since(start)
+}
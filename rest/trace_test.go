@@ -0,0 +1,69 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnTrace(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var traces []RequestTrace
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.Insecure = true
+		c.OnTrace = func(trace RequestTrace) { traces = append(traces, trace) }
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(traces))
+	}
+
+	first := traces[0]
+	if first.Verb != "GET" || first.Resource != "users" {
+		t.Errorf("unexpected request %s %s", first.Verb, first.Resource)
+	}
+
+	if first.ConnReused || first.Connect <= 0 || first.TLSHandshake <= 0 {
+		t.Errorf("expected a new connection to be traced, got %+v", first)
+	}
+
+	for _, trace := range traces {
+		if trace.TimeToFirstByte < 5*time.Millisecond || trace.Total < trace.TimeToFirstByte {
+			t.Errorf("unexpected response timings %+v", trace)
+		}
+	}
+
+	// the DNS lookup is only traced for host names
+	localhost := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	client = testRESTClient(t, srv, func(c *Config) {
+		c.Host = localhost
+		c.Insecure = true
+		c.OnTrace = func(trace RequestTrace) { traces = append(traces, trace) }
+	})
+
+	if err := client.Get().Resource("users").Do(context.TODO()).Error(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if last := traces[len(traces)-1]; last.DNS <= 0 {
+		t.Errorf("expected the DNS lookup to be traced, got %+v", last)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+)
+
+// unstructuredDecoder decodes JSON numbers as json.Number instead of float64 when the target is
+// untyped, eg. a map[string]interface{}, so that int64 values like IDs or expiry timestamps above
+// 2^53 keep their precision. Typed targets are passed to the underlying decoder. It is only used
+// when Config.UseNumber is set.
+type unstructuredDecoder struct {
+	decoder runtime.Decoder
+}
+
+// Decode implements runtime.Decoder.
+func (d unstructuredDecoder) Decode(data []byte, v interface{}) error {
+	switch v.(type) {
+	case *interface{}, *map[string]interface{}, *[]interface{}:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		return decoder.Decode(v)
+	default:
+		return d.decoder.Decode(data, v)
+	}
+}
@@ -12,6 +12,29 @@ import (
 	"github.com/marmotedu/component-base/pkg/scheme"
 )
 
+// The defaults below are used to build the server URL of an API group when Config.Host is not
+// a URL. Deployments serving the API groups under another domain may set them once at init.
+var (
+	// DefaultDomain is the domain the API groups are served under, as <group>.<domain>.
+	DefaultDomain = "marmotedu.com"
+
+	// DefaultInsecurePort is the port of the API groups served over plain HTTP.
+	DefaultInsecurePort = 8080
+
+	// DefaultSecurePort is the port of the API groups served over HTTPS.
+	DefaultSecurePort = 8443
+
+	// DefaultHostTemplate returns the server URL of the given API group. It may be replaced
+	// when the domain and ports are not enough to describe the default hosts.
+	DefaultHostTemplate = func(group string, tls bool) string {
+		if tls {
+			return fmt.Sprintf("https://%s.%s:%d", group, DefaultDomain, DefaultSecurePort)
+		}
+
+		return fmt.Sprintf("http://%s.%s:%d", group, DefaultDomain, DefaultInsecurePort)
+	}
+)
+
 // DefaultServerURL converts a host, host:port, or URL string to the default base server API path
 // to use with a Client at a given API version following the standard conventions for a
 // IAM API.
@@ -19,12 +42,7 @@ func DefaultServerURL(host, apiPath string, groupVersion scheme.GroupVersion,
 	defaultTLS bool) (*url.URL, string, error) {
 	hostURL, err := url.Parse(host)
 	if err != nil || hostURL.Scheme == "" || hostURL.Host == "" {
-		requestURL := fmt.Sprintf("http://%s.marmotedu.com:8080", groupVersion.Group)
-		if defaultTLS {
-			requestURL = fmt.Sprintf("https://%s.marmotedu.com:8443", groupVersion.Group)
-		}
-
-		hostURL, err = url.Parse(requestURL)
+		hostURL, err = url.Parse(DefaultHostTemplate(groupVersion.Group, defaultTLS))
 		if err != nil {
 			return nil, "", err
 		}
@@ -69,7 +87,7 @@ func DefaultVersionedAPIPath(apiPath string, groupVersion scheme.GroupVersion) s
 func defaultServerURLFor(config *Config) (*url.URL, string, error) {
 	// TODO: move the default to secure when the apiserver supports TLS by default
 	// config.Insecure is taken to mean "I want HTTPS but don't bother checking the certs against a CA."
-	hasCA := len(config.CAFile) != 0 || len(config.CAData) != 0
+	hasCA := config.HasCA()
 	hasCert := len(config.CertFile) != 0 || len(config.CertData) != 0
 	defaultTLS := hasCA || hasCert || config.Insecure
 
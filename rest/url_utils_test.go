@@ -0,0 +1,78 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/marmotedu/component-base/pkg/scheme"
+)
+
+func TestDefaultServerURLDefaults(t *testing.T) {
+	gv := scheme.GroupVersion{Group: "iam.api", Version: "v1"}
+
+	tests := []struct {
+		name       string
+		defaultTLS bool
+		want       string
+	}{
+		{"insecure", false, "http://iam.api.marmotedu.com:8080"},
+		{"secure", true, "https://iam.api.marmotedu.com:8443"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, _, err := DefaultServerURL("", "", gv, tc.defaultTLS)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if u.String() != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, u)
+			}
+		})
+	}
+}
+
+func TestDefaultServerURLOverridden(t *testing.T) {
+	defer func(domain string, insecurePort, securePort int) {
+		DefaultDomain, DefaultInsecurePort, DefaultSecurePort = domain, insecurePort, securePort
+	}(DefaultDomain, DefaultInsecurePort, DefaultSecurePort)
+
+	gv := scheme.GroupVersion{Group: "iam.api", Version: "v1"}
+
+	DefaultDomain, DefaultInsecurePort, DefaultSecurePort = "example.internal", 80, 443
+
+	u, _, err := DefaultServerURL("", "", gv, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "https://iam.api.example.internal:443"; u.String() != want {
+		t.Errorf("expected %s, got %s", want, u)
+	}
+
+	defer func(template func(string, bool) string) { DefaultHostTemplate = template }(DefaultHostTemplate)
+
+	DefaultHostTemplate = func(group string, tls bool) string {
+		return fmt.Sprintf("http://%s.gateway.example.internal", group)
+	}
+
+	config := &Config{ContentConfig: ContentConfig{GroupVersion: &gv}}
+
+	u, versionedAPIPath, err := defaultServerURLFor(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "http://iam.api.gateway.example.internal"; u.String() != want {
+		t.Errorf("expected %s, got %s", want, u)
+	}
+
+	if versionedAPIPath != "/v1" {
+		t.Errorf("expected versioned API path /v1, got %s", versionedAPIPath)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxSeenWarnings bounds the number of distinct warnings a client remembers to report them once.
+// Warnings seen past the bound are reported every time.
+const maxSeenWarnings = 1024
+
+// warningReporter reports the distinct warnings of the responses of a client to its handler.
+type warningReporter struct {
+	handler func(warning string)
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newWarningReporter(handler func(warning string), logger Logger) *warningReporter {
+	if handler == nil {
+		handler = func(warning string) {
+			logger.Warn("warning from the server", "warning", warning)
+		}
+	}
+
+	return &warningReporter{handler: handler, seen: map[string]struct{}{}}
+}
+
+// report passes the warnings which were not reported yet to the handler.
+func (w *warningReporter) report(warnings []string) {
+	for _, warning := range warnings {
+		w.mu.Lock()
+		_, seen := w.seen[warning]
+		if !seen && len(w.seen) < maxSeenWarnings {
+			w.seen[warning] = struct{}{}
+		}
+		w.mu.Unlock()
+
+		if !seen {
+			w.handler(warning)
+		}
+	}
+}
+
+// parseWarnings returns the texts of the Warning headers, eg. "Deprecated API" for
+// `299 - "Deprecated API" "Wed, 21 Oct 2015 07:28:00 GMT"`. A header value which is not in the
+// format of RFC 7234 is returned as is.
+func parseWarnings(header http.Header) []string {
+	var warnings []string
+
+	for _, value := range header.Values("Warning") {
+		texts, ok := parseWarningValue(value)
+		if !ok {
+			if value = strings.TrimSpace(value); len(value) != 0 {
+				warnings = append(warnings, value)
+			}
+
+			continue
+		}
+
+		warnings = append(warnings, texts...)
+	}
+
+	return warnings
+}
+
+// parseWarningValue parses the comma separated warnings of a header value, each made of a code,
+// an agent, a quoted text and an optional quoted date.
+func parseWarningValue(value string) ([]string, bool) {
+	var texts []string
+
+	for {
+		value = strings.TrimLeft(value, " \t,")
+		if len(value) == 0 {
+			return texts, len(texts) != 0
+		}
+
+		// warn-code SP warn-agent SP
+		fields := strings.SplitN(value, " ", 3)
+		if len(fields) != 3 || len(fields[0]) != 3 || len(fields[1]) == 0 {
+			return nil, false
+		}
+
+		text, rest, ok := unquote(fields[2])
+		if !ok {
+			return nil, false
+		}
+
+		texts = append(texts, text)
+
+		// [ SP warn-date ]
+		value = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(value, `"`) {
+			if _, value, ok = unquote(value); !ok {
+				return nil, false
+			}
+		}
+
+		if len(strings.TrimSpace(value)) != 0 && !strings.HasPrefix(strings.TrimLeft(value, " \t"), ",") {
+			return nil, false
+		}
+	}
+}
+
+// unquote reads the quoted string s starts with, and returns its content and what follows it.
+func unquote(s string) (text, rest string, ok bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", false
+	}
+
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", false
+			}
+
+			i++
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return "", "", false
+}
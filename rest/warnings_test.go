@@ -0,0 +1,67 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Warning", `299 iam-apiserver "v1 users are deprecated, use v2" "Wed, 21 Oct 2015 07:28:00 GMT"`)
+		w.Header().Add("Warning", `299 - "the \"nickname\" field is ignored"`)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var handled []string
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.WarningHandler = func(warning string) { handled = append(handled, warning) }
+	})
+
+	want := []string{"v1 users are deprecated, use v2", `the "nickname" field is ignored`}
+
+	for i := 0; i < 2; i++ {
+		result := client.Get().Resource("users").Do(context.TODO())
+		if err := result.Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if warnings := result.Warnings(); !reflect.DeepEqual(warnings, want) {
+			t.Errorf("expected the warnings %q, got %q", want, warnings)
+		}
+	}
+
+	if !reflect.DeepEqual(handled, want) {
+		t.Errorf("expected the handler to be called once per warning, got %q", handled)
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{"none", nil, nil},
+		{"with date", []string{`299 - "deprecated" "Wed, 21 Oct 2015 07:28:00 GMT"`}, []string{"deprecated"}},
+		{"comma separated", []string{`299 - "first", 299 - "second"`}, []string{"first", "second"}},
+		{"malformed", []string{`deprecated API`}, []string{"deprecated API"}},
+		{"unterminated text", []string{`299 - "deprecated`}, []string{`299 - "deprecated`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{"Warning": tc.values}
+			if got := parseWarnings(header); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
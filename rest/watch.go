@@ -0,0 +1,415 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/marmotedu/marmotedu-sdk-go/third_party/forked/gorequest"
+)
+
+// EventType defines the possible types of events.
+type EventType string
+
+// Defines the event types sent by the server on a watch stream.
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+	Error    EventType = "ERROR"
+	// Reset is sent by the watchers which reconnect, see WatchBackoff, when the stream is
+	// re-established without resuming from the last observed resource version: events may
+	// have been missed in between, so consumers should list the resources again.
+	Reset EventType = "RESET"
+)
+
+// Defaults of WatchBackoff.
+const (
+	DefaultWatchBackoffInitial = 500 * time.Millisecond
+	DefaultWatchBackoffMax     = 30 * time.Second
+)
+
+// WatchBackoff makes watchers reconnect when their stream drops, eg. on a server restart,
+// waiting an exponentially growing delay with jitter between failed attempts. Streams resume
+// from the resource version of the last event received, unless the server can't resume from it.
+type WatchBackoff struct {
+	// InitialInterval is the delay before the first attempt, doubled after every failed attempt.
+	// Defaults to DefaultWatchBackoffInitial.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between attempts. Defaults to DefaultWatchBackoffMax.
+	MaxInterval time.Duration
+}
+
+// Event represents a single event to a watched resource.
+type Event struct {
+	Type EventType `json:"type"`
+
+	// Object is the raw object as sent by the server:
+	//  * If Type is Added or Modified: the new state of the object.
+	//  * If Type is Deleted: the state of the object immediately before deletion.
+	//  * If Type is Error: a JSON object whose message field describes the error.
+	Object json.RawMessage `json:"object"`
+}
+
+// Watcher can be implemented by anything that knows how to watch and report changes.
+type Watcher interface {
+	// Stop stops watching. Will close the channel returned by ResultChan(). Releases
+	// any resources used by the watch.
+	Stop()
+
+	// ResultChan returns a chan which will receive all the events. If an error occurs
+	// or Stop() is called, the implementation will close this channel and
+	// release any resources used by the watch.
+	ResultChan() <-chan Event
+}
+
+// Watch attempts to begin watching the requested location. The returned Watcher streams the
+// events sent by the server until the server closes the connection, ctx is done or Stop is
+// called. With Config.WatchBackoff set, a closed connection is re-established instead.
+// The client Timeout does not apply to the watch stream, use ctx to bound it.
+func (r *Request) Watch(ctx context.Context) (Watcher, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	r.setParam("watch", "true")
+
+	ctx, cancel := r.withClientContext(ctx)
+
+	resp, err := r.stream(ctx)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	var reconnect *reconnector
+	if backoff := r.c.content.WatchBackoff; backoff != nil {
+		reconnect = &reconnector{backoff: *backoff, resume: r.resume}
+	}
+
+	return newStreamWatcher(ctx, resp.Body, cancel, reconnect), nil
+}
+
+// resume sends the watch request again, from the given resource version if not empty.
+func (r *Request) resume(ctx context.Context, resourceVersion string) (io.ReadCloser, error) {
+	delete(r.params, "resourceVersion")
+
+	if len(resourceVersion) != 0 {
+		r.setParam("resourceVersion", resourceVersion)
+	}
+
+	resp, err := r.stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// stream sends the request and returns the successful response with its body left unread.
+// The caller is responsible for closing the response body.
+func (r *Request) stream(ctx context.Context) (*http.Response, error) {
+	client := r.c.Client.Clone()
+	if err := r.authorize(ctx, client); err != nil {
+		return nil, err
+	}
+
+	r.applyHeaders(client)
+
+	req, err := r.sendBody(client.CustomMethod(r.verb, r.URL().String())).MakeRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	// Streams are long lived, so they are bounded by ctx rather than the client timeout.
+	httpClient := *client.Client
+	httpClient.Transport = client.Transport
+	if r.c.streamTransport != nil {
+		httpClient.Transport = r.c.streamTransport
+	}
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > http.StatusPartialContent {
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		return nil, &streamError{statusCode: resp.StatusCode, err: combineErr(gorequest.Response(resp), body, nil,
+			r.c.content.StatusReasons)}
+	}
+
+	return resp, nil
+}
+
+// streamError is returned when the server refuses to open a stream.
+type streamError struct {
+	statusCode int
+	err        error
+}
+
+func (e *streamError) Error() string {
+	return e.err.Error()
+}
+
+func (e *streamError) Unwrap() error {
+	return e.err
+}
+
+// reconnector re-establishes the stream of a watcher, see WatchBackoff.
+type reconnector struct {
+	backoff WatchBackoff
+	resume  func(ctx context.Context, resourceVersion string) (io.ReadCloser, error)
+}
+
+// reconnect opens a new stream, resuming from resourceVersion when possible, and retrying
+// with backoff until it succeeds or ctx is done. resumed tells whether the new stream resumed
+// from resourceVersion. A client error refusing the stream, eg. 401 Unauthorized after the
+// credentials were revoked, is returned as it won't go away by retrying, see
+// isTerminalStreamError.
+func (rc *reconnector) reconnect(ctx context.Context, resourceVersion string) (body io.ReadCloser, resumed bool, err error) {
+	delay := rc.backoff.InitialInterval
+	if delay <= 0 {
+		delay = DefaultWatchBackoffInitial
+	}
+
+	maxDelay := rc.backoff.MaxInterval
+	if maxDelay <= 0 {
+		maxDelay = DefaultWatchBackoffMax
+	}
+
+	for {
+		// jitter the delay within its upper half, so that watchers dropped together spread out
+		timer := time.NewTimer(delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, false, ctx.Err()
+		case <-timer.C:
+		}
+
+		body, err := rc.resume(ctx, resourceVersion)
+		if err == nil {
+			return body, len(resourceVersion) != 0, nil
+		}
+
+		// the server no longer holds the history since resourceVersion, start over from now
+		var streamErr *streamError
+		if errors.As(err, &streamErr) && streamErr.statusCode == http.StatusGone && len(resourceVersion) != 0 {
+			resourceVersion = ""
+
+			continue
+		}
+
+		if isTerminalStreamError(err) {
+			return nil, false, err
+		}
+
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// isTerminalStreamError returns whether err is a client error refusing a stream, other than
+// 408 Request Timeout, 410 Gone and 429 Too Many Requests which may be retried.
+func isTerminalStreamError(err error) bool {
+	var streamErr *streamError
+	if !errors.As(err, &streamErr) {
+		return false
+	}
+
+	switch code := streamErr.statusCode; code {
+	case http.StatusRequestTimeout, http.StatusGone, http.StatusTooManyRequests:
+		return false
+	default:
+		return code >= http.StatusBadRequest && code < http.StatusInternalServerError
+	}
+}
+
+// resourceVersionOf returns the resource version found in the metadata of an event object.
+func resourceVersionOf(object json.RawMessage) string {
+	var meta struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+	}
+
+	if err := json.Unmarshal(object, &meta); err != nil {
+		return ""
+	}
+
+	return meta.Metadata.ResourceVersion
+}
+
+// streamWatcher turns a stream of JSON encoded events into a Watcher. The stream is closed,
+// and its connection released, whichever way the watch ends: Stop, cancellation of ctx, a
+// decode error or the end of the stream.
+type streamWatcher struct {
+	ctx    context.Context
+	result chan Event
+	cancel context.CancelFunc
+	// reconnect re-establishes the stream when it drops, if set
+	reconnect *reconnector
+
+	mu   sync.Mutex
+	body io.ReadCloser
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newStreamWatcher(ctx context.Context, body io.ReadCloser, cancel context.CancelFunc,
+	reconnect *reconnector) *streamWatcher {
+	sw := &streamWatcher{
+		ctx:       ctx,
+		result:    make(chan Event),
+		body:      body,
+		cancel:    cancel,
+		reconnect: reconnect,
+		done:      make(chan struct{}),
+	}
+
+	go sw.receive()
+
+	return sw
+}
+
+// ResultChan implements Watcher.
+func (sw *streamWatcher) ResultChan() <-chan Event {
+	return sw.result
+}
+
+// Stop implements Watcher.
+func (sw *streamWatcher) Stop() {
+	sw.stopOnce.Do(func() {
+		close(sw.done)
+		sw.cancel()
+
+		sw.mu.Lock()
+		sw.body.Close()
+		sw.mu.Unlock()
+	})
+}
+
+// replaceBody swaps the dropped stream for a new one, unless the watcher was stopped meanwhile.
+func (sw *streamWatcher) replaceBody(body io.ReadCloser) bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	select {
+	case <-sw.done:
+		body.Close()
+
+		return false
+	default:
+	}
+
+	sw.body.Close()
+	sw.body = body
+
+	return true
+}
+
+// receive reads events from the stream until it ends, fails or the watcher is stopped. Watchers
+// which reconnect open a new stream instead when it ends or fails.
+func (sw *streamWatcher) receive() {
+	defer close(sw.result)
+	defer sw.Stop()
+
+	var resourceVersion string
+
+	decoder := json.NewDecoder(sw.body)
+
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			select {
+			case <-sw.done:
+				return
+			case <-sw.ctx.Done():
+				return
+			default:
+			}
+
+			if sw.reconnect == nil {
+				if err != io.EOF {
+					sw.send(errorEvent(err))
+				}
+
+				return
+			}
+
+			body, resumed, err := sw.reconnect.reconnect(sw.ctx, resourceVersion)
+			if err != nil {
+				if sw.ctx.Err() == nil {
+					sw.send(errorEvent(err))
+				}
+
+				return
+			}
+
+			if !sw.replaceBody(body) {
+				return
+			}
+
+			if !resumed {
+				resourceVersion = ""
+
+				if !sw.send(Event{Type: Reset}) {
+					return
+				}
+			}
+
+			decoder = json.NewDecoder(body)
+
+			continue
+		}
+
+		if version := resourceVersionOf(event.Object); len(version) != 0 {
+			resourceVersion = version
+		}
+
+		if !sw.send(event) {
+			return
+		}
+	}
+}
+
+// send delivers an event, giving up when the watcher is stopped or its context is done, so
+// that a consumer which went away never blocks the receiving goroutine.
+func (sw *streamWatcher) send(event Event) bool {
+	select {
+	case sw.result <- event:
+		return true
+	case <-sw.done:
+		return false
+	case <-sw.ctx.Done():
+		return false
+	}
+}
+
+func errorEvent(err error) Event {
+	object, _ := json.Marshal(struct {
+		Message string `json:"message"`
+	}{err.Error()})
+
+	return Event{Type: Error, Object: object}
+}
@@ -0,0 +1,230 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForGoroutines waits for the number of goroutines to drop back to want.
+func waitForGoroutines(t *testing.T, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine leak: %d goroutines running, expected at most %d", runtime.NumGoroutine(), want)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchPartialFrameDoesNotLeak(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"type":"ADDED","object":{"name":"colin"}}` + "\n" + `{"type":"MODIF`))
+		w.(http.Flusher).Flush()
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv)
+	before := runtime.NumGoroutine()
+
+	watcher, err := client.Get().Resource("users").Watch(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []EventType
+	for event := range watcher.ResultChan() {
+		events = append(events, event.Type)
+	}
+
+	if len(events) != 2 || events[0] != Added || events[1] != Error {
+		t.Errorf("expected an ADDED and an ERROR event, got %v", events)
+	}
+
+	waitForGoroutines(t, before)
+}
+
+func TestWatchContextCancelDoesNotLeak(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"type":"ADDED","object":{"name":"colin"}}` + "\n"))
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := testRESTClient(t, srv)
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The pending event is never read: cancellation alone must release the watcher.
+	if _, err := client.Get().Resource("users").Watch(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cancel()
+
+	waitForGoroutines(t, before)
+}
+
+func TestWatchReconnect(t *testing.T) {
+	tests := []struct {
+		name       string
+		resumable  bool
+		wantEvents []EventType
+	}{
+		{"resumed", true, []EventType{Added, Modified}},
+		{"reset", false, []EventType{Added, Reset, Modified}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				versions []string
+			)
+
+			release := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				mu.Lock()
+				connection := len(versions)
+				versions = append(versions, req.URL.Query().Get("resourceVersion"))
+				mu.Unlock()
+
+				switch {
+				case connection == 0:
+					// the first stream drops after one event
+					_, _ = w.Write([]byte(`{"type":"ADDED","object":{"metadata":{"name":"colin","resourceVersion":"5"}}}` + "\n"))
+
+					return
+				case !tc.resumable && req.URL.Query().Get("resourceVersion") != "":
+					w.WriteHeader(http.StatusGone)
+					_, _ = w.Write([]byte(`{"message":"too old resource version"}`))
+
+					return
+				}
+
+				_, _ = w.Write([]byte(`{"type":"MODIFIED","object":{"metadata":{"name":"colin","resourceVersion":"6"}}}` + "\n"))
+				w.(http.Flusher).Flush()
+
+				select {
+				case <-release:
+				case <-req.Context().Done():
+				}
+			}))
+			defer srv.Close()
+			defer close(release)
+
+			client := testRESTClient(t, srv, func(c *Config) {
+				c.WatchBackoff = &WatchBackoff{InitialInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond}
+			})
+
+			watcher, err := client.Get().Resource("users").Watch(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer watcher.Stop()
+
+			var events []EventType
+			for len(events) < len(tc.wantEvents) {
+				select {
+				case event, ok := <-watcher.ResultChan():
+					if !ok {
+						t.Fatalf("watcher closed after events %v", events)
+					}
+
+					events = append(events, event.Type)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out after events %v", events)
+				}
+			}
+
+			if fmt.Sprint(events) != fmt.Sprint(tc.wantEvents) {
+				t.Errorf("expected events %v, got %v", tc.wantEvents, events)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(versions) < 2 || versions[0] != "" || versions[1] != "5" {
+				t.Errorf("expected the reconnection to resume from version 5, got requests from %q", versions)
+			}
+
+			if !tc.resumable && (len(versions) != 3 || versions[2] != "") {
+				t.Errorf("expected a fresh watch after the resume was refused, got requests from %q", versions)
+			}
+		})
+	}
+}
+
+func TestWatchReconnectUnauthorized(t *testing.T) {
+	var connections int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&connections, 1) == 1 {
+			// the first stream drops, then the credentials are revoked
+			_, _ = w.Write([]byte(`{"type":"ADDED","object":{"metadata":{"name":"colin","resourceVersion":"5"}}}` + "\n"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":100205,"message":"Token invalid"}`))
+	}))
+	defer srv.Close()
+
+	client := testRESTClient(t, srv, func(c *Config) {
+		c.WatchBackoff = &WatchBackoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	})
+
+	watcher, err := client.Get().Resource("users").Watch(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer watcher.Stop()
+
+	var events []string
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				want := []string{"ADDED", `ERROR {"message":"{\"code\":100205,\"message\":\"Token invalid\"}"}`}
+				if fmt.Sprint(events) != fmt.Sprint(want) {
+					t.Errorf("expected events %q, got %q", want, events)
+				}
+
+				if n := atomic.LoadInt32(&connections); n != 2 {
+					t.Errorf("expected a single reconnection attempt, got %d connections", n)
+				}
+
+				return
+			}
+
+			if event.Type == Error {
+				events = append(events, string(event.Type)+" "+string(event.Object))
+			} else {
+				events = append(events, string(event.Type))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after events %q", events)
+		}
+	}
+}
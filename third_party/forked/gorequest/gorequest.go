@@ -78,6 +78,8 @@ type SuperAgent struct {
 	RawString            string
 	Client               *http.Client
 	Transport            *http.Transport
+	RoundTripper         http.RoundTripper // when set, sends the requests in place of Transport
+	Trailer              http.Header       // when set, sent after a chunked body
 	Cookies              []*http.Cookie
 	Errors               []error
 	BasicAuth            struct{ Username, Password string }
@@ -222,6 +224,8 @@ func (s *SuperAgent) Clone() *SuperAgent {
 		RawString:            s.RawString,
 		Client:               s.Client,
 		Transport:            s.Transport,
+		RoundTripper:         s.RoundTripper,
+		Trailer:              http.Header(cloneMapArray(s.Trailer)),
 		Cookies:              shallowCopyCookies(s.Cookies),
 		Errors:               shallowCopyErrors(s.Errors),
 		BasicAuth:            s.BasicAuth,
@@ -1212,9 +1216,16 @@ func (s *SuperAgent) getResponseBytes() (Response, []byte, []error) {
 		return nil, nil, s.Errors
 	}
 
-	// Set Transport
+	// Set Transport on a copy of the client, which clones share and may use concurrently
+	client := s.Client
 	if !DisableTransportSwap {
-		s.Client.Transport = s.Transport
+		swapped := *s.Client
+		if s.RoundTripper != nil {
+			swapped.Transport = s.RoundTripper
+		} else {
+			swapped.Transport = s.Transport
+		}
+		client = &swapped
 	}
 
 	// Log details of this request
@@ -1240,7 +1251,7 @@ func (s *SuperAgent) getResponseBytes() (Response, []byte, []error) {
 	}
 
 	// Send request
-	resp, err = s.Client.Do(req)
+	resp, err = client.Do(req)
 	if err != nil {
 		s.Errors = append(s.Errors, err)
 		return nil, nil, s.Errors
@@ -1400,7 +1411,7 @@ func (s *SuperAgent) MakeRequest() (*http.Request, error) {
 	}
 
 	if s.ctx != nil {
-		req.WithContext(s.ctx)
+		req = req.WithContext(s.ctx)
 	}
 
 	for k, vals := range s.Header {
@@ -1420,14 +1431,17 @@ func (s *SuperAgent) MakeRequest() (*http.Request, error) {
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	// Add all querystring from Query func
-	q := req.URL.Query()
-	for k, v := range s.QueryData {
-		for _, vv := range v {
-			q.Add(k, vv)
+	// Add all querystring from Query func. The query of the URL is left as is without any, eg. for
+	// the parameters of a signed URL to stay in order.
+	if len(s.QueryData) != 0 {
+		q := req.URL.Query()
+		for k, v := range s.QueryData {
+			for _, vv := range v {
+				q.Add(k, vv)
+			}
 		}
+		req.URL.RawQuery = q.Encode()
 	}
-	req.URL.RawQuery = q.Encode()
 
 	// Add basic auth
 	if s.BasicAuth != struct{ Username, Password string }{} {
@@ -1439,6 +1453,12 @@ func (s *SuperAgent) MakeRequest() (*http.Request, error) {
 		req.AddCookie(cookie)
 	}
 
+	// Trailers are only sent with a chunked body, so the content length must be unknown
+	if len(s.Trailer) != 0 {
+		req.Trailer = http.Header(cloneMapArray(s.Trailer))
+		req.ContentLength = -1
+	}
+
 	return req, nil
 }
 
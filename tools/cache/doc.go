@@ -0,0 +1,6 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package cache provides helpers to keep a local cache of iam resources in sync with the server.
+package cache // import "github.com/marmotedu/marmotedu-sdk-go/tools/cache"
@@ -0,0 +1,197 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// defaultRelistPeriod is how long ListWatch waits before relisting after the watch ended.
+const defaultRelistPeriod = time.Second
+
+// Event describes a change to an object held by a ListWatch.
+type Event struct {
+	Type rest.EventType
+	// Name is the name of the changed object.
+	Name string
+	// Object is the raw object, or its last known state for Deleted events.
+	Object json.RawMessage
+}
+
+// EventHandler is called for every change observed by a ListWatch.
+type EventHandler func(event Event)
+
+// ListWatch lists a resource to seed a local cache and then watches it to receive deltas.
+// Whenever the watch ends or fails the resource is listed again, and the differences to the
+// cache are emitted as events, so no change is lost while the watch was down.
+type ListWatch struct {
+	client       rest.Interface
+	resource     string
+	relistPeriod time.Duration
+
+	mu    sync.RWMutex
+	items map[string]json.RawMessage
+}
+
+// NewListWatch creates a ListWatch for the given resource, eg: users.
+func NewListWatch(client rest.Interface, resource string) *ListWatch {
+	return &ListWatch{
+		client:       client,
+		resource:     resource,
+		relistPeriod: defaultRelistPeriod,
+		items:        map[string]json.RawMessage{},
+	}
+}
+
+// Run seeds the cache with an initial list and keeps it in sync with a watch, invoking handler
+// for every change, until ctx is done. It always returns the error of ctx.
+func (lw *ListWatch) Run(ctx context.Context, handler EventHandler) error {
+	for {
+		if resourceVersion, err := lw.relist(ctx, handler); err == nil {
+			lw.watch(ctx, handler, resourceVersion)
+		}
+
+		timer := time.NewTimer(lw.relistPeriod)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// List returns the objects currently held in the cache.
+func (lw *ListWatch) List() []json.RawMessage {
+	lw.mu.RLock()
+	defer lw.mu.RUnlock()
+
+	items := make([]json.RawMessage, 0, len(lw.items))
+	for _, item := range lw.items {
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// relist lists the resource and emits the differences to the cache. It returns the resource
+// version of the list, for the watch to start from.
+func (lw *ListWatch) relist(ctx context.Context, handler EventHandler) (string, error) {
+	list := struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []json.RawMessage `json:"items"`
+	}{}
+	if err := lw.client.Get().Resource(lw.resource).Do(ctx).Into(&list); err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool, len(list.Items))
+	for _, object := range list.Items {
+		name := objectName(object)
+		seen[name] = true
+
+		lw.mu.RLock()
+		old, exists := lw.items[name]
+		lw.mu.RUnlock()
+
+		switch {
+		case !exists:
+			lw.apply(handler, Event{Type: rest.Added, Name: name, Object: object})
+		case !jsonEqual(old, object):
+			lw.apply(handler, Event{Type: rest.Modified, Name: name, Object: object})
+		}
+	}
+
+	lw.mu.RLock()
+	var deleted []Event
+	for name, object := range lw.items {
+		if !seen[name] {
+			deleted = append(deleted, Event{Type: rest.Deleted, Name: name, Object: object})
+		}
+	}
+	lw.mu.RUnlock()
+
+	for _, event := range deleted {
+		lw.apply(handler, event)
+	}
+
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch applies the events of a watch on the resource, from the resource version of the last
+// list, until the watch ends. The resource is listed again when the watch reconnects without
+// resuming, as events may have been missed.
+func (lw *ListWatch) watch(ctx context.Context, handler EventHandler, resourceVersion string) {
+	request := lw.client.Get().Resource(lw.resource)
+	if len(resourceVersion) != 0 {
+		request.Param("resourceVersion", resourceVersion)
+	}
+
+	watcher, err := request.Watch(ctx)
+	if err != nil {
+		return
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		switch event.Type {
+		case rest.Error:
+			return
+		case rest.Reset:
+			if _, err := lw.relist(ctx, handler); err != nil {
+				return
+			}
+
+			continue
+		}
+
+		lw.apply(handler, Event{Type: event.Type, Name: objectName(event.Object), Object: event.Object})
+	}
+}
+
+// apply updates the cache with the event and passes it on to the handler.
+func (lw *ListWatch) apply(handler EventHandler, event Event) {
+	lw.mu.Lock()
+	if event.Type == rest.Deleted {
+		delete(lw.items, event.Name)
+	} else {
+		lw.items[event.Name] = event.Object
+	}
+	lw.mu.Unlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
+
+// objectName returns the metadata.name of a raw object.
+func objectName(object json.RawMessage) string {
+	meta := struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}{}
+	_ = json.Unmarshal(object, &meta)
+
+	return meta.Metadata.Name
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var bufA, bufB bytes.Buffer
+	if json.Compact(&bufA, a) != nil || json.Compact(&bufB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
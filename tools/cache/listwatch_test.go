@@ -0,0 +1,146 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marmotedu/component-base/pkg/runtime"
+	"github.com/marmotedu/component-base/pkg/scheme"
+
+	"github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestListWatch(t *testing.T) {
+	var watchVersion string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("watch") != "true" {
+			fmt.Fprint(w, `{"metadata":{"resourceVersion":"42"},"items":[{"metadata":{"name":"alice"},"nickname":"a"},{"metadata":{"name":"bob"}}]}`)
+
+			return
+		}
+
+		watchVersion = req.URL.Query().Get("resourceVersion")
+
+		for _, event := range []string{
+			`{"type":"MODIFIED","object":{"metadata":{"name":"alice"},"nickname":"alice"}}`,
+			`{"type":"DELETED","object":{"metadata":{"name":"bob"}}}`,
+			`{"type":"ADDED","object":{"metadata":{"name":"carol"}}}`,
+		} {
+			fmt.Fprintln(w, event)
+		}
+		w.(http.Flusher).Flush()
+
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, err := rest.RESTClientFor(&rest.Config{
+		Host: srv.URL,
+		ContentConfig: rest.ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	want := []string{"ADDED alice", "ADDED bob", "MODIFIED alice", "DELETED bob", "ADDED carol"}
+
+	var got []string
+	lw := NewListWatch(client, "users")
+	_ = lw.Run(ctx, func(event Event) {
+		got = append(got, fmt.Sprintf("%s %s", event.Type, event.Name))
+		if len(got) == len(want) {
+			cancel()
+		}
+	})
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected events %v, got %v", want, got)
+	}
+
+	if items := lw.List(); len(items) != 2 {
+		t.Errorf("expected 2 cached objects, got %d", len(items))
+	}
+
+	if watchVersion != "42" {
+		t.Errorf("expected the watch to start from the resource version of the list, got %q", watchVersion)
+	}
+}
+
+func TestListWatchReset(t *testing.T) {
+	var lists, watches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("watch") != "true" {
+			if atomic.AddInt32(&lists, 1) == 1 {
+				fmt.Fprint(w, `{"items":[{"metadata":{"name":"alice"}},{"metadata":{"name":"bob"}}]}`)
+			} else {
+				fmt.Fprint(w, `{"items":[{"metadata":{"name":"alice"},"nickname":"alice"},{"metadata":{"name":"carol"}}]}`)
+			}
+
+			return
+		}
+
+		// the first stream drops without a resource version to resume from
+		if atomic.AddInt32(&watches, 1) == 1 {
+			fmt.Fprintln(w, `{"type":"MODIFIED","object":{"metadata":{"name":"alice"},"nickname":"alice"}}`)
+
+			return
+		}
+
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, err := rest.RESTClientFor(&rest.Config{
+		Host: srv.URL,
+		ContentConfig: rest.ContentConfig{
+			GroupVersion: &scheme.GroupVersion{Group: "iam.api", Version: "v1"},
+			Negotiator:   runtime.NewSimpleClientNegotiator(),
+		},
+		WatchBackoff: &rest.WatchBackoff{InitialInterval: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	want := []string{"ADDED alice", "ADDED bob", "MODIFIED alice", "ADDED carol", "DELETED bob"}
+
+	var got []string
+	lw := NewListWatch(client, "users")
+	_ = lw.Run(ctx, func(event Event) {
+		got = append(got, fmt.Sprintf("%s %s", event.Type, event.Name))
+		if len(got) == len(want) {
+			cancel()
+		}
+	})
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected events %v, got %v", want, got)
+	}
+
+	if watches := atomic.LoadInt32(&watches); watches != 2 {
+		t.Errorf("expected the watch to reconnect once, got %d streams", watches)
+	}
+
+	if items := lw.List(); len(items) != 2 {
+		t.Errorf("expected 2 cached objects, got %s", items)
+	}
+}
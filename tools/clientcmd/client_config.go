@@ -5,6 +5,8 @@
 package clientcmd
 
 import (
+	"fmt"
+	"io"
 	"net/url"
 	"time"
 
@@ -13,7 +15,9 @@ import (
 
 // Server contains information about how to communicate with a iam api server.
 type Server struct {
-	LocationOfOrigin string
+	// LocationOfOrigin indicates where this object came from. It is set by LoadFromFile
+	// and not serialized.
+	LocationOfOrigin string        `yaml:"-"`
 	Timeout          time.Duration `yaml:"timeout,omitempty"                    mapstructure:"timeout,omitempty"`
 	MaxRetries       int           `yaml:"max-retries,omitempty"                mapstructure:"max-retries,omitempty"`
 	RetryInterval    time.Duration `yaml:"retry-interval,omitempty"             mapstructure:"retry-interval,omitempty"`
@@ -38,7 +42,9 @@ type Server struct {
 // AuthInfo contains information that describes identity information.
 // This is use to tell the iam cluster who you are.
 type AuthInfo struct {
-	LocationOfOrigin  string
+	// LocationOfOrigin indicates where this object came from. It is set by LoadFromFile
+	// and not serialized.
+	LocationOfOrigin  string `yaml:"-"`
 	ClientCertificate string `yaml:"client-certificate,omitempty"      mapstructure:"client-certificate,omitempty"`
 	// ClientCertificateData contains PEM-encoded data from a client cert file for TLS. Overrides ClientCertificate
 	// +optional
@@ -60,18 +66,130 @@ type AuthInfo struct {
 	SecretKey string `yaml:"secret-key,omitempty" mapstructure:"secret-key,omitempty"`
 }
 
-// Config defines a config struct used by marmotedu-sdk-go.
+// Context binds a user to a server, by their names in Config.
+type Context struct {
+	// LocationOfOrigin indicates where this object came from. It is set by LoadFromFile
+	// and not serialized.
+	LocationOfOrigin string `yaml:"-"`
+	// Server is the name of the server in Config.Servers.
+	Server string `yaml:"server"         mapstructure:"server"`
+	// AuthInfo is the name of the user in Config.AuthInfos. Requests are not authenticated if not
+	// set.
+	// +optional
+	AuthInfo string `yaml:"user,omitempty" mapstructure:"user,omitempty"`
+}
+
+// DefaultContextName is the name of the context made of the single user and server sections of
+// a Config, unless Contexts has a context of that name.
+const DefaultContextName = "default"
+
+// Config defines a config struct used by marmotedu-sdk-go. It holds either a single user and
+// server, or named users, servers and the contexts binding them, like a kubeconfig, eg. to talk to
+// staging and production with a single file. The single sections are the DefaultContextName
+// context.
 type Config struct {
 	APIVersion string    `yaml:"apiVersion,omitempty" mapstructure:"apiVersion,omitempty"`
 	AuthInfo   *AuthInfo `yaml:"user,omitempty"       mapstructure:"user,omitempty"`
 	Server     *Server   `yaml:"server,omitempty"     mapstructure:"server,omitempty"`
+
+	// AuthInfos are the named users.
+	// +optional
+	AuthInfos map[string]*AuthInfo `yaml:"users,omitempty"           mapstructure:"users,omitempty"`
+	// Servers are the named servers.
+	// +optional
+	Servers map[string]*Server `yaml:"servers,omitempty"         mapstructure:"servers,omitempty"`
+	// Contexts are the named contexts.
+	// +optional
+	Contexts map[string]*Context `yaml:"contexts,omitempty"        mapstructure:"contexts,omitempty"`
+	// CurrentContext is the name of the context used when none is chosen.
+	// +optional
+	CurrentContext string `yaml:"current-context,omitempty" mapstructure:"current-context,omitempty"`
+}
+
+// hasSingleSections returns whether the single user or server section is set.
+func (config *Config) hasSingleSections() bool {
+	if config.AuthInfo != nil {
+		authInfo := *config.AuthInfo
+		authInfo.LocationOfOrigin = ""
+
+		if authInfo != (AuthInfo{}) {
+			return true
+		}
+	}
+
+	if config.Server != nil {
+		server := *config.Server
+		server.LocationOfOrigin = ""
+
+		if server != (Server{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveContext returns the name of the context chosen by name, or by CurrentContext if name
+// is empty, and a Config holding copies of its user and server as single sections. The returned
+// name is empty when the single sections are used as is.
+func (config *Config) resolveContext(name string) (string, *Config, error) {
+	if len(name) == 0 {
+		name = config.CurrentContext
+	}
+
+	flat := &Config{APIVersion: config.APIVersion, AuthInfo: &AuthInfo{}, Server: &Server{}}
+
+	context, ok := config.Contexts[name]
+	if !ok {
+		switch {
+		case len(name) == 0 && len(config.Contexts) != 0 && !config.hasSingleSections():
+			return "", nil, ErrNoContext
+		case len(name) != 0 && name != DefaultContextName:
+			return name, nil, fmt.Errorf("context %q not found", name)
+		}
+
+		if config.AuthInfo != nil {
+			*flat.AuthInfo = *config.AuthInfo
+		}
+
+		if config.Server != nil {
+			*flat.Server = *config.Server
+		}
+
+		return "", flat, nil
+	}
+
+	if context == nil {
+		return name, nil, fmt.Errorf("context %q is empty", name)
+	}
+
+	server, ok := config.Servers[context.Server]
+	if !ok || server == nil {
+		return name, nil, fmt.Errorf("context %q: server %q not found", name, context.Server)
+	}
+
+	*flat.Server = *server
+
+	if len(context.AuthInfo) != 0 {
+		authInfo, ok := config.AuthInfos[context.AuthInfo]
+		if !ok || authInfo == nil {
+			return name, nil, fmt.Errorf("context %q: user %q not found", name, context.AuthInfo)
+		}
+
+		*flat.AuthInfo = *authInfo
+	}
+
+	return name, flat, nil
 }
 
 // NewConfig is a convenience function that returns a new Config object with non-nil maps.
 func NewConfig() *Config {
 	return &Config{
-		Server:   &Server{},
-		AuthInfo: &AuthInfo{},
+		Server:    &Server{},
+		AuthInfo:  &AuthInfo{},
+		AuthInfos: map[string]*AuthInfo{},
+		Servers:   map[string]*Server{},
+		Contexts:  map[string]*Context{},
 	}
 }
 
@@ -83,12 +201,29 @@ type ClientConfig interface {
 
 // DirectClientConfig wrap for Config.
 type DirectClientConfig struct {
+	// config holds the user and server of the chosen context as single sections.
 	config Config
+	// contextName is the name of the chosen context, empty for the single sections of the Config.
+	contextName string
+	// err tells why the chosen context could not be resolved.
+	err error
 }
 
-// NewClientConfigFromConfig takes your Config and gives you back a ClientConfig.
+// newDirectClientConfig returns a DirectClientConfig for the context of config chosen by
+// contextName, or by its current context if contextName is empty.
+func newDirectClientConfig(config *Config, contextName string) *DirectClientConfig {
+	name, flat, err := config.resolveContext(contextName)
+	if err != nil {
+		return &DirectClientConfig{contextName: name, err: err}
+	}
+
+	return &DirectClientConfig{config: *flat, contextName: name}
+}
+
+// NewClientConfigFromConfig takes your Config and gives you back a ClientConfig for its current
+// context.
 func NewClientConfigFromConfig(config *Config) ClientConfig {
-	return &DirectClientConfig{*config}
+	return newDirectClientConfig(config, "")
 }
 
 // NewClientConfigFromBytes takes your iamconfig and gives you back a ClientConfig.
@@ -98,7 +233,7 @@ func NewClientConfigFromBytes(configBytes []byte) (ClientConfig, error) {
 		return nil, err
 	}
 
-	return &DirectClientConfig{*config}, nil
+	return newDirectClientConfig(config, ""), nil
 }
 
 // RESTConfigFromIAMConfig is a convenience method to give back a restconfig from your iamconfig bytes.
@@ -114,13 +249,13 @@ func RESTConfigFromIAMConfig(configBytes []byte) (*restclient.Config, error) {
 
 // ClientConfig implements ClientConfig.
 func (config *DirectClientConfig) ClientConfig() (*restclient.Config, error) {
-	user := config.getAuthInfo()
-	server := config.getServer()
-
 	if err := config.ConfirmUsable(); err != nil {
 		return nil, err
 	}
 
+	user := config.getAuthInfo()
+	server := config.getServer()
+
 	clientConfig := &restclient.Config{
 		BearerToken:   user.Token,
 		Username:      user.Username,
@@ -156,7 +291,12 @@ func (config *DirectClientConfig) ClientConfig() (*restclient.Config, error) {
 // ConfirmUsable looks a particular context and determines if that particular part of
 // the config is useable.  There might still be errors in the config, but no errors in the
 // sections requested or referenced.  It does not return early so that it can find as many errors as possible.
+// The errors of a named context are prefixed with its name.
 func (config *DirectClientConfig) ConfirmUsable() error {
+	if config.err != nil {
+		return newErrConfigurationInvalid([]error{config.err})
+	}
+
 	validationErrors := make([]error, 0)
 
 	authInfo := config.getAuthInfo()
@@ -165,10 +305,16 @@ func (config *DirectClientConfig) ConfirmUsable() error {
 	validationErrors = append(validationErrors, validateServerInfo(server)...)
 	// when direct client config is specified, and our only error is that no server is defined, we should
 	// return a standard "no config" error
-	if len(validationErrors) == 1 && validationErrors[0] == ErrEmptyServer {
+	if len(validationErrors) == 1 && validationErrors[0] == ErrEmptyServer && len(config.contextName) == 0 {
 		return newErrConfigurationInvalid([]error{ErrEmptyConfig})
 	}
 
+	if len(config.contextName) != 0 {
+		for i, err := range validationErrors {
+			validationErrors[i] = fmt.Errorf("context %q: %w", config.contextName, err)
+		}
+	}
+
 	return newErrConfigurationInvalid(validationErrors)
 }
 
@@ -184,20 +330,63 @@ func (config *DirectClientConfig) getServer() Server {
 
 // BuildConfigFromFlags is a helper function that builds configs from a master
 // url or a iamconfig filepath. These are passed in as command line flags for cluster
-// components. Warnings should reflect this usage. If neither masterUrl or iamconfigPath
-// are passed in we fallback to inClusterConfig. If inClusterConfig fails, we fallback
-// to the default config.
+// components. Warnings should reflect this usage. An empty iamconfigPath is resolved with
+// ResolveConfigPath. The current context of the iamconfig is used.
 func BuildConfigFromFlags(serverURL, iamconfigPath string) (*restclient.Config, error) {
-	config, err := LoadFromFile(iamconfigPath)
+	config, err := LoadFromFile(ResolveConfigPath(iamconfigPath))
 	if err != nil {
 		return nil, err
 	}
 
-	if len(serverURL) > 0 {
-		config.Server.Address = serverURL
+	directClientConfig := newDirectClientConfig(config, "")
+	if len(serverURL) > 0 && directClientConfig.err == nil {
+		directClientConfig.config.Server.Address = serverURL
 	}
 
-	directClientConfig := &DirectClientConfig{*config}
+	return directClientConfig.ClientConfig()
+}
+
+// BuildConfigFromContext builds a config from the context named contextName of the iamconfig
+// file, or from its current context if contextName is empty. An empty iamconfigPath is resolved
+// with ResolveConfigPath. The single user and server sections of an iamconfig are the
+// DefaultContextName context.
+func BuildConfigFromContext(iamconfigPath, contextName string) (*restclient.Config, error) {
+	config, err := LoadFromFile(ResolveConfigPath(iamconfigPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return newDirectClientConfig(config, contextName).ClientConfig()
+}
+
+// BuildConfigFromTokenReader builds a config like BuildConfigFromFlags, authenticated with the
+// bearer token read from tokenReader, eg. os.Stdin for an interactive tool prompting for it,
+// which replaces the credentials of the iamconfig file. See ReadToken. The iamconfig file is
+// optional, in which case serverURL is required.
+func BuildConfigFromTokenReader(serverURL, iamconfigPath string, tokenReader io.Reader) (*restclient.Config, error) {
+	token, err := ReadToken(tokenReader)
+	if err != nil {
+		return nil, err
+	}
+
+	config := NewConfig()
+	if len(iamconfigPath) > 0 {
+		if config, err = LoadFromFile(iamconfigPath); err != nil {
+			return nil, err
+		}
+	}
+
+	directClientConfig := newDirectClientConfig(config, "")
+	if directClientConfig.err == nil {
+		if len(serverURL) > 0 {
+			directClientConfig.config.Server.Address = serverURL
+		}
+
+		authInfo := directClientConfig.config.AuthInfo
+		authInfo.Token = token
+		authInfo.Username, authInfo.Password = "", ""
+		authInfo.SecretID, authInfo.SecretKey = "", ""
+	}
 
 	return directClientConfig.ClientConfig()
 }
@@ -0,0 +1,249 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	restclient "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+func TestRESTConfigInlineCAData(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	tests := []struct {
+		name   string
+		caData string
+	}{
+		{"raw PEM", "|\n    " + strings.ReplaceAll(strings.TrimSpace(string(caPEM)), "\n", "\n    ")},
+		{"base64", base64.StdEncoding.EncodeToString(caPEM)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := fmt.Sprintf(`
+apiVersion: v1
+user:
+  token: token
+server:
+  address: %s
+  certificate-authority-data: %s
+`, srv.URL, tc.caData)
+
+			config, err := RESTConfigFromIAMConfig([]byte(data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tlsConfig, err := restclient.TLSConfigFor(config)
+			if err != nil {
+				t.Fatalf("unexpected error building the TLS config: %v", err)
+			}
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("expected the server certificate to be trusted, got %v", err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestBuildConfigFromTokenReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantToken string
+		wantErr   error
+	}{
+		{"token", "token\n", "token", nil},
+		{"surrounding whitespace", "  token \t\r\n", "token", nil},
+		{"only the first line", "token\nleftover\n", "token", nil},
+		{"no final newline", "token", "token", nil},
+		{"empty", "", "", ErrEmptyToken},
+		{"blank line", " \n", "", ErrEmptyToken},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := BuildConfigFromTokenReader("https://iam.api.marmotedu.com:8443", "", strings.NewReader(tc.input))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if config.BearerToken != tc.wantToken || config.Host != "https://iam.api.marmotedu.com:8443" {
+				t.Errorf("unexpected config %+v", config)
+			}
+		})
+	}
+}
+
+func TestReadToken(t *testing.T) {
+	r := strings.NewReader("token\nleftover\n")
+
+	token, err := ReadToken(r)
+	if err != nil || token != "token" {
+		t.Fatalf("expected the token, got %q (%v)", token, err)
+	}
+
+	if rest, _ := io.ReadAll(r); string(rest) != "leftover\n" {
+		t.Errorf("expected the next lines to be left unread, got %q", rest)
+	}
+
+	if _, err := ReadToken(strings.NewReader(strings.Repeat("t", maxTokenLength+1))); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("expected a too long token error, got %v", err)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	config := NewConfig()
+	config.AuthInfos["colin"] = &AuthInfo{Token: "token"}
+	config.Servers["iam"] = &Server{Address: "https://iam.api.marmotedu.com"}
+	config.Contexts["iam"] = &Context{Server: "iam", AuthInfo: "colin"}
+
+	restConfig, err := newDirectClientConfig(config, "iam").ClientConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if restConfig.Host != "https://iam.api.marmotedu.com" || restConfig.BearerToken != "token" {
+		t.Errorf("expected the server and user of the context, got %+v", restConfig)
+	}
+}
+
+func TestBuildConfigFromContext(t *testing.T) {
+	dir := t.TempDir()
+
+	writeConfig := func(name, data string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return file
+	}
+
+	contexts := writeConfig("contexts", `
+apiVersion: v1
+current-context: staging
+users:
+  colin:
+    token: staging-token
+  admin:
+    secret-id: id
+    secret-key: key
+servers:
+  staging:
+    address: https://iam.staging.marmotedu.com:8443
+  production:
+    address: https://iam.api.marmotedu.com:8443
+contexts:
+  staging:
+    server: staging
+    user: colin
+  production:
+    server: production
+    user: admin
+  anonymous:
+    server: production
+  broken:
+    server: production
+    user: nobody
+  invalid:
+    server: staging
+    user: both
+`)
+	legacy := writeConfig("legacy", `
+apiVersion: v1
+user:
+  token: token
+server:
+  address: https://iam.api.marmotedu.com:8443
+`)
+
+	tests := []struct {
+		name      string
+		file      string
+		context   string
+		wantHost  string
+		wantToken string
+		wantErr   string
+	}{
+		{"current context", contexts, "", "https://iam.staging.marmotedu.com:8443", "staging-token", ""},
+		{"named context", contexts, "production", "https://iam.api.marmotedu.com:8443", "", ""},
+		{"context without user", contexts, "anonymous", "https://iam.api.marmotedu.com:8443", "", ""},
+		{"unknown context", contexts, "dev", "", "", `context "dev" not found`},
+		{"unknown user", contexts, "broken", "", "", `context "broken": user "nobody" not found`},
+		{"legacy single sections", legacy, "", "https://iam.api.marmotedu.com:8443", "token", ""},
+		{"legacy default context", legacy, DefaultContextName, "https://iam.api.marmotedu.com:8443", "token", ""},
+		{"legacy unknown context", legacy, "staging", "", "", `context "staging" not found`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := BuildConfigFromContext(tc.file, tc.context)
+			if len(tc.wantErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if config.Host != tc.wantHost || config.BearerToken != tc.wantToken {
+				t.Errorf("expected host %q and token %q, got %+v", tc.wantHost, tc.wantToken, config)
+			}
+		})
+	}
+
+	// the validation errors tell which context failed
+	data, _ := os.ReadFile(contexts)
+	data = []byte(strings.Replace(string(data), "  admin:", "  both:\n    token: token\n    username: colin\n  admin:", 1))
+
+	_, err := BuildConfigFromContext(writeConfig("invalid", string(data)), "invalid")
+	if !IsConfigurationInvalid(err) || !strings.Contains(err.Error(), `context "invalid": more than one authentication method`) {
+		t.Errorf("expected the validation error of the context, got %v", err)
+	}
+
+	// the server flag overrides the server of the current context
+	t.Setenv(RecommendedConfigPathEnvVar, contexts)
+
+	config, err := BuildConfigFromFlags("https://localhost:8443", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Host != "https://localhost:8443" || config.BearerToken != "staging-token" {
+		t.Errorf("unexpected config %+v", config)
+	}
+}
@@ -5,11 +5,21 @@
 package clientcmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// maxTokenLength bounds the size of the tokens read by ReadToken.
+const maxTokenLength = 64 * 1024
+
+// ErrEmptyToken is returned by ReadToken when no token was entered.
+var ErrEmptyToken = errors.New("empty token")
+
 // ParseTimeout returns a parsed duration from a string
 // A duration string value must be a positive integer, optionally followed by a corresponding time unit (s|m|h).
 func ParseTimeout(duration string) (time.Duration, error) {
@@ -25,3 +35,42 @@ func ParseTimeout(duration string) (time.Duration, error) {
 		"invalid timeout value. Timeout must be a single integer in seconds, or an integer followed by a corresponding time unit (e.g. 1s | 2m | 3h)",
 	)
 }
+
+// ReadToken reads a bearer token from the first line of r, eg. os.Stdin. r is read one byte at a
+// time, so that nothing past the end of the line is consumed, eg. the answers to later prompts.
+// Surrounding whitespace is trimmed, and ErrEmptyToken is returned when nothing else is left.
+func ReadToken(r io.Reader) (string, error) {
+	var line []byte
+
+	b := make([]byte, 1)
+
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+
+			if len(line) == maxTokenLength {
+				return "", fmt.Errorf("read token: %w", bufio.ErrTooLong)
+			}
+
+			line = append(line, b[0])
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("read token: %w", err)
+		}
+	}
+
+	token := strings.TrimSpace(string(line))
+	if len(token) == 0 {
+		return "", ErrEmptyToken
+	}
+
+	return token, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	restclient "github.com/marmotedu/marmotedu-sdk-go/rest"
+)
+
+// Defines the well-known locations of the iam service account credentials mounted into a pod.
+const (
+	InClusterTokenFile  = "/var/run/secrets/iam/token"
+	InClusterRootCAFile = "/var/run/secrets/iam/ca.crt"
+)
+
+// ErrNotInCluster indicates that the in-cluster config can not be used because the
+// IAM_SERVICE_HOST and IAM_SERVICE_PORT environment variables are not defined.
+var ErrNotInCluster = errors.New("unable to load in-cluster configuration, " +
+	"IAM_SERVICE_HOST and IAM_SERVICE_PORT must be defined")
+
+// inClusterTokenFile and inClusterRootCAFile can be replaced to point at a fake mount.
+var (
+	inClusterTokenFile  = InClusterTokenFile
+	inClusterRootCAFile = InClusterRootCAFile
+)
+
+// InClusterConfig returns a config object which uses the service account token and root CA
+// mounted into a pod alongside the iam server, and the service address from the
+// IAM_SERVICE_HOST and IAM_SERVICE_PORT environment variables. The token file is re-read
+// periodically, so rotated tokens are picked up without rebuilding the client.
+// It returns ErrNotInCluster if called from a process not running in such a pod.
+func InClusterConfig() (*restclient.Config, error) {
+	host, port := os.Getenv("IAM_SERVICE_HOST"), os.Getenv("IAM_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, ErrNotInCluster
+	}
+
+	token, err := ioutil.ReadFile(inClusterTokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsClientConfig := restclient.TLSClientConfig{}
+	if _, err := os.Stat(inClusterRootCAFile); err != nil {
+		return nil, fmt.Errorf("expected to load root CA config from %s, but got err: %w", inClusterRootCAFile, err)
+	}
+
+	tlsClientConfig.CAFile = inClusterRootCAFile
+
+	return &restclient.Config{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		TLSClientConfig: tlsClientConfig,
+		BearerToken:     strings.TrimSpace(string(token)),
+		BearerTokenFile: inClusterTokenFile,
+	}, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func fakeInClusterMount(t *testing.T, token string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	oldTokenFile, oldRootCAFile := inClusterTokenFile, inClusterRootCAFile
+	inClusterTokenFile, inClusterRootCAFile = filepath.Join(dir, "token"), filepath.Join(dir, "ca.crt")
+
+	t.Cleanup(func() {
+		inClusterTokenFile, inClusterRootCAFile = oldTokenFile, oldRootCAFile
+	})
+
+	return dir
+}
+
+func TestInClusterConfig(t *testing.T) {
+	dir := fakeInClusterMount(t, "in-cluster-token\n")
+	t.Setenv("IAM_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("IAM_SERVICE_PORT", "8443")
+
+	config, err := InClusterConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Host != "https://10.0.0.1:8443" {
+		t.Errorf("expected host %q, got %q", "https://10.0.0.1:8443", config.Host)
+	}
+
+	if config.BearerToken != "in-cluster-token" {
+		t.Errorf("expected token %q, got %q", "in-cluster-token", config.BearerToken)
+	}
+
+	if config.BearerTokenFile != filepath.Join(dir, "token") {
+		t.Errorf("expected token file %q, got %q", filepath.Join(dir, "token"), config.BearerTokenFile)
+	}
+
+	if config.CAFile != filepath.Join(dir, "ca.crt") {
+		t.Errorf("expected CA file %q, got %q", filepath.Join(dir, "ca.crt"), config.CAFile)
+	}
+}
+
+func TestInClusterConfigNotInCluster(t *testing.T) {
+	fakeInClusterMount(t, "in-cluster-token")
+	t.Setenv("IAM_SERVICE_HOST", "")
+	t.Setenv("IAM_SERVICE_PORT", "")
+
+	if _, err := InClusterConfig(); !errors.Is(err, ErrNotInCluster) {
+		t.Errorf("expected ErrNotInCluster, got %v", err)
+	}
+}
@@ -5,8 +5,11 @@
 package clientcmd
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 
 	"github.com/marmotedu/component-base/pkg/util/homedir"
 	yaml "gopkg.in/yaml.v3"
@@ -28,6 +31,23 @@ var (
 	RecommendedSchemaFile = path.Join(RecommendedConfigDir, RecommendedSchemaName)
 )
 
+// ResolveConfigPath returns the path of the iamconfig file to load, like kubectl does: explicit,
+// typically the value of the RecommendedConfigPathFlag flag, if not empty, else the first path of
+// the RecommendedConfigPathEnvVar environment variable if set, else RecommendedHomeFile.
+func ResolveConfigPath(explicit string) string {
+	if len(explicit) != 0 {
+		return explicit
+	}
+
+	for _, file := range filepath.SplitList(os.Getenv(RecommendedConfigPathEnvVar)) {
+		if len(file) != 0 {
+			return file
+		}
+	}
+
+	return RecommendedHomeFile
+}
+
 // LoadFromFile load config from file.
 func LoadFromFile(filename string) (*Config, error) {
 	iamconfigBytes, err := ioutil.ReadFile(filename)
@@ -40,10 +60,6 @@ func LoadFromFile(filename string) (*Config, error) {
 		return nil, err
 	}
 
-	// set LocationOfOrigin on every Cluster, User, and Context
-	config.AuthInfo.LocationOfOrigin = filename
-	config.Server.LocationOfOrigin = filename
-
 	if config.AuthInfo == nil {
 		config.AuthInfo = &AuthInfo{}
 	}
@@ -52,6 +68,28 @@ func LoadFromFile(filename string) (*Config, error) {
 		config.Server = &Server{}
 	}
 
+	// set LocationOfOrigin on every Cluster, User, and Context
+	config.AuthInfo.LocationOfOrigin = filename
+	config.Server.LocationOfOrigin = filename
+
+	for _, authInfo := range config.AuthInfos {
+		if authInfo != nil {
+			authInfo.LocationOfOrigin = filename
+		}
+	}
+
+	for _, server := range config.Servers {
+		if server != nil {
+			server.LocationOfOrigin = filename
+		}
+	}
+
+	for _, context := range config.Contexts {
+		if context != nil {
+			context.LocationOfOrigin = filename
+		}
+	}
+
 	return config, nil
 }
 
@@ -64,9 +102,103 @@ func Load(data []byte) (*Config, error) {
 		return config, nil
 	}
 
-	if err := yaml.Unmarshal(data, config); err != nil {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	// comments only
+	if document.Kind == 0 {
+		return config, nil
+	}
+
+	// anchors and aliases are resolved by Decode, reject documents whose aliases expand to a
+	// huge tree first, eg. billion laughs attacks.
+	if err := checkAliases(&document); err != nil {
+		return nil, err
+	}
+
+	if err := document.Decode(config); err != nil {
 		return nil, err
 	}
 
 	return config, nil
 }
+
+const (
+	// maxAliasDepth is the maximum number of aliases nested in each other in an iamconfig.
+	maxAliasDepth = 8
+	// maxExpandedNodes is the maximum number of nodes of an iamconfig once its aliases are expanded.
+	maxExpandedNodes = 10000
+)
+
+// expansion is the size of a node once its aliases are expanded.
+type expansion struct {
+	nodes int
+	depth int
+}
+
+// checkAliases returns an error when the aliases of the document nest too deeply or expand to
+// too many nodes. The expansion of every node is computed once, so that a document made of
+// aliases referring to each other is checked without being expanded.
+func checkAliases(document *yaml.Node) error {
+	expansions := map[*yaml.Node]*expansion{}
+
+	var expand func(node *yaml.Node) (*expansion, error)
+	expand = func(node *yaml.Node) (*expansion, error) {
+		if e, ok := expansions[node]; ok {
+			if e == nil {
+				return nil, fmt.Errorf("iamconfig anchor %q contains itself", node.Anchor)
+			}
+
+			return e, nil
+		}
+
+		// a nil entry marks the nodes being expanded
+		expansions[node] = nil
+
+		e := &expansion{nodes: 1}
+
+		if node.Kind == yaml.AliasNode && node.Alias != nil {
+			target, err := expand(node.Alias)
+			if err != nil {
+				return nil, err
+			}
+
+			e.nodes, e.depth = target.nodes, target.depth+1
+		}
+
+		for _, child := range node.Content {
+			c, err := expand(child)
+			if err != nil {
+				return nil, err
+			}
+
+			// saturate instead of overflowing on exponential expansions
+			e.nodes += c.nodes
+			if e.nodes > maxExpandedNodes {
+				e.nodes = maxExpandedNodes + 1
+			}
+
+			if c.depth > e.depth {
+				e.depth = c.depth
+			}
+		}
+
+		if e.depth > maxAliasDepth {
+			return nil, fmt.Errorf("iamconfig aliases are nested more than %d levels deep", maxAliasDepth)
+		}
+
+		if e.nodes > maxExpandedNodes {
+			return nil, fmt.Errorf("iamconfig aliases expand to more than %d nodes", maxExpandedNodes)
+		}
+
+		expansions[node] = e
+
+		return e, nil
+	}
+
+	_, err := expand(document)
+
+	return err
+}
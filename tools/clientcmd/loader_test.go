@@ -0,0 +1,151 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadResolvesAliases(t *testing.T) {
+	data := `
+x-defaults: &defaults
+  timeout: 10s
+  max-retries: 2
+user:
+  client-certificate: &bundle /etc/iam/client.pem
+  client-key: *bundle
+server:
+  <<: *defaults
+  address: https://iam.api.marmotedu.com:8443
+  certificate-authority: *bundle
+`
+
+	config, err := Load([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.AuthInfo.ClientKey != "/etc/iam/client.pem" || config.Server.CertificateAuthority != "/etc/iam/client.pem" {
+		t.Errorf("expected the aliases to resolve to the anchored path, got %+v %+v", config.AuthInfo, config.Server)
+	}
+
+	if config.Server.Timeout.String() != "10s" || config.Server.MaxRetries != 2 {
+		t.Errorf("expected the anchored defaults to be merged, got %+v", config.Server)
+	}
+}
+
+func TestLoadRejectsAliasBombs(t *testing.T) {
+	// deep builds a chain of aliases nested depth levels deep
+	deep := func(depth int) string {
+		var b strings.Builder
+		b.WriteString("a0: &a0 lol\n")
+
+		for i := 1; i <= depth; i++ {
+			fmt.Fprintf(&b, "a%d: &a%d [*a%d, *a%d]\n", i, i, i-1, i-1)
+		}
+
+		fmt.Fprintf(&b, "apiVersion: v1\nuser: {token: *a%d}\n", depth)
+
+		return b.String()
+	}
+
+	// wide builds a chain of aliases repeated width times per level
+	wide := func(width, depth int) string {
+		var b strings.Builder
+		b.WriteString("a0: &a0 lol\n")
+
+		for i := 1; i <= depth; i++ {
+			aliases := make([]string, width)
+			for j := range aliases {
+				aliases[j] = fmt.Sprintf("*a%d", i-1)
+			}
+
+			fmt.Fprintf(&b, "a%d: &a%d [%s]\n", i, i, strings.Join(aliases, ", "))
+		}
+
+		return b.String()
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"deep chain", deep(maxAliasDepth + 1), "nested more than"},
+		{"billion laughs", wide(10, 8), "expand to more than"},
+		{"wide chain", wide(200, 3), "expand to more than"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load([]byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	for _, data := range []string{"", "\n", "# no settings yet\n"} {
+		config, err := Load([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", data, err)
+		}
+
+		if config == nil {
+			t.Errorf("expected the default config for %q", data)
+		}
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		explicit string
+		env      string
+		want     string
+	}{
+		{"explicit flag", "/etc/iam/flag.yaml", "/etc/iam/env.yaml", "/etc/iam/flag.yaml"},
+		{"environment variable", "", "/etc/iam/env.yaml", "/etc/iam/env.yaml"},
+		{"first path of the environment variable", "", string(filepath.ListSeparator) + "/etc/iam/env.yaml" +
+			string(filepath.ListSeparator) + "/etc/iam/other.yaml", "/etc/iam/env.yaml"},
+		{"home file", "", "", RecommendedHomeFile},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(RecommendedConfigPathEnvVar, tc.env)
+
+			if got := ResolveConfigPath(tc.explicit); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildConfigFromFlagsResolvesPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	data := "apiVersion: v1\nuser:\n  token: token\nserver:\n  address: https://iam.api.marmotedu.com:8443\n"
+
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv(RecommendedConfigPathEnvVar, file)
+
+	config, err := BuildConfigFromFlags("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.BearerToken != "token" || config.Host != "https://iam.api.marmotedu.com:8443" {
+		t.Errorf("expected the config of %s, got %+v", RecommendedConfigPathEnvVar, config)
+	}
+}
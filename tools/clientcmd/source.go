@@ -0,0 +1,44 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// ConfigSource provides the raw bytes of an iamconfig. Implementing it allows loading the
+// configuration from a key-value store like Consul, etcd or Vault, without the SDK depending
+// on their clients.
+type ConfigSource interface {
+	Read() ([]byte, error)
+}
+
+// FileSource is a ConfigSource reading the iamconfig from a file.
+type FileSource struct {
+	Filename string
+}
+
+var _ ConfigSource = FileSource{}
+
+// Read implements ConfigSource.
+func (s FileSource) Read() ([]byte, error) {
+	return ioutil.ReadFile(s.Filename)
+}
+
+// NewClientConfigFromSource reads the iamconfig from the source and gives you back a
+// ClientConfig. A nil source reads the recommended iamconfig file in the home directory.
+func NewClientConfigFromSource(src ConfigSource) (ClientConfig, error) {
+	if src == nil {
+		src = FileSource{Filename: RecommendedHomeFile}
+	}
+
+	data, err := src.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read iamconfig: %w", err)
+	}
+
+	return NewClientConfigFromBytes(data)
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memorySource is an in-memory ConfigSource, standing in for a key-value store.
+type memorySource struct {
+	data  []byte
+	err   error
+	reads int
+}
+
+func (s *memorySource) Read() ([]byte, error) {
+	s.reads++
+
+	return s.data, s.err
+}
+
+func TestNewClientConfigFromSource(t *testing.T) {
+	data, err := Write(testConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	src := &memorySource{data: data}
+
+	clientConfig, err := NewClientConfigFromSource(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if src.reads != 1 {
+		t.Errorf("expected the source to be read once, got %d", src.reads)
+	}
+
+	if config.Host != "https://iam.api.marmotedu.com:8443" || config.Username != "colin" || config.MaxRetries != 2 {
+		t.Errorf("unexpected rest config %+v", config)
+	}
+
+	errUnavailable := errors.New("consul is unavailable")
+	if _, err := NewClientConfigFromSource(&memorySource{err: errUnavailable}); !errors.Is(err, errUnavailable) {
+		t.Errorf("expected the source error, got %v", err)
+	}
+
+	if _, err := NewClientConfigFromSource(&memorySource{data: []byte("server: [")}); err == nil {
+		t.Error("expected an error for an invalid iamconfig")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	data, err := Write(testConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConfig, err := NewClientConfigFromSource(FileSource{Filename: filename})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.TLSClientConfig.ServerName != "iam.api.marmotedu.com" {
+		t.Errorf("expected the server name of the file, got %q", config.TLSClientConfig.ServerName)
+	}
+
+	if _, err := NewClientConfigFromSource(FileSource{Filename: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
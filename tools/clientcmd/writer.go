@@ -0,0 +1,128 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// defaultYAMLIndent is the indentation used by yaml.v3 when none is configured.
+const defaultYAMLIndent = 4
+
+// Marshaler serializes a Config into the content of a config file. The output must be
+// readable by Load.
+type Marshaler interface {
+	Marshal(config *Config) ([]byte, error)
+}
+
+// YAMLMarshaler serializes a Config as YAML, indented by Indent spaces.
+type YAMLMarshaler struct {
+	// Indent is the number of spaces used for indentation. If not set, 4 spaces are used.
+	Indent int
+}
+
+// Marshal implements Marshaler.
+func (m YAMLMarshaler) Marshal(config *Config) ([]byte, error) {
+	indent := m.Indent
+	if indent == 0 {
+		indent = defaultYAMLIndent
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// JSONMarshaler serializes a Config as JSON with sorted keys, using the same field names as
+// the YAML representation. Prefix and Indent are used as by json.MarshalIndent.
+type JSONMarshaler struct {
+	Prefix string
+	Indent string
+}
+
+// Marshal implements Marshaler.
+func (m JSONMarshaler) Marshal(config *Config) ([]byte, error) {
+	// Go through YAML first, so that the field names match the ones read by Load.
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+
+	data, err = json.MarshalIndent(fields, m.Prefix, m.Indent)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// WriteOption customizes how a config is written.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	marshaler Marshaler
+}
+
+// WithMarshaler sets the Marshaler used to serialize the config. YAMLMarshaler with the
+// standard indentation is used by default.
+func WithMarshaler(marshaler Marshaler) WriteOption {
+	return func(o *writeOptions) {
+		o.marshaler = marshaler
+	}
+}
+
+// Write serializes the config into the content of a config file.
+func Write(config Config, opts ...WriteOption) ([]byte, error) {
+	options := writeOptions{marshaler: YAMLMarshaler{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options.marshaler.Marshal(&config)
+}
+
+// WriteToFile serializes the config and writes it to filename, creating the parent directory
+// if needed. The file is only readable by its owner, since it may contain credentials.
+func WriteToFile(config Config, filename string, opts ...WriteOption) error {
+	content, err := Write(config, opts...)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filename)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(filename, content, 0o600)
+}
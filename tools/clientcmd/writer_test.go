@@ -0,0 +1,79 @@
+// Copyright 2020 Lingfei Kong <colin404@foxmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package clientcmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	return Config{
+		APIVersion: "v1",
+		AuthInfo:   &AuthInfo{Username: "colin", Password: "secret"},
+		Server: &Server{
+			Address:       "https://iam.api.marmotedu.com:8443",
+			Timeout:       10 * time.Second,
+			MaxRetries:    2,
+			TLSServerName: "iam.api.marmotedu.com",
+		},
+		// loaded configs have the non-nil maps of NewConfig
+		AuthInfos: map[string]*AuthInfo{},
+		Servers:   map[string]*Server{},
+		Contexts:  map[string]*Context{},
+	}
+}
+
+func TestWriteToFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		marshaler Marshaler
+		want      string
+	}{
+		{"default yaml", nil, "user:\n    username: colin\n"},
+		{"yaml with 2 spaces", YAMLMarshaler{Indent: 2}, "user:\n  username: colin\n"},
+		{"json", JSONMarshaler{Indent: "  "}, "{\n  \"apiVersion\": \"v1\",\n  \"server\": {\n    \"address\""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []WriteOption
+			if tc.marshaler != nil {
+				opts = append(opts, WithMarshaler(tc.marshaler))
+			}
+
+			config := testConfig()
+
+			content, err := Write(config, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(string(content), tc.want) {
+				t.Errorf("expected output to contain %q, got:\n%s", tc.want, content)
+			}
+
+			filename := filepath.Join(t.TempDir(), ".iam", "config")
+			if err := WriteToFile(config, filename, opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			loaded, err := LoadFromFile(filename)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			loaded.AuthInfo.LocationOfOrigin = ""
+			loaded.Server.LocationOfOrigin = ""
+
+			if !reflect.DeepEqual(*loaded, config) {
+				t.Errorf("expected config to round-trip, got %+v", loaded)
+			}
+		})
+	}
+}